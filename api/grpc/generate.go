@@ -0,0 +1,5 @@
+// Package grpc holds the protobuf definitions of the gRPC API and the code
+// generated from them.
+package grpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative products/v1/products.proto
@@ -0,0 +1,118 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/response"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+type issueAPIKeyRequest struct {
+	Name string `json:"name" binding:"required"`
+	Role string `json:"role" binding:"required"`
+}
+
+type APIKeyHandlers struct {
+	service usecase.KeyService
+}
+
+func NewAPIKeyHandlers(service usecase.KeyService) *APIKeyHandlers {
+	return &APIKeyHandlers{
+		service: service,
+	}
+}
+
+// IssueAPIKey godoc
+// @Summary Issue an API key
+// @Description create a new API key; the plaintext key is only returned in this response
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @param key body issueAPIKeyRequest true "Key name and role (reader, editor or admin)"
+// @Success 201 {object} response.DTOIssuedAPIKey
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Router /api/v1/admin/api-keys [post]
+func (ah *APIKeyHandlers) IssueAPIKey(ctx *gin.Context) {
+	request := issueAPIKeyRequest{}
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(err.Error()))
+		return
+	}
+	rawKey, key, err := ah.service.IssueKey(ctx.Request.Context(), request.Name, entity.Role(request.Role))
+	if abortOnContextError(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(err.Error()))
+		return
+	}
+	ctx.JSON(http.StatusCreated, response.DTOIssuedAPIKey{
+		DTOAPIKey: *response.ConvertFromAPIKeyToResponse(*key),
+		Key:       rawKey,
+	})
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description list issued API keys, including revoked ones, without their secret
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {array} response.DTOAPIKey
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/admin/api-keys [get]
+func (ah *APIKeyHandlers) ListAPIKeys(ctx *gin.Context) {
+	keys, err := ah.service.FindAll(ctx.Request.Context())
+	if abortOnContextError(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, response.NewErrorResponse(err.Error()))
+		return
+	}
+	responseJSON := make([]response.DTOAPIKey, 0, len(keys))
+	for _, key := range keys {
+		responseJSON = append(responseJSON, *response.ConvertFromAPIKeyToResponse(key))
+	}
+	ctx.JSON(http.StatusOK, responseJSON)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description revoke an API key by id
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @param id path string true "API key id"
+// @Success 204 {object} nil
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /api/v1/admin/api-keys/{id} [delete]
+func (ah *APIKeyHandlers) RevokeAPIKey(ctx *gin.Context) {
+	err := ah.service.RevokeKey(ctx.Request.Context(), ctx.Param("id"))
+	if abortOnContextError(ctx, err) {
+		return
+	}
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		ctx.JSON(http.StatusNotFound, response.NewErrorResponse(err.Error()))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, response.NewErrorResponse(err.Error()))
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
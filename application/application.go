@@ -1,13 +1,36 @@
 package application
 
 import (
+	"errors"
+	"flag"
 	"os"
-	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/yescorihuela/agrak/infrastructure/config"
+	"github.com/yescorihuela/agrak/shared/logging"
 )
 
-func Run() error {
-	serverHost := os.Getenv("BACKEND_IP")
-	serverPort, _ := strconv.ParseUint(os.Getenv("BACKEND_PORT"), 10, 0) // This value would be defined by envvar
-	server := NewServer(serverHost, uint(serverPort))
+// Run loads the configuration from args, the environment and the optional
+// config file, and serves the API until a shutdown signal is received.
+func Run(args []string) error {
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if cfg.PrintConfig {
+		return cfg.Print(os.Stdout)
+	}
+	if err := logging.Configure(cfg.Log.Level, cfg.Log.Format); err != nil {
+		return err
+	}
+	log.WithField("config", cfg.Redacted()).Debugln("configuration loaded")
+
+	server, err := NewServer(cfg)
+	if err != nil {
+		return err
+	}
 	return server.Run()
 }
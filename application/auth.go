@@ -0,0 +1,106 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/infrastructure/response"
+	"github.com/yescorihuela/agrak/shared/identity"
+	"github.com/yescorihuela/agrak/shared/logging"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+const (
+	APIKeyHeader        = "X-API-Key"
+	bearerPrefix        = "Bearer "
+	principalContextKey = "principal"
+)
+
+// Authenticate resolves either a bearer token or the X-API-Key header to the
+// caller of the request. The caller is stored in the gin context and in the
+// request context, so layers below the handlers can read it through
+// identity.FromContext. tokenValidator may be nil when bearer tokens are
+// not accepted.
+func Authenticate(keyService usecase.KeyService, tokenValidator usecase.TokenValidator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var principal *entity.Principal
+		if authorization := ctx.GetHeader("Authorization"); strings.HasPrefix(authorization, bearerPrefix) {
+			if tokenValidator == nil {
+				abortUnauthorized(ctx, "bearer tokens are not accepted")
+				return
+			}
+			validated, err := tokenValidator.Validate(strings.TrimPrefix(authorization, bearerPrefix))
+			if err != nil {
+				ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+				abortUnauthorized(ctx, err.Error())
+				return
+			}
+			principal = validated
+		} else {
+			rawKey := ctx.GetHeader(APIKeyHeader)
+			if rawKey == "" {
+				abortUnauthorized(ctx, "missing credentials")
+				return
+			}
+			key, err := keyService.Authenticate(ctx.Request.Context(), rawKey)
+			if abortOnContextError(ctx, err) {
+				return
+			}
+			if errors.Is(err, usecase.ErrInvalidAPIKey) {
+				abortUnauthorized(ctx, err.Error())
+				return
+			}
+			if err != nil {
+				logging.FromContext(ctx.Request.Context()).WithError(err).Errorln("error trying to authenticate api key")
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, response.NewErrorResponse("authentication unavailable"))
+				return
+			}
+			principal = &entity.Principal{
+				Subject: key.ID,
+				Method:  entity.AuthMethodAPIKey,
+				Role:    key.Role,
+			}
+		}
+
+		ctx.Set(principalContextKey, principal)
+		ctx.Request = ctx.Request.WithContext(identity.WithPrincipal(ctx.Request.Context(), principal))
+		ctx.Next()
+	}
+}
+
+// Authorize lets the request through when the caller holds role, for API
+// keys, or scope, for bearer tokens.
+func Authorize(role entity.Role, scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal := CurrentPrincipal(ctx)
+		if principal == nil {
+			abortUnauthorized(ctx, "missing credentials")
+			return
+		}
+		if !principal.Allows(role, scope) {
+			message := "the " + string(role) + " role is required"
+			if principal.Method == entity.AuthMethodJWT {
+				message = "the " + scope + " scope is required"
+			}
+			ctx.AbortWithStatusJSON(http.StatusForbidden, response.NewErrorResponse(message))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func CurrentPrincipal(ctx *gin.Context) *entity.Principal {
+	value, ok := ctx.Get(principalContextKey)
+	if !ok {
+		return nil
+	}
+	principal, _ := value.(*entity.Principal)
+	return principal
+}
+
+func abortUnauthorized(ctx *gin.Context, message string) {
+	ctx.AbortWithStatusJSON(http.StatusUnauthorized, response.NewErrorResponse(message))
+}
@@ -0,0 +1,125 @@
+package application
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/shared/identity"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+func newAuthRouter(keyService usecase.KeyService, tokenValidator usecase.TokenValidator) *gin.Engine {
+	router := gin.Default()
+	v1 := router.Group("api/v1", Authenticate(keyService, tokenValidator))
+	v1.GET("/products/", Authorize(entity.RoleReader, entity.ScopeProductsRead), func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, identity.FromContext(ctx.Request.Context()).Subject)
+	})
+	v1.DELETE("/products/:sku", Authorize(entity.RoleAdmin, entity.ScopeProductsDelete), func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		description string
+		method      string
+		resource    string
+		apiKey      string
+		key         *entity.APIKey
+		err         error
+		status      int
+		message     string
+	}{
+		{"401 Unauthorized (missing key)", http.MethodGet, "/api/v1/products/", "", nil, nil, http.StatusUnauthorized, "missing credentials"},
+		{"401 Unauthorized (invalid key)", http.MethodGet, "/api/v1/products/", "agk_unknown", nil, usecase.ErrInvalidAPIKey, http.StatusUnauthorized, "invalid api key"},
+		{"500 Internal Server Error", http.MethodGet, "/api/v1/products/", "agk_reader", nil, errors.New("connection refused"), http.StatusInternalServerError, "authentication unavailable"},
+		{"200 OK (reader reads)", http.MethodGet, "/api/v1/products/", "agk_reader", &entity.APIKey{ID: "reader-key", Role: entity.RoleReader}, nil, http.StatusOK, ""},
+		{"403 Forbidden (editor deletes)", http.MethodDelete, "/api/v1/products/FAL-1000000", "agk_editor", &entity.APIKey{Role: entity.RoleEditor}, nil, http.StatusForbidden, "the admin role is required"},
+		{"204 No Content (admin deletes)", http.MethodDelete, "/api/v1/products/FAL-1000000", "agk_admin", &entity.APIKey{Role: entity.RoleAdmin}, nil, http.StatusNoContent, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			keyServiceMock := new(usecase.KeyServiceMock)
+			keyServiceMock.On("Authenticate", tt.apiKey).Return(tt.key, tt.err)
+
+			rr := httptest.NewRecorder()
+			request, err := http.NewRequest(tt.method, tt.resource, nil)
+			assert.NoError(t, err)
+			if tt.apiKey != "" {
+				request.Header.Set(APIKeyHeader, tt.apiKey)
+			}
+
+			newAuthRouter(keyServiceMock, nil).ServeHTTP(rr, request)
+
+			assert.Equal(t, tt.status, rr.Code)
+			if tt.message != "" {
+				response, _ := json.Marshal(gin.H{"message": tt.message})
+				assert.Equal(t, response, rr.Body.Bytes())
+			}
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.key.ID, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthenticateBearerToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rawToken := "header.payload.signature"
+
+	tests := []struct {
+		description string
+		method      string
+		resource    string
+		principal   *entity.Principal
+		err         error
+		status      int
+		message     string
+	}{
+		{"401 Unauthorized (invalid token)", http.MethodGet, "/api/v1/products/", nil, fmt.Errorf("%w: token is expired", usecase.ErrInvalidToken), http.StatusUnauthorized, "invalid bearer token: token is expired"},
+		{"200 OK (read scope)", http.MethodGet, "/api/v1/products/", &entity.Principal{Subject: "checkout", Method: entity.AuthMethodJWT, Scopes: []string{entity.ScopeProductsRead}}, nil, http.StatusOK, ""},
+		{"403 Forbidden (missing delete scope)", http.MethodDelete, "/api/v1/products/FAL-1000000", &entity.Principal{Method: entity.AuthMethodJWT, Scopes: []string{entity.ScopeProductsWrite}}, nil, http.StatusForbidden, "the products:delete scope is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			tokenValidatorMock := new(usecase.TokenValidatorMock)
+			tokenValidatorMock.On("Validate", rawToken).Return(tt.principal, tt.err)
+
+			rr := httptest.NewRecorder()
+			request, err := http.NewRequest(tt.method, tt.resource, nil)
+			assert.NoError(t, err)
+			request.Header.Set("Authorization", "Bearer "+rawToken)
+
+			newAuthRouter(new(usecase.KeyServiceMock), tokenValidatorMock).ServeHTTP(rr, request)
+
+			assert.Equal(t, tt.status, rr.Code)
+			if tt.message != "" {
+				response, _ := json.Marshal(gin.H{"message": tt.message})
+				assert.Equal(t, response, rr.Body.Bytes())
+			}
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.principal.Subject, rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("401 Unauthorized (bearer tokens disabled)", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/api/v1/products/", nil)
+		request.Header.Set("Authorization", "Bearer "+rawToken)
+
+		newAuthRouter(new(usecase.KeyServiceMock), nil).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
@@ -1,38 +1,167 @@
 package application
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"github.com/yescorihuela/agrak/docs"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/infrastructure/cache"
+	"github.com/yescorihuela/agrak/infrastructure/config"
+	"github.com/yescorihuela/agrak/infrastructure/events"
+	"github.com/yescorihuela/agrak/infrastructure/metrics"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/apikey"
 	"github.com/yescorihuela/agrak/infrastructure/postgresql/connection"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/migration"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/outbox"
 	"github.com/yescorihuela/agrak/infrastructure/postgresql/product"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/transaction"
+	postgresqlWebhook "github.com/yescorihuela/agrak/infrastructure/postgresql/webhook"
+	"github.com/yescorihuela/agrak/infrastructure/ratelimit"
+	"github.com/yescorihuela/agrak/infrastructure/token"
+	"github.com/yescorihuela/agrak/infrastructure/tracing"
+	"github.com/yescorihuela/agrak/infrastructure/webhook"
 	"github.com/yescorihuela/agrak/usecase"
+	"google.golang.org/grpc"
 )
 
 type Server struct {
-	engine   *gin.Engine
-	dbClient *connection.PostgresqlConnection
-	httpAddr string
+	engine        *gin.Engine
+	dbClient      *connection.PostgresqlConnection
+	migrator      *migration.Migrator
+	httpAddr      string
+	cachePolicies CachePolicies
+	rateLimits    RateLimits
+	limiter       ratelimit.Limiter
+	timeouts      HTTPTimeouts
+	httpServer    *http.Server
+	shutdownHooks []ShutdownHook
+	draining      int32
+	metrics       *metrics.Metrics
+	auth          config.AuthConfig
+	publishers    []usecase.EventPublisher
+	stream        *events.Broadcaster
+	heartbeat     time.Duration
+	graphqlLimits GraphQLLimits
+	maxBatchSize  int
+	keys          usecase.KeyService
+	tokens        usecase.TokenValidator
+	products      usecase.Service
+	availability  *usecase.AvailabilityScheduler
+	grpcAddr      string
+	grpcServer    *grpc.Server
 }
 
-func NewServer(host string, port uint) *Server {
-	dbClient := connection.InitPGClient()
-	connection.AutoMigrateEntities(dbClient)
+func NewServer(cfg *config.Config) (*Server, error) {
+	rateLimits, err := NewRateLimits(cfg.RateLimit.Read, cfg.RateLimit.Write, cfg.RateLimit.Bulk, cfg.RateLimit.Client)
+	if err != nil {
+		return nil, err
+	}
+	timeouts := HTTPTimeouts{
+		Read:       cfg.Server.ReadTimeout,
+		Write:      cfg.Server.WriteTimeout,
+		Idle:       cfg.Server.IdleTimeout,
+		Request:    cfg.Server.RequestTimeout,
+		Shutdown:   cfg.Server.ShutdownTimeout,
+		DrainDelay: cfg.Server.DrainDelay,
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config().
+		Exporter(tracing.Exporter(cfg.Tracing.Exporter)).
+		ServiceName(cfg.Tracing.ServiceName).
+		SampleRatio(cfg.Tracing.SampleRatio))
+	if err != nil {
+		return nil, err
+	}
+	serverMetrics := metrics.New()
+	dbClient := NewDatabase(cfg.Database)
+	if err := dbClient.Use(metrics.NewGormPlugin(serverMetrics), tracing.NewGormPlugin()); err != nil {
+		return nil, err
+	}
+	if err := dbClient.Connect(context.Background()); err != nil {
+		return nil, err
+	}
+	migrator, err := migration.NewMigrator(dbClient)
+	if err != nil {
+		return nil, err
+	}
+	if err := prepareSchema(context.Background(), migrator, cfg.Database.Migrations); err != nil {
+		return nil, err
+	}
 	server := &Server{
-		engine:   gin.Default(),
-		dbClient: dbClient,
-		httpAddr: fmt.Sprintf("%s:%d", host, port),
+		engine:        gin.New(),
+		dbClient:      dbClient,
+		migrator:      migrator,
+		httpAddr:      fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
+		cachePolicies: NewCachePolicies(cfg.Cache.ProductCacheControl, cfg.Cache.ProductsCacheControl),
+		rateLimits:    rateLimits,
+		limiter:       ratelimit.NewMemoryLimiter(),
+		timeouts:      timeouts,
+		metrics:       serverMetrics,
+		auth:          cfg.Auth,
+		stream:        events.NewBroadcaster(cfg.Stream.BufferSize),
+		heartbeat:     cfg.Stream.HeartbeatInterval,
+		maxBatchSize:  cfg.Products.MaxBatchSize,
+		graphqlLimits: GraphQLLimits{
+			MaxComplexity: cfg.GraphQL.MaxComplexity,
+			MaxDepth:      cfg.GraphQL.MaxDepth,
+		},
 	}
-	server.registerRoutes()
-	return server
-}
-
-func (s *Server) Run() error {
-	return s.engine.Run(s.httpAddr)
+	server.publishers = []usecase.EventPublisher{events.NewLogPublisher()}
+	server.OnShutdown(shutdownTracing)
+	server.engine.Use(gin.Recovery(), Trace(), RequestLogger(log.StandardLogger()))
+	if err := server.registerMetrics(); err != nil {
+		return nil, err
+	}
+	server.httpServer = &http.Server{
+		Addr:         server.httpAddr,
+		Handler:      server.engine,
+		ReadTimeout:  timeouts.Read,
+		WriteTimeout: timeouts.Write,
+		IdleTimeout:  timeouts.Idle,
+		ConnContext:  withConn,
+	}
+	// Streams only end when their client leaves, so end them on shutdown.
+	server.httpServer.RegisterOnShutdown(server.stream.Close)
+	if err := server.registerServices(); err != nil {
+		return nil, err
+	}
+	if err := server.registerRoutes(); err != nil {
+		return nil, err
+	}
+	if cfg.GRPC.Port != 0 {
+		server.grpcAddr = fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.GRPC.Port)
+		server.grpcServer = NewGRPCServer(NewProductGRPCServer(server.products, server.stream), server.keys, server.tokens, server.limiter, server.rateLimits, timeouts.Request)
+		server.OnShutdown(server.stopGRPC)
+	}
+	webhookSubscriptions := postgresqlWebhook.NewPersistenceWebhookRepository(dbClient)
+	webhookDeliveries := postgresqlWebhook.NewPersistenceDeliveryRepository(dbClient)
+	server.publishers = append(server.publishers, webhook.NewPublisher(webhookSubscriptions, webhookDeliveries))
+	dispatcher := usecase.NewWebhookDispatcher(webhookSubscriptions, webhookDeliveries, webhook.NewHTTPSender(cfg.Webhook.Timeout), cfg.Webhook.MaxAttempts)
+	server.StartWorker("webhook dispatcher", func(ctx context.Context) {
+		dispatcher.Run(ctx, cfg.Webhook.DispatchInterval)
+	})
+	outboxEvents := outbox.NewPersistenceOutboxRepository(dbClient)
+	relay := usecase.NewOutboxRelay(outboxEvents, cfg.Outbox.BatchSize, server.publishers...)
+	server.StartWorker("outbox relay", func(ctx context.Context) {
+		relay.Run(ctx, cfg.Outbox.RelayInterval)
+	})
+	// Relays share the outbox between replicas, so the streams follow what
+	// every relay dispatched rather than what the relay of this one claimed.
+	follower := usecase.NewOutboxFollower(outboxEvents, cfg.Outbox.BatchSize, server.stream)
+	server.StartWorker("outbox follower", func(ctx context.Context) {
+		follower.Run(ctx, cfg.Outbox.RelayInterval)
+	})
+	server.StartWorker("availability scheduler", func(ctx context.Context) {
+		server.availability.Run(ctx, cfg.Products.AvailabilityCheckInterval)
+	})
+	return server, nil
 }
 
 // @title Agrak Products API
@@ -45,19 +174,175 @@ func (s *Server) Run() error {
 
 // @host localhost:8000
 // @BasePath /api/v1
-func (s *Server) registerRoutes() {
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+func (s *Server) registerRoutes() error {
 	docs.SwaggerInfo.BasePath = "/api/v1"
 	s.engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	productRepository := product.NewPersistenceProductRepository(s.dbClient)
-	productService := usecase.NewProductService(productRepository)
+	hh := NewHealthHandlers(map[string]DependencyCheck{
+		"database": func(ctx context.Context) error {
+			return connection.Ping(ctx, s.dbClient)
+		},
+		"migrations": func(ctx context.Context) error {
+			return s.migrator.Check(ctx)
+		},
+	}, s.IsDraining)
+	s.engine.GET("/healthz", hh.Liveness)
+	s.engine.GET("/readyz", hh.Readiness)
+	s.engine.GET("/metrics", gin.WrapH(s.metrics.Handler()))
 
-	ph := NewProductHandlers(productService)
+	ph := NewProductHandlers(s.products).WithMaxBatchSize(s.maxBatchSize)
+	ah := NewAPIKeyHandlers(s.keys)
+	sh := NewStreamHandlers(s.stream, s.heartbeat, s.timeouts.Write)
+	gh, err := NewGraphQLHandlers(s.products, s.graphqlLimits, func(ctx *gin.Context) bool {
+		return allowRequest(ctx, s.limiter, "write", s.rateLimits.Write)
+	})
+	if err != nil {
+		return err
+	}
+	wh := NewWebhookHandlers(usecase.NewWebhookService(
+		postgresqlWebhook.NewPersistenceWebhookRepository(s.dbClient),
+		postgresqlWebhook.NewPersistenceDeliveryRepository(s.dbClient),
+	))
+
+	read := []gin.HandlerFunc{
+		Authorize(entity.RoleReader, entity.ScopeProductsRead),
+		RateLimit(s.limiter, "read", s.rateLimits.Read),
+	}
+	bulk := []gin.HandlerFunc{
+		Authorize(entity.RoleReader, entity.ScopeProductsRead),
+		RateLimit(s.limiter, "bulk", s.rateLimits.Bulk),
+	}
+	write := []gin.HandlerFunc{
+		Authorize(entity.RoleEditor, entity.ScopeProductsWrite),
+		RateLimit(s.limiter, "write", s.rateLimits.Write),
+	}
+	remove := []gin.HandlerFunc{
+		Authorize(entity.RoleAdmin, entity.ScopeProductsDelete),
+		RateLimit(s.limiter, "write", s.rateLimits.Write),
+	}
 
-	v1 := s.engine.Group("api/v1")
-	v1.GET("/products/", ph.GetAllProducts)
-	v1.GET("/products/:sku", ph.GetProductBySku)
-	v1.POST("/products", ph.CreateProduct)
-	v1.PUT("/products/:sku", ph.UpdateProduct)
-	v1.DELETE("/products/:sku", ph.Delete)
+	// Clients are throttled by IP before their credentials are looked up, so
+	// floods of missing or invalid credentials do not reach the database.
+	client := RateLimit(s.limiter, "client", s.rateLimits.Client)
+	v1 := s.engine.Group("api/v1", client, RequestDeadline(s.timeouts.Request), Authenticate(s.keys, s.tokens))
+	v1.GET("/products/", Authorize(entity.RoleReader, entity.ScopeProductsRead), ListingRateLimit(s.limiter, s.rateLimits),
+		CacheControl(s.cachePolicies.Products), ph.GetAllProducts)
+	v1.GET("/products/:sku", append(read, CacheControl(s.cachePolicies.Product), ph.GetProductBySku)...)
+	v1.POST("/products", append(write, ph.CreateProduct)...)
+	v1.POST("/products:method", append(bulk, ph.ProductsMethod)...)
+	v1.PUT("/products/:sku", append(write, ph.UpdateProduct)...)
+	v1.POST("/products/:sku/rename", append(write, ph.RenameProduct)...)
+	v1.POST("/products/:sku/publish", append(write, ph.PublishProduct)...)
+	v1.POST("/products/:sku/discontinue", append(write, ph.DiscontinueProduct)...)
+	v1.DELETE("/products/:sku", append(remove, ph.Delete)...)
+
+	// The stream outlives any request deadline, it ends when the client
+	// disconnects or the server shuts down.
+	stream := s.engine.Group("api/v1", client, Authenticate(s.keys, s.tokens))
+	stream.GET("/products/stream", append(read, sh.StreamProducts)...)
+
+	// Each GraphQL field checks the role or scope it needs, so the endpoint
+	// only requires credentials. Mutations also take from the write limit.
+	graphqlRoutes := s.engine.Group("/graphql", client, RequestDeadline(s.timeouts.Request), Authenticate(s.keys, s.tokens), RateLimit(s.limiter, "read", s.rateLimits.Read))
+	graphqlRoutes.GET("", gh.Query)
+	graphqlRoutes.POST("", gh.Query)
+
+	admin := v1.Group("/admin", Authorize(entity.RoleAdmin, entity.ScopeAdmin), RateLimit(s.limiter, "write", s.rateLimits.Write))
+	admin.POST("/api-keys", ah.IssueAPIKey)
+	admin.GET("/api-keys", ah.ListAPIKeys)
+	admin.DELETE("/api-keys/:id", ah.RevokeAPIKey)
+
+	webhooks := v1.Group("/webhooks", Authorize(entity.RoleAdmin, entity.ScopeAdmin), RateLimit(s.limiter, "write", s.rateLimits.Write))
+	webhooks.POST("", wh.CreateWebhook)
+	webhooks.GET("", wh.ListWebhooks)
+	webhooks.GET("/:id", wh.GetWebhook)
+	webhooks.DELETE("/:id", wh.DeleteWebhook)
+	webhooks.GET("/:id/deliveries", wh.ListDeliveries)
+	webhooks.POST("/:id/deliveries/:delivery_id/redeliver", wh.Redeliver)
+	return nil
+}
+
+// registerServices builds the use cases shared by the HTTP and gRPC
+// servers.
+func (s *Server) registerServices() error {
+	keyService := usecase.NewAPIKeyService(apikey.NewPersistenceAPIKeyRepository(s.dbClient))
+	if s.auth.AdminAPIKey != "" {
+		if err := keyService.EnsureKey(context.Background(), "bootstrap admin", s.auth.AdminAPIKey, entity.RoleAdmin); err != nil {
+			log.WithError(err).Errorln("error trying to seed the bootstrap admin api key")
+		}
+	}
+
+	var tokenValidator usecase.TokenValidator
+	jwtOptions := token.Config().
+		HMACSecret(s.auth.JWTHS256Secret).
+		RSAPublicKeyFile(s.auth.JWTRSAPublicKeyFile).
+		JWKSFile(s.auth.JWTJWKSFile).
+		Audience(s.auth.JWTAudience).
+		Issuer(s.auth.JWTIssuer)
+	if token.MergeOptions(jwtOptions).IsEnabled() {
+		jwtValidator, err := token.NewJWTValidator(jwtOptions)
+		if err != nil {
+			return err
+		}
+		tokenValidator = jwtValidator
+	}
+	s.keys = keyService
+	s.tokens = tokenValidator
+
+	productRepository := cache.NewCachedProductRepository(
+		tracing.NewTracedProductRepository(product.NewPersistenceProductRepository(s.dbClient)),
+	)
+	if err := s.registerCacheMetrics(productRepository); err != nil {
+		return err
+	}
+	unitOfWork := cache.NewCachedUnitOfWork(tracing.NewTracedUnitOfWork(transaction.NewUnitOfWork(s.dbClient)), productRepository)
+	s.products = tracing.NewTracedService(usecase.NewProductService(productRepository, unitOfWork))
+	s.availability = usecase.NewAvailabilityScheduler(productRepository, unitOfWork)
+	return nil
+}
+
+// registerMetrics instruments every route and exposes the DB pool and
+// catalog size. It must run before the routes are registered.
+func (s *Server) registerMetrics() error {
+	s.engine.Use(Instrument(s.metrics))
+
+	db, err := s.dbClient.GetConnection()
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if err := s.metrics.RegisterDBStats(sqlDB, "products"); err != nil {
+		return err
+	}
+	if err := s.metrics.RegisterGauge("products", "Number of stored products.", func() (float64, error) {
+		count, err := product.Count(s.dbClient)
+		return float64(count), err
+	}); err != nil {
+		return err
+	}
+	return s.metrics.RegisterGauge("product_stream_subscribers", "Open product event streams.", func() (float64, error) {
+		return float64(s.stream.Subscribers()), nil
+	})
+}
+
+func (s *Server) registerCacheMetrics(productCache *cache.CachedProductRepository) error {
+	if err := s.metrics.RegisterCounter("product_cache_hits_total", "Product lookups served from cache.", func() float64 {
+		return float64(productCache.Stats().Hits)
+	}); err != nil {
+		return err
+	}
+	return s.metrics.RegisterCounter("product_cache_misses_total", "Product lookups that reached the repository.", func() float64 {
+		return float64(productCache.Stats().Misses)
+	})
 }
@@ -0,0 +1,272 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+	"github.com/yescorihuela/agrak/infrastructure/config"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/connection"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/migration"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/product"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/transaction"
+	"github.com/yescorihuela/agrak/infrastructure/response"
+	"github.com/yescorihuela/agrak/shared/logging"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+// NewCLI returns the command line of the service. Without a subcommand it
+// serves the API, as before subcommands existed.
+func NewCLI() *cli.App {
+	return &cli.App{
+		Name:            "products-api",
+		Usage:           "serve and operate the products API",
+		HideHelpCommand: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "config",
+				Usage: "YAML or TOML configuration file used by every subcommand (env " + config.ConfigFileEnv + ")",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			return Run(withConfigFile(c, nil))
+		},
+		Commands: []*cli.Command{
+			{
+				Name:            "serve",
+				Usage:           "serve the API, accepts every configuration flag (see serve --help)",
+				SkipFlagParsing: true,
+				Action: func(c *cli.Context) error {
+					return Run(withConfigFile(c, c.Args().Slice()))
+				},
+			},
+			migrateCommand(),
+			productsCommand(),
+			{
+				Name:      "import",
+				Usage:     "create or update the products of a JSON array, as written by export",
+				ArgsUsage: "<file|->",
+				Action: withProducts(func(ctx context.Context, pc productCommands, c *cli.Context) error {
+					if c.NArg() != 1 {
+						return errors.New("import needs a file, or - to read from stdin")
+					}
+					input := io.Reader(os.Stdin)
+					if path := c.Args().First(); path != "-" {
+						file, err := os.Open(path)
+						if err != nil {
+							return err
+						}
+						defer file.Close()
+						input = file
+					}
+					return pc.importFrom(ctx, input)
+				}),
+			},
+			{
+				Name:  "export",
+				Usage: "write every product as a JSON array",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "file to write instead of stdout"},
+				},
+				Action: withProducts(func(ctx context.Context, pc productCommands, c *cli.Context) error {
+					path := c.String("output")
+					if path == "" {
+						return pc.exportTo(ctx, pc.out)
+					}
+					file, err := os.Create(path)
+					if err != nil {
+						return err
+					}
+					if err := pc.exportTo(ctx, file); err != nil {
+						file.Close()
+						return err
+					}
+					return file.Close()
+				}),
+			},
+			{
+				Name:  "seed",
+				Usage: "create sample products, skipping the ones that already exist",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "count", Value: 10, Usage: "number of sample products"},
+				},
+				Action: withProducts(func(ctx context.Context, pc productCommands, c *cli.Context) error {
+					return pc.seed(ctx, c.Int("count"))
+				}),
+			},
+		},
+	}
+}
+
+func migrateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "apply or roll back the database migrations",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "up",
+				Usage: "apply every pending migration",
+				Action: withMigrator(func(ctx context.Context, migrator *migration.Migrator, c *cli.Context) error {
+					return migrator.Up(ctx)
+				}),
+			},
+			{
+				Name:  "down",
+				Usage: "roll back the last applied migration",
+				Action: withMigrator(func(ctx context.Context, migrator *migration.Migrator, c *cli.Context) error {
+					return migrator.Down(ctx)
+				}),
+			},
+			{
+				Name:      "to",
+				Usage:     "apply or roll back migrations until version is the current one, 0 rolls back everything",
+				ArgsUsage: "<version>",
+				Action: withMigrator(func(ctx context.Context, migrator *migration.Migrator, c *cli.Context) error {
+					version, err := strconv.Atoi(c.Args().First())
+					if err != nil || version < 0 || c.NArg() != 1 {
+						return fmt.Errorf("invalid migration version %q", c.Args().First())
+					}
+					return migrator.To(ctx, version)
+				}),
+			},
+			{
+				Name:  "status",
+				Usage: "list the migrations and when they were applied",
+				Action: withMigrator(func(ctx context.Context, migrator *migration.Migrator, c *cli.Context) error {
+					statuses, err := migrator.Status(ctx)
+					if err != nil {
+						return err
+					}
+					return printMigrationStatus(c.App.Writer, statuses)
+				}),
+			},
+		},
+	}
+}
+
+func productsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "products",
+		Usage: "read and change products without going through the HTTP API",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "get",
+				Usage:     "print one product as JSON",
+				ArgsUsage: "<sku>",
+				Action: withProducts(func(ctx context.Context, pc productCommands, c *cli.Context) error {
+					if c.NArg() != 1 {
+						return errors.New("get needs a sku")
+					}
+					return pc.get(ctx, c.Args().First())
+				}),
+			},
+			{
+				Name:  "list",
+				Usage: "print every product as JSON",
+				Action: withProducts(func(ctx context.Context, pc productCommands, c *cli.Context) error {
+					return pc.exportTo(ctx, pc.out)
+				}),
+			},
+			{
+				Name:  "create",
+				Usage: "create a product",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "sku", Required: true},
+					&cli.StringFlag{Name: "name", Required: true},
+					&cli.StringFlag{Name: "brand", Required: true},
+					&cli.StringFlag{Name: "size", Usage: "defaults to ST"},
+					&cli.Float64Flag{Name: "price", Required: true},
+					&cli.StringFlag{Name: "principal-image", Required: true},
+					&cli.StringSliceFlag{Name: "other-image", Usage: "additional image URL, may be repeated"},
+				},
+				Action: withProducts(func(ctx context.Context, pc productCommands, c *cli.Context) error {
+					return pc.create(ctx, response.DTOProduct{
+						Sku:            c.String("sku"),
+						Name:           c.String("name"),
+						Brand:          c.String("brand"),
+						Size:           c.String("size"),
+						Price:          c.Float64("price"),
+						PrincipalImage: c.String("principal-image"),
+						OtherImages:    c.StringSlice("other-image"),
+					})
+				}),
+			},
+			{
+				Name:      "delete",
+				Usage:     "delete a product",
+				ArgsUsage: "<sku>",
+				Action: withProducts(func(ctx context.Context, pc productCommands, c *cli.Context) error {
+					if c.NArg() != 1 {
+						return errors.New("delete needs a sku")
+					}
+					return pc.delete(ctx, c.Args().First())
+				}),
+			},
+		},
+	}
+}
+
+// withConfigFile passes the global --config flag on to config.Load.
+func withConfigFile(c *cli.Context, args []string) []string {
+	if path := c.String("config"); path != "" {
+		return append([]string{"--config", path}, args...)
+	}
+	return args
+}
+
+// openDatabase loads the configuration, without flags other than --config,
+// and connects to the database.
+func openDatabase(c *cli.Context) (*connection.PostgresqlConnection, *config.Config, error) {
+	cfg, err := config.Load(withConfigFile(c, nil))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := logging.Configure(cfg.Log.Level, cfg.Log.Format); err != nil {
+		return nil, nil, err
+	}
+	dbClient := NewDatabase(cfg.Database)
+	if err := dbClient.Connect(c.Context); err != nil {
+		return nil, nil, err
+	}
+	return dbClient, cfg, nil
+}
+
+func withMigrator(fn func(ctx context.Context, migrator *migration.Migrator, c *cli.Context) error) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		dbClient, _, err := openDatabase(c)
+		if err != nil {
+			return err
+		}
+		defer dbClient.Close()
+		migrator, err := migration.NewMigrator(dbClient)
+		if err != nil {
+			return err
+		}
+		return fn(c.Context, migrator, c)
+	}
+}
+
+// withProducts runs fn against the product use case backed directly by
+// PostgreSQL. Running servers keep their cached copies until they expire.
+func withProducts(fn func(ctx context.Context, pc productCommands, c *cli.Context) error) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		dbClient, cfg, err := openDatabase(c)
+		if err != nil {
+			return err
+		}
+		defer dbClient.Close()
+		migrator, err := migration.NewMigrator(dbClient)
+		if err != nil {
+			return err
+		}
+		if err := prepareSchema(c.Context, migrator, cfg.Database.Migrations); err != nil {
+			return err
+		}
+		service := usecase.NewProductService(product.NewPersistenceProductRepository(dbClient), transaction.NewUnitOfWork(dbClient))
+		return fn(c.Context, productCommands{service: service, out: c.App.Writer}, c)
+	}
+}
@@ -0,0 +1,68 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yescorihuela/agrak/infrastructure/config"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/connection"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/migration"
+)
+
+// NewDatabase builds the PostgreSQL connection described by cfg. The pool is
+// opened on first use or by Connect.
+func NewDatabase(cfg config.DatabaseConfig) *connection.PostgresqlConnection {
+	return connection.NewPostgreSQLConnection(connection.Config().
+		Server(cfg.Host).
+		Port(cfg.Port).
+		DatabaseName(cfg.Name).
+		User(cfg.User).
+		Password(cfg.Password).
+		SSLMode(cfg.SSLMode).
+		SSLRootCert(cfg.SSLRootCert).
+		SSLCert(cfg.SSLCert).
+		SSLKey(cfg.SSLKey).
+		ConnectTimeout(cfg.ConnectTimeout).
+		StatementTimeout(cfg.StatementTimeout).
+		Pool(connection.PoolOptions{
+			MaxOpenConns:    cfg.MaxOpenConns,
+			MaxIdleConns:    cfg.MaxIdleConns,
+			ConnMaxLifetime: cfg.ConnMaxLifetime,
+			ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+		}).
+		Retry(connection.RetryOptions{
+			Attempts:       cfg.ConnectRetries,
+			InitialBackoff: cfg.ConnectBackoff,
+			MaxBackoff:     connection.PostgresqlDefaultRetry.MaxBackoff,
+		}))
+}
+
+// prepareSchema applies pending migrations in auto mode and refuses to
+// serve when the schema does not match this build.
+func prepareSchema(ctx context.Context, migrator *migration.Migrator, mode string) error {
+	if mode == config.MigrationsAuto {
+		if err := migrator.Up(ctx); err != nil {
+			return err
+		}
+	}
+	if err := migrator.Check(ctx); err != nil {
+		return fmt.Errorf("refusing to serve: %w; run the migrate command or set DB_MIGRATIONS=auto", err)
+	}
+	return nil
+}
+
+func printMigrationStatus(out io.Writer, statuses []migration.Status) error {
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return writer.Flush()
+}
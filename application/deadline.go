@@ -0,0 +1,48 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yescorihuela/agrak/infrastructure/response"
+)
+
+// StatusClientClosedRequest is logged for requests whose client went away
+// before a response was written. It is never seen by the client.
+const StatusClientClosedRequest = 499
+
+// RequestDeadline bounds the time handlers may spend on a request. Queries
+// run with the request context are cancelled once it expires, and also as
+// soon as the client disconnects.
+func RequestDeadline(timeout time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		deadlineCtx, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+		defer cancel()
+		ctx.Request = ctx.Request.WithContext(deadlineCtx)
+		ctx.Next()
+	}
+}
+
+// abortOnContextError answers 504 Gateway Timeout when err was caused by the
+// request running out of time, and stops without a body when it was caused
+// by the client disconnecting. It reports whether the request was aborted.
+// Drivers do not always wrap the context error, so the request context is
+// checked as well.
+func abortOnContextError(ctx *gin.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	requestErr := ctx.Request.Context().Err()
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(requestErr, context.DeadlineExceeded):
+		ctx.AbortWithStatusJSON(http.StatusGatewayTimeout, response.NewErrorResponse("request timed out"))
+		return true
+	case errors.Is(err, context.Canceled) || errors.Is(requestErr, context.Canceled):
+		ctx.AbortWithStatus(StatusClientClosedRequest)
+		return true
+	}
+	return false
+}
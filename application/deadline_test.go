@@ -0,0 +1,71 @@
+package application
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+func TestRequestDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestDeadline(20 * time.Millisecond))
+	router.GET("/slow", func(ctx *gin.Context) {
+		<-ctx.Request.Context().Done()
+		if abortOnContextError(ctx, ctx.Request.Context().Err()) {
+			return
+		}
+		ctx.Status(http.StatusOK)
+	})
+
+	rr := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/slow", nil)
+	started := time.Now()
+	router.ServeHTTP(rr, request)
+
+	assert.Equal(t, http.StatusGatewayTimeout, rr.Code)
+	assert.JSONEq(t, `{"message":"request timed out"}`, rr.Body.String())
+	assert.Less(t, int64(time.Since(started)), int64(time.Second))
+}
+
+func TestProductHandlersContextErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sku := "FAL-1000000"
+
+	t.Run("should answer 504 when the lookup timed out", func(t *testing.T) {
+		mockUsecase := new(usecase.UseCaseMock)
+		mockUsecase.On("FindBySku", sku).Return(nil, context.DeadlineExceeded)
+		router := gin.New()
+		router.GET("/products/:sku", NewProductHandlers(mockUsecase).GetProductBySku)
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/products/"+sku, nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusGatewayTimeout, rr.Code)
+		mockUsecase.AssertExpectations(t)
+	})
+
+	t.Run("should stop without a body when the client went away", func(t *testing.T) {
+		mockUsecase := new(usecase.UseCaseMock)
+		mockUsecase.On("DeleteProduct", sku).Return(context.Canceled)
+		router := gin.New()
+		router.DELETE("/products/:sku", NewProductHandlers(mockUsecase).Delete)
+
+		requestCtx, cancel := context.WithCancel(context.Background())
+		cancel()
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequestWithContext(requestCtx, http.MethodDelete, "/products/"+sku, nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, StatusClientClosedRequest, rr.Code)
+		assert.Empty(t, rr.Body.String())
+		mockUsecase.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,160 @@
+package application
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// GraphQLLimits bounds the work a single GraphQL operation can ask for.
+// Complexity counts one per selected field, with the selection of a
+// paginated field counted once per requested item. Depth counts nested
+// fields. Introspection fields are not counted.
+type GraphQLLimits struct {
+	MaxComplexity int
+	MaxDepth      int
+}
+
+// paginatedFields holds the default page size of the fields taking a
+// "first" argument.
+var paginatedFields = map[string]int{
+	"products": defaultConnectionSize,
+}
+
+type queryCost struct {
+	complexity int
+	depth      int
+}
+
+// checkLimits measures the operations of document that would run for
+// operationName and returns an error when one goes over limits.
+func checkLimits(document *ast.Document, operationName string, variables map[string]interface{}, limits GraphQLLimits) error {
+	fragments := make(map[string]*ast.FragmentDefinition)
+	operations := make([]*ast.OperationDefinition, 0)
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operations = append(operations, definition)
+			}
+		}
+	}
+	for _, operation := range operations {
+		measurer := &costMeasurer{fragments: fragments, variables: withDefaults(operation, variables)}
+		cost := measurer.selectionSet(operation.SelectionSet, map[string]bool{})
+		if limits.MaxDepth > 0 && cost.depth > limits.MaxDepth {
+			return fmt.Errorf("query depth %d exceeds the maximum of %d", cost.depth, limits.MaxDepth)
+		}
+		if limits.MaxComplexity > 0 && cost.complexity > limits.MaxComplexity {
+			return fmt.Errorf("query complexity %d exceeds the maximum of %d", cost.complexity, limits.MaxComplexity)
+		}
+	}
+	return nil
+}
+
+// withDefaults adds the default values operation declares for the
+// variables missing from variables.
+func withDefaults(operation *ast.OperationDefinition, variables map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{}, len(variables))
+	for name, value := range variables {
+		values[name] = value
+	}
+	for _, definition := range operation.VariableDefinitions {
+		name := definition.Variable.Name.Value
+		if _, ok := values[name]; ok {
+			continue
+		}
+		if value, ok := definition.DefaultValue.(*ast.IntValue); ok {
+			if size, err := strconv.Atoi(value.Value); err == nil {
+				values[name] = size
+			}
+		}
+	}
+	return values
+}
+
+type costMeasurer struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// selectionSet measures selections; spread holds the fragments already
+// being measured, so that cycles, rejected by validation anyway, end.
+func (m *costMeasurer) selectionSet(selections *ast.SelectionSet, spread map[string]bool) queryCost {
+	total := queryCost{}
+	if selections == nil {
+		return total
+	}
+	for _, selection := range selections.Selections {
+		var cost queryCost
+		switch selection := selection.(type) {
+		case *ast.Field:
+			cost = m.field(selection, spread)
+		case *ast.InlineFragment:
+			cost = m.selectionSet(selection.SelectionSet, spread)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := m.fragments[name]
+			if !ok || spread[name] {
+				continue
+			}
+			spread[name] = true
+			cost = m.selectionSet(fragment.SelectionSet, spread)
+			delete(spread, name)
+		}
+		total.complexity += cost.complexity
+		if cost.depth > total.depth {
+			total.depth = cost.depth
+		}
+	}
+	return total
+}
+
+func (m *costMeasurer) field(field *ast.Field, spread map[string]bool) queryCost {
+	if strings.HasPrefix(field.Name.Value, "__") {
+		return queryCost{}
+	}
+	children := m.selectionSet(field.SelectionSet, spread)
+	if pageSize, ok := paginatedFields[field.Name.Value]; ok {
+		pageSize = m.pageSize(field, pageSize)
+		children.complexity *= pageSize
+	}
+	return queryCost{
+		complexity: 1 + children.complexity,
+		depth:      1 + children.depth,
+	}
+}
+
+// pageSize returns the "first" argument of field, as the resolver will see
+// it, or pageSize when it is not given.
+func (m *costMeasurer) pageSize(field *ast.Field, pageSize int) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			if size, err := strconv.Atoi(value.Value); err == nil {
+				pageSize = size
+			}
+		case *ast.Variable:
+			switch size := m.variables[value.Name.Value].(type) {
+			case float64:
+				pageSize = int(size)
+			case int:
+				pageSize = size
+			}
+		}
+	}
+	if pageSize > maxConnectionSize {
+		return maxConnectionSize
+	}
+	if pageSize < 1 {
+		return 1
+	}
+	return pageSize
+}
@@ -0,0 +1,143 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+// maxGraphQLBodyBytes bounds the size of a GraphQL request body.
+const maxGraphQLBodyBytes = 1 << 20
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type GraphQLHandlers struct {
+	schema        graphql.Schema
+	service       usecase.Service
+	limits        GraphQLLimits
+	allowMutation func(ctx *gin.Context) bool
+}
+
+// NewGraphQLHandlers serves the schema of NewGraphQLSchema. allowMutation,
+// when set, is asked before running a mutation and is expected to abort the
+// request when it returns false, which lets mutations use the write rate
+// limit while queries only go through the one of the route.
+func NewGraphQLHandlers(service usecase.Service, limits GraphQLLimits, allowMutation func(ctx *gin.Context) bool) (*GraphQLHandlers, error) {
+	schema, err := NewGraphQLSchema(service)
+	if err != nil {
+		return nil, err
+	}
+	return &GraphQLHandlers{
+		schema:        schema,
+		service:       service,
+		limits:        limits,
+		allowMutation: allowMutation,
+	}, nil
+}
+
+// Query godoc
+// @Summary Run a GraphQL operation
+// @Description run a query or mutation against the product schema. Queries may also be sent with GET and the query, operationName and variables parameters. Operations over the complexity or depth limits are rejected before they run.
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @param request body object true "query, operationName and variables"
+// @Success 200 {object} object "data and errors of the operation"
+// @Failure 400 {object} object "errors of a request that could not run"
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 405 {object} object "mutation sent with GET"
+// @Failure 429 {object} response.ErrorResponse
+// @Router /graphql [post]
+func (gh *GraphQLHandlers) Query(ctx *gin.Context) {
+	request, err := gh.readRequest(ctx)
+	if err != nil {
+		abortGraphQL(ctx, http.StatusBadRequest, err)
+		return
+	}
+	document, err := parser.Parse(parser.ParseParams{Source: request.Query})
+	if err != nil {
+		abortGraphQL(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if validation := graphql.ValidateDocument(&gh.schema, document, nil); !validation.IsValid {
+		ctx.JSON(http.StatusBadRequest, &graphql.Result{Errors: validation.Errors})
+		return
+	}
+	if err := checkLimits(document, request.OperationName, request.Variables, gh.limits); err != nil {
+		abortGraphQL(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if hasMutation(document, request.OperationName) {
+		if ctx.Request.Method == http.MethodGet {
+			abortGraphQL(ctx, http.StatusMethodNotAllowed, gqlerrors.NewFormattedError("mutations must be sent with POST"))
+			return
+		}
+		if gh.allowMutation != nil && !gh.allowMutation(ctx) {
+			return
+		}
+	}
+
+	requestCtx := ctx.Request.Context()
+	requestCtx = context.WithValue(requestCtx, loaderKey{}, newProductLoader(requestCtx, gh.service))
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        gh.schema,
+		AST:           document,
+		OperationName: request.OperationName,
+		Args:          request.Variables,
+		Context:       requestCtx,
+	})
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (gh *GraphQLHandlers) readRequest(ctx *gin.Context) (*graphQLRequest, error) {
+	request := &graphQLRequest{}
+	if ctx.Request.Method == http.MethodGet {
+		request.Query = ctx.Query("query")
+		request.OperationName = ctx.Query("operationName")
+		if variables := ctx.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				return nil, gqlerrors.NewFormattedError("variables must be a JSON object")
+			}
+		}
+	} else {
+		body := http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxGraphQLBodyBytes)
+		if err := json.NewDecoder(body).Decode(request); err != nil {
+			return nil, gqlerrors.NewFormattedError("the body must be a JSON object with a query")
+		}
+	}
+	if request.Query == "" {
+		return nil, gqlerrors.NewFormattedError("query is required")
+	}
+	return request, nil
+}
+
+// hasMutation tells whether the operation of document run for
+// operationName is a mutation.
+func hasMutation(document *ast.Document, operationName string) bool {
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok || (operationName != "" && (operation.Name == nil || operation.Name.Value != operationName)) {
+			continue
+		}
+		if operation.Operation == ast.OperationTypeMutation {
+			return true
+		}
+	}
+	return false
+}
+
+func abortGraphQL(ctx *gin.Context, status int, err error) {
+	ctx.AbortWithStatusJSON(status, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+}
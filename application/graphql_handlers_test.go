@@ -0,0 +1,209 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/infrastructure/memory"
+	"github.com/yescorihuela/agrak/shared/identity"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+type graphQLResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func newGraphQLTestEngine(t *testing.T, service usecase.Service, role entity.Role, limits GraphQLLimits) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	handlers, err := NewGraphQLHandlers(service, limits, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	engine := gin.New()
+	engine.Use(func(ctx *gin.Context) {
+		principal := &entity.Principal{Subject: "test", Method: entity.AuthMethodAPIKey, Role: role}
+		ctx.Request = ctx.Request.WithContext(identity.WithPrincipal(ctx.Request.Context(), principal))
+	})
+	engine.GET("/graphql", handlers.Query)
+	engine.POST("/graphql", handlers.Query)
+	return engine
+}
+
+// newGraphQLTestService stores skus as active products.
+func newGraphQLTestService(t *testing.T, skus ...string) usecase.Service {
+	products := memory.NewProductRepository()
+	service := usecase.NewProductService(products, memory.NewUnitOfWork(products, memory.NewOutboxRepository()))
+	for _, sku := range skus {
+		_, err := service.CreateProduct(context.Background(), commandProduct(sku))
+		assert.NoError(t, err)
+		_, err = service.ChangeStatus(context.Background(), sku, entity.StatusActive, nil)
+		assert.NoError(t, err)
+	}
+	return service
+}
+
+func postGraphQL(engine *gin.Engine, query string, variables map[string]interface{}) (int, graphQLResponse) {
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	request := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	var response graphQLResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+	return recorder.Code, response
+}
+
+func TestGraphQLHandlers_Query(t *testing.T) {
+	limits := GraphQLLimits{MaxComplexity: 1000, MaxDepth: 10}
+
+	t.Run("should batch the product lookups of a request", func(t *testing.T) {
+		serviceMock := new(usecase.UseCaseMock)
+		first, second := commandProduct("FAL-1000000"), commandProduct("FAL-1000001")
+		first.Status, second.Status = entity.StatusActive, entity.StatusActive
+		serviceMock.On("FindBySkus", []string{"FAL-1000000", "FAL-1000001", "FAL-9999999"}).
+			Return([]entity.Product{first, second}, nil).Once()
+		engine := newGraphQLTestEngine(t, serviceMock, entity.RoleReader, limits)
+
+		status, response := postGraphQL(engine, `{
+			first: product(sku: "FAL-1000000") { sku }
+			second: product(sku: "FAL-1000001") { sku brand }
+			again: product(sku: "FAL-1000000") { name }
+			missing: product(sku: "FAL-9999999") { sku }
+		}`, nil)
+
+		assert.Equal(t, http.StatusOK, status)
+		assert.Empty(t, response.Errors)
+		assert.Equal(t, map[string]interface{}{"sku": "FAL-1000000"}, response.Data["first"])
+		assert.Equal(t, map[string]interface{}{"sku": "FAL-1000001", "brand": "CAT"}, response.Data["second"])
+		assert.Equal(t, map[string]interface{}{"name": "Polera"}, response.Data["again"])
+		assert.Nil(t, response.Data["missing"])
+		serviceMock.AssertNumberOfCalls(t, "FindBySkus", 1)
+	})
+
+	t.Run("should page through filtered products", func(t *testing.T) {
+		engine := newGraphQLTestEngine(t, newGraphQLTestService(t, "FAL-1000000", "FAL-1000001", "FAL-1000002", "FAL-2000000"), entity.RoleReader, limits)
+		query := `query Page($after: String) {
+			products(filter: {skuPrefix: "FAL-1"}, first: 2, after: $after) {
+				edges { node { sku } }
+				pageInfo { hasNextPage endCursor }
+			}
+		}`
+
+		status, response := postGraphQL(engine, query, nil)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Empty(t, response.Errors)
+		connection := response.Data["products"].(map[string]interface{})
+		assert.Len(t, connection["edges"], 2)
+		pageInfo := connection["pageInfo"].(map[string]interface{})
+		assert.Equal(t, true, pageInfo["hasNextPage"])
+
+		_, response = postGraphQL(engine, query, map[string]interface{}{"after": pageInfo["endCursor"]})
+		connection = response.Data["products"].(map[string]interface{})
+		edges := connection["edges"].([]interface{})
+		assert.Len(t, edges, 1)
+		assert.Equal(t, map[string]interface{}{"node": map[string]interface{}{"sku": "FAL-1000002"}}, edges[0])
+		assert.Equal(t, false, connection["pageInfo"].(map[string]interface{})["hasNextPage"])
+	})
+
+	t.Run("should reject queries over the complexity limit before running them", func(t *testing.T) {
+		serviceMock := new(usecase.UseCaseMock)
+		engine := newGraphQLTestEngine(t, serviceMock, entity.RoleReader, GraphQLLimits{MaxComplexity: 100, MaxDepth: 10})
+
+		status, response := postGraphQL(engine, `query Big($first: Int = 50) {
+			products(first: $first) { edges { node { sku name } } }
+		}`, nil)
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "query complexity 201 exceeds the maximum of 100", response.Errors[0].Message)
+		serviceMock.AssertNotCalled(t, "FindPage", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should reject queries over the depth limit", func(t *testing.T) {
+		engine := newGraphQLTestEngine(t, newGraphQLTestService(t), entity.RoleReader, GraphQLLimits{MaxComplexity: 1000, MaxDepth: 3})
+
+		status, response := postGraphQL(engine, `{ products { ...page } }
+			fragment page on ProductConnection { edges { node { sku } } }`, nil)
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "query depth 4 exceeds the maximum of 3", response.Errors[0].Message)
+	})
+
+	t.Run("should create, update and delete products", func(t *testing.T) {
+		engine := newGraphQLTestEngine(t, newGraphQLTestService(t), entity.RoleAdmin, limits)
+		input := map[string]interface{}{
+			"sku":            "FAL-1000000",
+			"name":           "Polera",
+			"brand":          "CAT",
+			"size":           "XL",
+			"price":          20000,
+			"principalImage": "https://placehold.jp/3d4070/ffffff/150x150.png",
+		}
+
+		status, response := postGraphQL(engine, `mutation ($input: ProductInput!) { createProduct(input: $input) { sku status } }`,
+			map[string]interface{}{"input": input})
+		assert.Equal(t, http.StatusOK, status)
+		assert.Empty(t, response.Errors)
+		assert.Equal(t, map[string]interface{}{"sku": "FAL-1000000", "status": "DRAFT"}, response.Data["createProduct"])
+
+		input["price"] = 25000
+		_, response = postGraphQL(engine, `mutation ($input: ProductInput!) { updateProduct(sku: "FAL-1000000", input: $input) { price } }`,
+			map[string]interface{}{"input": input})
+		assert.Empty(t, response.Errors)
+		assert.Equal(t, map[string]interface{}{"price": float64(25000)}, response.Data["updateProduct"])
+
+		_, response = postGraphQL(engine, `mutation { deleteProduct(sku: "FAL-1000000") }`, nil)
+		assert.Empty(t, response.Errors)
+		assert.Equal(t, true, response.Data["deleteProduct"])
+
+		_, response = postGraphQL(engine, `{ product(sku: "FAL-1000000") { sku } }`, nil)
+		assert.Nil(t, response.Data["product"])
+	})
+
+	t.Run("should report invalid products and duplicated skus", func(t *testing.T) {
+		engine := newGraphQLTestEngine(t, newGraphQLTestService(t, "FAL-1000000"), entity.RoleEditor, limits)
+		mutation := `mutation ($sku: String!) {
+			createProduct(input: {sku: $sku, name: "Polera", brand: "CAT", price: 20000, principalImage: "https://placehold.jp/3d4070/ffffff/150x150.png"}) { sku }
+		}`
+
+		_, response := postGraphQL(engine, mutation, map[string]interface{}{"sku": "FAL-1000000"})
+		assert.Equal(t, "CONFLICT", response.Errors[0].Extensions["code"])
+
+		_, response = postGraphQL(engine, mutation, map[string]interface{}{"sku": "FAL-1"})
+		assert.Equal(t, "BAD_USER_INPUT", response.Errors[0].Extensions["code"])
+	})
+
+	t.Run("should check the role of each field", func(t *testing.T) {
+		engine := newGraphQLTestEngine(t, newGraphQLTestService(t, "FAL-1000000"), entity.RoleEditor, limits)
+
+		_, response := postGraphQL(engine, `mutation { deleteProduct(sku: "FAL-1000000") }`, nil)
+
+		assert.Equal(t, "the admin role is required", response.Errors[0].Message)
+		assert.Equal(t, "FORBIDDEN", response.Errors[0].Extensions["code"])
+	})
+
+	t.Run("should only run queries sent with GET", func(t *testing.T) {
+		engine := newGraphQLTestEngine(t, newGraphQLTestService(t, "FAL-1000000"), entity.RoleAdmin, limits)
+
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(`{ product(sku: "FAL-1000000") { sku } }`), nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"data":{"product":{"sku":"FAL-1000000"}}}`, recorder.Body.String())
+
+		recorder = httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(`mutation { deleteProduct(sku: "FAL-1000000") }`), nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	})
+}
@@ -0,0 +1,95 @@
+package application
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+// productLoader batches the product lookups of one GraphQL request. Load
+// only records the SKU and returns a thunk; the executor resolves thunks
+// after every sibling field has been visited, so the first one to run
+// fetches all the SKUs recorded so far with a single FindBySkus call.
+// Products the caller cannot see, see visibleFilter, resolve to nil.
+type productLoader struct {
+	ctx     context.Context
+	service usecase.Service
+	visible repository.ProductFilter
+
+	mutex    sync.Mutex
+	pending  []string
+	products map[string]*entity.Product
+	failures map[string]error
+}
+
+func newProductLoader(ctx context.Context, service usecase.Service) *productLoader {
+	visible, _ := visibleFilter(ctx, "")
+	return &productLoader{
+		ctx:      ctx,
+		service:  service,
+		visible:  visible,
+		products: make(map[string]*entity.Product),
+		failures: make(map[string]error),
+	}
+}
+
+// Load returns a thunk resolving to the product stored under sku, or to nil
+// when there is none.
+func (l *productLoader) Load(sku string) func() (interface{}, error) {
+	l.mutex.Lock()
+	if !l.loaded(sku) && !l.isPending(sku) {
+		l.pending = append(l.pending, sku)
+	}
+	l.mutex.Unlock()
+
+	return func() (interface{}, error) {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		if !l.loaded(sku) {
+			l.dispatch()
+		}
+		if err := l.failures[sku]; err != nil {
+			return nil, err
+		}
+		if product := l.products[sku]; product != nil {
+			return productNode(*product), nil
+		}
+		return nil, nil
+	}
+}
+
+func (l *productLoader) dispatch() {
+	skus := l.pending
+	l.pending = nil
+	sort.Strings(skus)
+	products, err := l.service.FindBySkus(l.ctx, skus)
+	for _, sku := range skus {
+		l.products[sku] = nil
+		if err != nil {
+			l.failures[sku] = err
+		}
+	}
+	for i := range products {
+		if l.visible.Matches(products[i]) {
+			l.products[products[i].Sku] = &products[i]
+		}
+	}
+}
+
+func (l *productLoader) loaded(sku string) bool {
+	_, ok := l.products[sku]
+	return ok
+}
+
+func (l *productLoader) isPending(sku string) bool {
+	for _, pending := range l.pending {
+		if pending == sku {
+			return true
+		}
+	}
+	return false
+}
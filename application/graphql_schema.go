@@ -0,0 +1,405 @@
+package application
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/factory"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/shared/identity"
+	"github.com/yescorihuela/agrak/shared/logging"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+const (
+	defaultConnectionSize = 20
+	maxConnectionSize     = 100
+)
+
+type loaderKey struct{}
+
+// graphQLError is reported to clients with its code in the extensions of
+// the error.
+type graphQLError struct {
+	message string
+	code    string
+}
+
+func (e graphQLError) Error() string {
+	return e.message
+}
+
+func (e graphQLError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+var productStatusType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "ProductStatus",
+	Values: graphql.EnumValueConfigMap{
+		"DRAFT":        &graphql.EnumValueConfig{Value: entity.StatusDraft},
+		"ACTIVE":       &graphql.EnumValueConfig{Value: entity.StatusActive},
+		"DISCONTINUED": &graphql.EnumValueConfig{Value: entity.StatusDiscontinued},
+	},
+})
+
+var productType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Product",
+	Fields: graphql.Fields{
+		"sku":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"name":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"brand":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"size":           &graphql.Field{Type: graphql.String},
+		"price":          &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"principalImage": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"otherImages":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+		"status":         &graphql.Field{Type: productStatusType},
+		"availableFrom":  &graphql.Field{Type: graphql.String, Description: "RFC 3339 time the availability window opens."},
+		"availableUntil": &graphql.Field{Type: graphql.String, Description: "RFC 3339 time the availability window closes."},
+		"createdAt":      &graphql.Field{Type: graphql.String, Description: "RFC 3339 creation time."},
+		"updatedAt":      &graphql.Field{Type: graphql.String, Description: "RFC 3339 time of the last update."},
+	},
+})
+
+var productEdgeType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ProductEdge",
+	Fields: graphql.Fields{
+		"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"node":   &graphql.Field{Type: graphql.NewNonNull(productType)},
+	},
+})
+
+var pageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"endCursor":   &graphql.Field{Type: graphql.String},
+	},
+})
+
+var productConnectionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ProductConnection",
+	Fields: graphql.Fields{
+		"edges":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(productEdgeType)))},
+		"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+	},
+})
+
+var productFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ProductFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"brand":     &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Case-insensitive brand."},
+		"skuPrefix": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"status":    &graphql.InputObjectFieldConfig{Type: productStatusType, Description: "Any other than ACTIVE needs the admin role."},
+	},
+})
+
+var productInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "ProductInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"sku":            &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"name":           &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"brand":          &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"size":           &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"price":          &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+		"principalImage": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"otherImages":    &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+		"availableFrom":  &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "RFC 3339 time the availability window opens."},
+		"availableUntil": &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "RFC 3339 time the availability window closes."},
+	},
+})
+
+// productResolvers resolves the root fields of the schema with service.
+type productResolvers struct {
+	service usecase.Service
+}
+
+// NewGraphQLSchema exposes usecase.Service as the product queries and
+// mutations served at /graphql. Every root field checks the caller against
+// the same role and scope as the matching REST route.
+func NewGraphQLSchema(service usecase.Service) (graphql.Schema, error) {
+	resolvers := &productResolvers{service: service}
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"product": &graphql.Field{
+				Type: productType,
+				Args: graphql.FieldConfigArgument{
+					"sku": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: authorized(entity.RoleReader, entity.ScopeProductsRead, resolvers.product),
+			},
+			"products": &graphql.Field{
+				Type: graphql.NewNonNull(productConnectionType),
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: productFilterType},
+					"first": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: defaultConnectionSize,
+						Description:  "Page size, at most 100.",
+					},
+					"after": &graphql.ArgumentConfig{Type: graphql.String, Description: "endCursor of the previous page."},
+				},
+				Resolve: authorized(entity.RoleReader, entity.ScopeProductsRead, resolvers.products),
+			},
+		},
+	})
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createProduct": &graphql.Field{
+				Type: graphql.NewNonNull(productType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(productInputType)},
+				},
+				Resolve: authorized(entity.RoleEditor, entity.ScopeProductsWrite, resolvers.createProduct),
+			},
+			"updateProduct": &graphql.Field{
+				Type: graphql.NewNonNull(productType),
+				Args: graphql.FieldConfigArgument{
+					"sku":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(productInputType)},
+				},
+				Resolve: authorized(entity.RoleEditor, entity.ScopeProductsWrite, resolvers.updateProduct),
+			},
+			"deleteProduct": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"sku": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: authorized(entity.RoleAdmin, entity.ScopeProductsDelete, resolvers.deleteProduct),
+			},
+		},
+	})
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+func (r *productResolvers) product(p graphql.ResolveParams) (interface{}, error) {
+	sku, _ := p.Args["sku"].(string)
+	loader, ok := p.Context.Value(loaderKey{}).(*productLoader)
+	if !ok {
+		loader = newProductLoader(p.Context, r.service)
+	}
+	thunk := loader.Load(sku)
+	return func() (interface{}, error) {
+		product, err := thunk()
+		if err != nil {
+			return nil, graphqlError(p.Context, err)
+		}
+		return product, nil
+	}, nil
+}
+
+func (r *productResolvers) products(p graphql.ResolveParams) (interface{}, error) {
+	first, _ := p.Args["first"].(int)
+	switch {
+	case first < 0:
+		return nil, graphQLError{message: "first must not be negative", code: "BAD_USER_INPUT"}
+	case first > maxConnectionSize:
+		first = maxConnectionSize
+	}
+	after, _ := p.Args["after"].(string)
+	afterSku, err := base64.RawURLEncoding.DecodeString(after)
+	if err != nil {
+		return nil, graphQLError{message: "invalid after cursor", code: "BAD_USER_INPUT"}
+	}
+	filter := repository.ProductFilter{}
+	var requestedStatus entity.ProductStatus
+	if input, ok := p.Args["filter"].(map[string]interface{}); ok {
+		filter.Brand, _ = input["brand"].(string)
+		filter.SkuPrefix, _ = input["skuPrefix"].(string)
+		requestedStatus, _ = input["status"].(entity.ProductStatus)
+	}
+	visible, err := listingFilter(p.Context, string(requestedStatus), "")
+	if err != nil {
+		return nil, graphqlError(p.Context, err)
+	}
+	filter.Status, filter.AvailableAt = visible.Status, visible.AvailableAt
+
+	// One more product than asked tells whether there is a next page.
+	products, err := r.service.FindPage(p.Context, filter, string(afterSku), first+1)
+	if err != nil {
+		return nil, graphqlError(p.Context, err)
+	}
+	hasNextPage := len(products) > first
+	if hasNextPage {
+		products = products[:first]
+	}
+	edges := make([]map[string]interface{}, 0, len(products))
+	var endCursor interface{}
+	for _, product := range products {
+		cursor := base64.RawURLEncoding.EncodeToString([]byte(product.Sku))
+		edges = append(edges, map[string]interface{}{
+			"cursor": cursor,
+			"node":   productNode(product),
+		})
+		endCursor = cursor
+	}
+	return map[string]interface{}{
+		"edges": edges,
+		"pageInfo": map[string]interface{}{
+			"hasNextPage": hasNextPage,
+			"endCursor":   endCursor,
+		},
+	}, nil
+}
+
+func (r *productResolvers) createProduct(p graphql.ResolveParams) (interface{}, error) {
+	product, err := newProductFromInput(p.Args["input"])
+	if err != nil {
+		return nil, err
+	}
+	createdProduct, err := r.service.CreateProduct(p.Context, *product)
+	if err != nil {
+		return nil, graphqlError(p.Context, err)
+	}
+	return productNode(*createdProduct), nil
+}
+
+func (r *productResolvers) updateProduct(p graphql.ResolveParams) (interface{}, error) {
+	product, err := newProductFromInput(p.Args["input"])
+	if err != nil {
+		return nil, err
+	}
+	sku, _ := p.Args["sku"].(string)
+	updatedProduct, err := r.service.UpdateProduct(p.Context, sku, *product)
+	if err != nil {
+		return nil, graphqlError(p.Context, err)
+	}
+	return productNode(*updatedProduct), nil
+}
+
+// deleteProduct returns true, also when there was no product under sku.
+func (r *productResolvers) deleteProduct(p graphql.ResolveParams) (interface{}, error) {
+	sku, _ := p.Args["sku"].(string)
+	if err := r.service.DeleteProduct(p.Context, sku); err != nil {
+		return nil, graphqlError(p.Context, err)
+	}
+	return true, nil
+}
+
+// authorized runs resolve when the caller holds role, for API keys, or
+// scope, for bearer tokens, like the Authorize middleware.
+func authorized(role entity.Role, scope string, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		principal := identity.FromContext(p.Context)
+		if principal == nil {
+			return nil, graphQLError{message: "missing credentials", code: "UNAUTHENTICATED"}
+		}
+		if !principal.Allows(role, scope) {
+			message := "the " + string(role) + " role is required"
+			if principal.Method == entity.AuthMethodJWT {
+				message = "the " + scope + " scope is required"
+			}
+			return nil, graphQLError{message: message, code: "FORBIDDEN"}
+		}
+		return resolve(p)
+	}
+}
+
+// graphqlError maps the errors of the use cases to GraphQL errors.
+// Unexpected errors are logged and reported without details.
+func graphqlError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrProductNotFound):
+		return graphQLError{message: "product not found", code: "NOT_FOUND"}
+	case errors.Is(err, repository.ErrDuplicatedProduct):
+		return graphQLError{message: err.Error(), code: "CONFLICT"}
+	case errors.Is(err, usecase.ErrSkuChange):
+		return graphQLError{message: err.Error(), code: "BAD_USER_INPUT"}
+	case errors.Is(err, errStatusForbidden):
+		return graphQLError{message: err.Error(), code: "FORBIDDEN"}
+	case errors.Is(err, context.DeadlineExceeded):
+		return graphQLError{message: "request timed out", code: "TIMEOUT"}
+	case errors.Is(err, context.Canceled):
+		return graphQLError{message: "request cancelled", code: "CANCELLED"}
+	}
+	logging.FromContext(ctx).WithError(err).Errorln("error serving GraphQL request")
+	return graphQLError{message: "internal error", code: "INTERNAL"}
+}
+
+func newProductFromInput(value interface{}) (*entity.Product, error) {
+	input, _ := value.(map[string]interface{})
+	sku, _ := input["sku"].(string)
+	name, _ := input["name"].(string)
+	brand, _ := input["brand"].(string)
+	size, _ := input["size"].(string)
+	price, _ := input["price"].(float64)
+	principalImage, _ := input["principalImage"].(string)
+	otherImages := make([]string, 0)
+	if images, ok := input["otherImages"].([]interface{}); ok {
+		for _, image := range images {
+			if image, ok := image.(string); ok {
+				otherImages = append(otherImages, image)
+			}
+		}
+	}
+	product, err := factory.NewProduct(sku, name, brand, size, price, principalImage, otherImages)
+	if err != nil {
+		return nil, graphQLError{message: err.Error(), code: "BAD_USER_INPUT"}
+	}
+	if product.AvailableFrom, err = parseInputTime(input, "availableFrom"); err != nil {
+		return nil, err
+	}
+	if product.AvailableUntil, err = parseInputTime(input, "availableUntil"); err != nil {
+		return nil, err
+	}
+	if _, err := product.IsValid(); err != nil {
+		return nil, graphQLError{message: err.Error(), code: "BAD_USER_INPUT"}
+	}
+	return product, nil
+}
+
+func productNode(product entity.Product) map[string]interface{} {
+	otherImages := product.OtherImages
+	if otherImages == nil {
+		otherImages = []string{}
+	}
+	return map[string]interface{}{
+		"sku":            product.Sku,
+		"name":           product.Name,
+		"brand":          product.Brand,
+		"size":           product.Size,
+		"price":          product.Price,
+		"principalImage": product.PrincipalImage,
+		"otherImages":    otherImages,
+		"status":         product.Status,
+		"availableFrom":  formatOptionalTime(product.AvailableFrom),
+		"availableUntil": formatOptionalTime(product.AvailableUntil),
+		"createdAt":      formatTime(product.CreatedAt),
+		"updatedAt":      formatTime(product.UpdatedAt),
+	}
+}
+
+// formatTime leaves zero times, of products not read back from storage,
+// null.
+func formatTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatOptionalTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+// parseInputTime returns the RFC 3339 time of the field of input, nil when
+// it is not set.
+func parseInputTime(input map[string]interface{}, field string) (*time.Time, error) {
+	value, _ := input[field].(string)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, graphQLError{message: field + " must be an RFC 3339 date-time", code: "BAD_USER_INPUT"}
+	}
+	return &t, nil
+}
@@ -0,0 +1,296 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	productsv1 "github.com/yescorihuela/agrak/api/grpc/products/v1"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/infrastructure/ratelimit"
+	"github.com/yescorihuela/agrak/shared/identity"
+	"github.com/yescorihuela/agrak/shared/logging"
+	"github.com/yescorihuela/agrak/usecase"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+const apiKeyMetadata = "x-api-key"
+
+// permission is the role, for API keys, or scope, for bearer tokens, a
+// gRPC method requires. They match the HTTP routes.
+type permission struct {
+	role  entity.Role
+	scope string
+}
+
+var grpcPermissions = map[string]permission{
+	"/products.v1.ProductService/CreateProduct": {entity.RoleEditor, entity.ScopeProductsWrite},
+	"/products.v1.ProductService/GetProduct":    {entity.RoleReader, entity.ScopeProductsRead},
+	"/products.v1.ProductService/ListProducts":  {entity.RoleReader, entity.ScopeProductsRead},
+	"/products.v1.ProductService/UpdateProduct": {entity.RoleEditor, entity.ScopeProductsWrite},
+	"/products.v1.ProductService/DeleteProduct": {entity.RoleAdmin, entity.ScopeProductsDelete},
+	"/products.v1.ProductService/WatchProducts": {entity.RoleReader, entity.ScopeProductsRead},
+}
+
+// grpcRateLimitGroups is the rate limit group each gRPC method takes from,
+// like the HTTP routes. Methods without a group, such as reflection, are
+// only throttled by client.
+var grpcRateLimitGroups = map[string]string{
+	"/products.v1.ProductService/CreateProduct": "write",
+	"/products.v1.ProductService/GetProduct":    "read",
+	"/products.v1.ProductService/ListProducts":  "read",
+	"/products.v1.ProductService/UpdateProduct": "write",
+	"/products.v1.ProductService/DeleteProduct": "write",
+	"/products.v1.ProductService/WatchProducts": "read",
+}
+
+// NewGRPCServer serves products over gRPC. Calls are authenticated,
+// authorized and rate limited like the HTTP routes, sharing their buckets
+// in limiter, logged, and unary calls get the request deadline. Server
+// reflection is enabled for tools like grpcurl.
+func NewGRPCServer(products *ProductGRPCServer, keyService usecase.KeyService, tokenValidator usecase.TokenValidator, limiter ratelimit.Limiter, limits RateLimits, requestTimeout time.Duration) *grpc.Server {
+	auth := &grpcAuthenticator{keyService: keyService, tokenValidator: tokenValidator}
+	throttle := &grpcRateLimiter{limiter: limiter, limits: limits}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(logUnary, deadlineUnary(requestTimeout), throttle.clientUnary, auth.unary, throttle.methodUnary),
+		grpc.ChainStreamInterceptor(logStream, throttle.clientStream, auth.stream, throttle.methodStream),
+	)
+	productsv1.RegisterProductServiceServer(server, products)
+	reflection.Register(server)
+	return server
+}
+
+type grpcAuthenticator struct {
+	keyService     usecase.KeyService
+	tokenValidator usecase.TokenValidator
+}
+
+func (a *grpcAuthenticator) unary(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, request)
+}
+
+func (a *grpcAuthenticator) stream(server interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authorize(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(server, &contextStream{ServerStream: stream, ctx: ctx})
+}
+
+// authorize resolves the caller from the metadata of the call, see
+// Authenticate, and checks it may call method. Methods without permissions,
+// such as reflection, are public.
+func (a *grpcAuthenticator) authorize(ctx context.Context, method string) (context.Context, error) {
+	required, ok := grpcPermissions[method]
+	if !ok {
+		return ctx, nil
+	}
+	principal, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !principal.Allows(required.role, required.scope) {
+		message := "the " + string(required.role) + " role is required"
+		if principal.Method == entity.AuthMethodJWT {
+			message = "the " + required.scope + " scope is required"
+		}
+		return nil, status.Error(codes.PermissionDenied, message)
+	}
+	return identity.WithPrincipal(ctx, principal), nil
+}
+
+func (a *grpcAuthenticator) authenticate(ctx context.Context) (*entity.Principal, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if authorization := firstValue(md, "authorization"); strings.HasPrefix(authorization, bearerPrefix) {
+		if a.tokenValidator == nil {
+			return nil, status.Error(codes.Unauthenticated, "bearer tokens are not accepted")
+		}
+		principal, err := a.tokenValidator.Validate(strings.TrimPrefix(authorization, bearerPrefix))
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return principal, nil
+	}
+	rawKey := firstValue(md, apiKeyMetadata)
+	if rawKey == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+	key, err := a.keyService.Authenticate(ctx, rawKey)
+	if errors.Is(err, usecase.ErrInvalidAPIKey) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Errorln("error trying to authenticate api key")
+		return nil, status.Error(codes.Unavailable, "authentication unavailable")
+	}
+	return &entity.Principal{
+		Subject: key.ID,
+		Method:  entity.AuthMethodAPIKey,
+		Role:    key.Role,
+	}, nil
+}
+
+// grpcRateLimiter throttles calls like RateLimit throttles HTTP requests:
+// each client by peer IP before its credentials are looked up, and then
+// each caller by the group of the method.
+type grpcRateLimiter struct {
+	limiter ratelimit.Limiter
+	limits  RateLimits
+}
+
+func (l *grpcRateLimiter) clientUnary(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := l.allow(ctx, "client", l.limits.Client); err != nil {
+		return nil, err
+	}
+	return handler(ctx, request)
+}
+
+func (l *grpcRateLimiter) clientStream(server interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := l.allow(stream.Context(), "client", l.limits.Client); err != nil {
+		return err
+	}
+	return handler(server, stream)
+}
+
+func (l *grpcRateLimiter) methodUnary(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := l.allowMethod(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, request)
+}
+
+func (l *grpcRateLimiter) methodStream(server interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := l.allowMethod(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(server, stream)
+}
+
+func (l *grpcRateLimiter) allowMethod(ctx context.Context, method string) error {
+	switch grpcRateLimitGroups[method] {
+	case "read":
+		return l.allow(ctx, "read", l.limits.Read)
+	case "write":
+		return l.allow(ctx, "write", l.limits.Write)
+	}
+	return nil
+}
+
+// allow takes a call of the caller from the bucket of group, failing with
+// RESOURCE_EXHAUSTED and a retry-after header when the bucket is empty.
+func (l *grpcRateLimiter) allow(ctx context.Context, group string, limit ratelimit.Limit) error {
+	if limit.IsUnlimited() {
+		return nil
+	}
+	result := l.limiter.Allow(group+"|"+grpcRateLimitKey(ctx), limit)
+	if result.Allowed {
+		return nil
+	}
+	retryAfter := result.RetryAfterSeconds()
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfter)))
+	return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry in %d seconds", retryAfter)
+}
+
+// grpcRateLimitKey tells callers apart like rateLimitKey, by their
+// credentials once authenticated and by peer IP otherwise.
+func grpcRateLimitKey(ctx context.Context) string {
+	if principal := identity.FromContext(ctx); principal != nil && principal.Subject != "" {
+		return string(principal.Method) + ":" + principal.Subject
+	}
+	caller, ok := peer.FromContext(ctx)
+	if !ok || caller.Addr == nil {
+		return "ip:"
+	}
+	address := caller.Addr.String()
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+	return "ip:" + address
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// deadlineUnary bounds unary calls like RequestDeadline bounds HTTP
+// requests. A shorter deadline set by the client still applies.
+func deadlineUnary(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, request)
+	}
+}
+
+func logUnary(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	started := time.Now()
+	ctx, logger := withCallLogger(ctx)
+	response, err := handler(ctx, request)
+	logCall(logger, info.FullMethod, started, err)
+	return response, err
+}
+
+func logStream(server interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	started := time.Now()
+	ctx, logger := withCallLogger(stream.Context())
+	err := handler(server, &contextStream{ServerStream: stream, ctx: ctx})
+	logCall(logger, info.FullMethod, started, err)
+	return err
+}
+
+// withCallLogger puts a logger carrying the request ID of the call in its
+// context, like RequestLogger does for HTTP. The ID is taken from a valid
+// x-request-id metadata entry or generated.
+func withCallLogger(ctx context.Context) (context.Context, *log.Entry) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	requestID := firstValue(md, strings.ToLower(RequestIDHeader))
+	if !validRequestID(requestID) {
+		requestID = newRequestID()
+	}
+	logger := log.WithField("request_id", requestID)
+	return logging.WithLogger(ctx, logger), logger
+}
+
+// logCall writes one line per call once it is done.
+func logCall(logger *log.Entry, method string, started time.Time, err error) {
+	code := status.Code(err)
+	entry := logger.WithFields(log.Fields{
+		"method":     method,
+		"code":       code.String(),
+		"latency_ms": float64(time.Since(started).Microseconds()) / 1000,
+	})
+	switch code {
+	case codes.OK:
+		entry.Infoln("request completed")
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		entry.WithError(err).Errorln("request failed")
+	default:
+		entry.WithError(err).Warnln("request rejected")
+	}
+}
+
+// contextStream replaces the context of a server stream.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,257 @@
+package application
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	productsv1 "github.com/yescorihuela/agrak/api/grpc/products/v1"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/factory"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/events"
+	"github.com/yescorihuela/agrak/shared/logging"
+	"github.com/yescorihuela/agrak/usecase"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// ProductGRPCServer exposes usecase.Service over gRPC, see
+// api/grpc/products/v1/products.proto.
+type ProductGRPCServer struct {
+	productsv1.UnimplementedProductServiceServer
+	service     usecase.Service
+	broadcaster *events.Broadcaster
+}
+
+func NewProductGRPCServer(service usecase.Service, broadcaster *events.Broadcaster) *ProductGRPCServer {
+	return &ProductGRPCServer{
+		service:     service,
+		broadcaster: broadcaster,
+	}
+}
+
+func (gs *ProductGRPCServer) CreateProduct(ctx context.Context, request *productsv1.CreateProductRequest) (*productsv1.Product, error) {
+	product, err := newProductFromMessage(request.GetProduct())
+	if err != nil {
+		return nil, err
+	}
+	createdProduct, err := gs.service.CreateProduct(ctx, *product)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return productMessage(*createdProduct), nil
+}
+
+func (gs *ProductGRPCServer) GetProduct(ctx context.Context, request *productsv1.GetProductRequest) (*productsv1.Product, error) {
+	product, err := gs.service.FindBySku(ctx, request.GetSku())
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	visible, err := visibleFilter(ctx, "")
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	if !visible.Matches(*product) {
+		return nil, grpcError(ctx, repository.ErrProductNotFound)
+	}
+	return productMessage(*product), nil
+}
+
+func (gs *ProductGRPCServer) ListProducts(ctx context.Context, request *productsv1.ListProductsRequest) (*productsv1.ListProductsResponse, error) {
+	pageSize := int(request.GetPageSize())
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+	afterSku, err := base64.RawURLEncoding.DecodeString(request.GetPageToken())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+	}
+
+	filter, err := listingFilter(ctx, "", "")
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	// One more product than asked tells whether there is a next page.
+	products, err := gs.service.FindPage(ctx, filter, string(afterSku), pageSize+1)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	response := &productsv1.ListProductsResponse{}
+	if len(products) > pageSize {
+		products = products[:pageSize]
+		response.NextPageToken = base64.RawURLEncoding.EncodeToString([]byte(products[pageSize-1].Sku))
+	}
+	response.Products = make([]*productsv1.Product, 0, len(products))
+	for _, product := range products {
+		response.Products = append(response.Products, productMessage(product))
+	}
+	return response, nil
+}
+
+func (gs *ProductGRPCServer) UpdateProduct(ctx context.Context, request *productsv1.UpdateProductRequest) (*productsv1.Product, error) {
+	product, err := newProductFromMessage(request.GetProduct())
+	if err != nil {
+		return nil, err
+	}
+	updatedProduct, err := gs.service.UpdateProduct(ctx, request.GetSku(), *product)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return productMessage(*updatedProduct), nil
+}
+
+func (gs *ProductGRPCServer) DeleteProduct(ctx context.Context, request *productsv1.DeleteProductRequest) (*productsv1.DeleteProductResponse, error) {
+	if err := gs.service.DeleteProduct(ctx, request.GetSku()); err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return &productsv1.DeleteProductResponse{}, nil
+}
+
+func (gs *ProductGRPCServer) WatchProducts(request *productsv1.WatchProductsRequest, stream productsv1.ProductService_WatchProductsServer) error {
+	subscription := gs.broadcaster.Subscribe(request.GetLastEventId(), events.Filter{
+		Brand:     request.GetBrand(),
+		SkuPrefix: request.GetSkuPrefix(),
+		Allowed: func(event entity.ProductEvent) bool {
+			return visibleEvent(stream.Context(), event)
+		},
+	})
+	defer gs.broadcaster.Unsubscribe(subscription)
+	if !subscription.Resumed {
+		return status.Error(codes.OutOfRange, "events after last_event_id are no longer available")
+	}
+	for _, event := range subscription.Backlog {
+		if err := stream.Send(eventMessage(event)); err != nil {
+			return err
+		}
+	}
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return grpcError(ctx, ctx.Err())
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return status.Error(codes.Unavailable, "watch interrupted, resume with last_event_id")
+			}
+			if err := stream.Send(eventMessage(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// grpcError maps the errors of the use cases to status codes. Unexpected
+// errors are logged and reported as INTERNAL without details.
+func grpcError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrProductNotFound):
+		return status.Error(codes.NotFound, "product not found")
+	case errors.Is(err, repository.ErrDuplicatedProduct):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, usecase.ErrSkuChange):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, errStatusForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "request timed out")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request cancelled")
+	}
+	logging.FromContext(ctx).WithError(err).Errorln("error serving gRPC request")
+	return status.Error(codes.Internal, "internal error")
+}
+
+func newProductFromMessage(message *productsv1.Product) (*entity.Product, error) {
+	if message == nil {
+		return nil, status.Error(codes.InvalidArgument, "product is required")
+	}
+	product, err := factory.NewProduct(
+		message.GetSku(),
+		message.GetName(),
+		message.GetBrand(),
+		message.GetSize(),
+		message.GetPrice(),
+		message.GetPrincipalImage(),
+		message.GetOtherImages(),
+	)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if product.AvailableFrom, err = messageTime(message.GetAvailableFrom()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid available_from: "+err.Error())
+	}
+	if product.AvailableUntil, err = messageTime(message.GetAvailableUntil()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid available_until: "+err.Error())
+	}
+	if _, err := product.IsValid(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return product, nil
+}
+
+func productMessage(product entity.Product) *productsv1.Product {
+	message := &productsv1.Product{
+		Sku:            product.Sku,
+		Name:           product.Name,
+		Brand:          product.Brand,
+		Size:           product.Size,
+		Price:          product.Price,
+		PrincipalImage: product.PrincipalImage,
+		OtherImages:    product.OtherImages,
+		Status:         string(product.Status),
+	}
+	if product.AvailableFrom != nil {
+		message.AvailableFrom = timestamppb.New(*product.AvailableFrom)
+	}
+	if product.AvailableUntil != nil {
+		message.AvailableUntil = timestamppb.New(*product.AvailableUntil)
+	}
+	if !product.CreatedAt.IsZero() {
+		message.CreateTime = timestamppb.New(product.CreatedAt)
+	}
+	if !product.UpdatedAt.IsZero() {
+		message.UpdateTime = timestamppb.New(product.UpdatedAt)
+	}
+	return message
+}
+
+// messageTime converts an optional timestamp, nil when unset.
+func messageTime(timestamp *timestamppb.Timestamp) (*time.Time, error) {
+	if timestamp == nil {
+		return nil, nil
+	}
+	if err := timestamp.CheckValid(); err != nil {
+		return nil, err
+	}
+	t := timestamp.AsTime()
+	return &t, nil
+}
+
+func eventMessage(event entity.ProductEvent) *productsv1.ProductEvent {
+	message := &productsv1.ProductEvent{
+		Id:            event.ID,
+		Type:          string(event.Type),
+		OccurTime:     timestamppb.New(event.OccurredAt),
+		Sku:           event.Sku,
+		PreviousSku:   event.PreviousSku,
+		ChangedFields: event.ChangedFields,
+	}
+	if event.Product != nil {
+		message.Product = productMessage(*event.Product)
+	}
+	return message
+}
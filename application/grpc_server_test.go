@@ -0,0 +1,276 @@
+package application
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	productsv1 "github.com/yescorihuela/agrak/api/grpc/products/v1"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/infrastructure/events"
+	"github.com/yescorihuela/agrak/infrastructure/memory"
+	"github.com/yescorihuela/agrak/infrastructure/ratelimit"
+	"github.com/yescorihuela/agrak/usecase"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newGRPCTestClient(t *testing.T, broadcaster *events.Broadcaster, limits RateLimits) productsv1.ProductServiceClient {
+	products := memory.NewProductRepository()
+	service := usecase.NewProductService(products, memory.NewUnitOfWork(products, memory.NewOutboxRepository()))
+	keyServiceMock := new(usecase.KeyServiceMock)
+	keyServiceMock.On("Authenticate", "reader-key").Return(&entity.APIKey{ID: "reader", Role: entity.RoleReader}, nil)
+	keyServiceMock.On("Authenticate", "admin-key").Return(&entity.APIKey{ID: "admin", Role: entity.RoleAdmin}, nil)
+	keyServiceMock.On("Authenticate", mock.Anything).Return(nil, usecase.ErrInvalidAPIKey)
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewGRPCServer(NewProductGRPCServer(service, broadcaster), keyServiceMock, nil, ratelimit.NewMemoryLimiter(), limits, time.Second)
+	go func() { _ = server.Serve(listener) }()
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+	return productsv1.NewProductServiceClient(conn)
+}
+
+func withAPIKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), apiKeyMetadata, key)
+}
+
+func testProductMessage(sku string) *productsv1.Product {
+	return &productsv1.Product{
+		Sku:            sku,
+		Name:           "Polera",
+		Brand:          "CAT",
+		Size:           "XL",
+		Price:          20000,
+		PrincipalImage: "https://placehold.jp/3d4070/ffffff/150x150.png",
+	}
+}
+
+func TestProductGRPCServer(t *testing.T) {
+	client := newGRPCTestClient(t, events.NewBroadcaster(10), RateLimits{})
+	admin := withAPIKey("admin-key")
+
+	t.Run("should create, get, update and delete products", func(t *testing.T) {
+		created, err := client.CreateProduct(admin, &productsv1.CreateProductRequest{Product: testProductMessage("FAL-1000000")})
+		assert.NoError(t, err)
+		assert.Equal(t, "FAL-1000000", created.GetSku())
+
+		found, err := client.GetProduct(admin, &productsv1.GetProductRequest{Sku: "FAL-1000000"})
+		assert.NoError(t, err)
+		assert.Equal(t, "CAT", found.GetBrand())
+
+		changed := testProductMessage("FAL-1000000")
+		changed.Price = 25000
+		updated, err := client.UpdateProduct(admin, &productsv1.UpdateProductRequest{Sku: "FAL-1000000", Product: changed})
+		assert.NoError(t, err)
+		assert.Equal(t, float64(25000), updated.GetPrice())
+
+		_, err = client.DeleteProduct(admin, &productsv1.DeleteProductRequest{Sku: "FAL-1000000"})
+		assert.NoError(t, err)
+		_, err = client.GetProduct(admin, &productsv1.GetProductRequest{Sku: "FAL-1000000"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("should carry the status and the availability window", func(t *testing.T) {
+		launch := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		scheduled := testProductMessage("FAL-1000003")
+		scheduled.Status = string(entity.StatusActive)
+		scheduled.AvailableFrom = timestamppb.New(launch)
+		created, err := client.CreateProduct(admin, &productsv1.CreateProductRequest{Product: scheduled})
+		assert.NoError(t, err)
+		assert.Equal(t, string(entity.StatusDraft), created.GetStatus(), "products are created as drafts")
+		assert.Equal(t, launch, created.GetAvailableFrom().AsTime())
+		assert.Nil(t, created.GetAvailableUntil())
+
+		scheduled.AvailableUntil = timestamppb.New(launch.Add(24 * time.Hour))
+		updated, err := client.UpdateProduct(admin, &productsv1.UpdateProductRequest{Sku: "FAL-1000003", Product: scheduled})
+		assert.NoError(t, err)
+		assert.Equal(t, launch.Add(24*time.Hour), updated.GetAvailableUntil().AsTime())
+
+		found, err := client.GetProduct(admin, &productsv1.GetProductRequest{Sku: "FAL-1000003"})
+		assert.NoError(t, err)
+		assert.Equal(t, string(entity.StatusDraft), found.GetStatus())
+		assert.Equal(t, launch, found.GetAvailableFrom().AsTime())
+		assert.Equal(t, launch.Add(24*time.Hour), found.GetAvailableUntil().AsTime())
+
+		scheduled.AvailableUntil = timestamppb.New(launch.Add(-time.Hour))
+		_, err = client.UpdateProduct(admin, &productsv1.UpdateProductRequest{Sku: "FAL-1000003", Product: scheduled})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "the window must end after it opens")
+	})
+
+	t.Run("should map domain errors to status codes", func(t *testing.T) {
+		_, err := client.CreateProduct(admin, &productsv1.CreateProductRequest{Product: testProductMessage("FAL-1000001")})
+		assert.NoError(t, err)
+
+		_, err = client.CreateProduct(admin, &productsv1.CreateProductRequest{Product: testProductMessage("FAL-1000001")})
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+		invalid := testProductMessage("FAL-1000002")
+		invalid.Price = 0
+		_, err = client.CreateProduct(admin, &productsv1.CreateProductRequest{Product: invalid})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.CreateProduct(admin, &productsv1.CreateProductRequest{})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.UpdateProduct(admin, &productsv1.UpdateProductRequest{Sku: "FAL-9999999", Product: testProductMessage("FAL-9999999")})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("should authenticate and authorize calls", func(t *testing.T) {
+		_, err := client.GetProduct(context.Background(), &productsv1.GetProductRequest{Sku: "FAL-1000001"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		_, err = client.GetProduct(withAPIKey("unknown-key"), &productsv1.GetProductRequest{Sku: "FAL-1000001"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		_, err = client.GetProduct(withAPIKey("reader-key"), &productsv1.GetProductRequest{Sku: "FAL-1000001"})
+		assert.Equal(t, codes.NotFound, status.Code(err), "readers cannot see drafts")
+
+		_, err = client.DeleteProduct(withAPIKey("reader-key"), &productsv1.DeleteProductRequest{Sku: "FAL-1000001"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
+func TestProductGRPCServer_RateLimit(t *testing.T) {
+	t.Run("should throttle each caller by the group of the method", func(t *testing.T) {
+		client := newGRPCTestClient(t, events.NewBroadcaster(10), RateLimits{Read: ratelimit.PerMinute(60, 1), Write: ratelimit.PerMinute(60, 1)})
+		admin := withAPIKey("admin-key")
+
+		_, err := client.CreateProduct(admin, &productsv1.CreateProductRequest{Product: testProductMessage("FAL-1000000")})
+		assert.NoError(t, err)
+		var header metadata.MD
+		_, err = client.CreateProduct(admin, &productsv1.CreateProductRequest{Product: testProductMessage("FAL-1000001")}, grpc.Header(&header))
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, []string{"1"}, header.Get("retry-after"))
+
+		_, err = client.GetProduct(admin, &productsv1.GetProductRequest{Sku: "FAL-1000000"})
+		assert.NoError(t, err, "reads take from their own bucket")
+		_, err = client.GetProduct(withAPIKey("reader-key"), &productsv1.GetProductRequest{Sku: "FAL-1000000"})
+		assert.Equal(t, codes.NotFound, status.Code(err), "each caller has its own bucket")
+		_, err = client.ListProducts(admin, &productsv1.ListProductsRequest{})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("should throttle clients before authenticating them", func(t *testing.T) {
+		client := newGRPCTestClient(t, events.NewBroadcaster(10), RateLimits{Client: ratelimit.PerMinute(60, 2)})
+
+		for i := 0; i < 2; i++ {
+			_, err := client.GetProduct(withAPIKey("unknown-key"), &productsv1.GetProductRequest{Sku: "FAL-1000000"})
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		}
+		_, err := client.GetProduct(withAPIKey("unknown-key"), &productsv1.GetProductRequest{Sku: "FAL-1000000"})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		_, err = client.GetProduct(withAPIKey("admin-key"), &productsv1.GetProductRequest{Sku: "FAL-1000000"})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err), "valid credentials share the bucket of the client")
+	})
+}
+
+func TestProductGRPCServer_ListProducts(t *testing.T) {
+	client := newGRPCTestClient(t, events.NewBroadcaster(10), RateLimits{})
+	admin := withAPIKey("admin-key")
+	for _, sku := range []string{"FAL-1000003", "FAL-1000001", "FAL-1000002"} {
+		_, err := client.CreateProduct(admin, &productsv1.CreateProductRequest{Product: testProductMessage(sku)})
+		assert.NoError(t, err)
+	}
+
+	skus := make([]string, 0)
+	pageToken := ""
+	pages := 0
+	for {
+		page, err := client.ListProducts(admin, &productsv1.ListProductsRequest{PageSize: 2, PageToken: pageToken})
+		if !assert.NoError(t, err) {
+			return
+		}
+		pages++
+		for _, product := range page.GetProducts() {
+			skus = append(skus, product.GetSku())
+		}
+		if pageToken = page.GetNextPageToken(); pageToken == "" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"FAL-1000001", "FAL-1000002", "FAL-1000003"}, skus)
+	assert.Equal(t, 2, pages)
+
+	_, err := client.ListProducts(admin, &productsv1.ListProductsRequest{PageToken: "not base64!"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.ListProducts(admin, &productsv1.ListProductsRequest{PageSize: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestProductGRPCServer_WatchProducts(t *testing.T) {
+	broadcaster := events.NewBroadcaster(10)
+	client := newGRPCTestClient(t, broadcaster, RateLimits{})
+	event := func(id, brand string) entity.ProductEvent {
+		return entity.ProductEvent{ID: id, Type: entity.EventProductCreated, Sku: "FAL-1000000", Product: &entity.Product{Sku: "FAL-1000000", Brand: brand, Status: entity.StatusActive}}
+	}
+	draft := event("draft", "Nike")
+	draft.Product.Status = entity.StatusDraft
+	_ = broadcaster.Publish(context.Background(), event("a", "Nike"))
+
+	t.Run("should resume and stream matching events", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(withAPIKey("reader-key"))
+		defer cancel()
+		stream, err := client.WatchProducts(ctx, &productsv1.WatchProductsRequest{Brand: "nike", LastEventId: "a"})
+		assert.NoError(t, err)
+		assert.Eventually(t, func() bool { return broadcaster.Subscribers() == 1 }, time.Second, 10*time.Millisecond)
+
+		_ = broadcaster.Publish(context.Background(), event("b", "Adidas"))
+		_ = broadcaster.Publish(context.Background(), draft)
+		_ = broadcaster.Publish(context.Background(), event("c", "Nike"))
+
+		received, err := stream.Recv()
+		assert.NoError(t, err)
+		assert.Equal(t, "c", received.GetId())
+		assert.Equal(t, "product.created", received.GetType())
+		assert.Equal(t, "Nike", received.GetProduct().GetBrand())
+	})
+
+	t.Run("should stream drafts to admins only", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(withAPIKey("admin-key"))
+		stream, err := client.WatchProducts(ctx, &productsv1.WatchProductsRequest{LastEventId: "b"})
+		assert.NoError(t, err)
+
+		received, err := stream.Recv()
+		assert.NoError(t, err)
+		assert.Equal(t, "draft", received.GetId())
+		cancel()
+		assert.Eventually(t, func() bool { return broadcaster.Subscribers() == 0 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should fail when events were missed", func(t *testing.T) {
+		stream, err := client.WatchProducts(withAPIKey("reader-key"), &productsv1.WatchProductsRequest{LastEventId: "evicted"})
+		assert.NoError(t, err)
+
+		_, err = stream.Recv()
+		assert.Equal(t, codes.OutOfRange, status.Code(err))
+	})
+
+	t.Run("should end when the broadcaster closes", func(t *testing.T) {
+		stream, err := client.WatchProducts(withAPIKey("reader-key"), &productsv1.WatchProductsRequest{})
+		assert.NoError(t, err)
+		assert.Eventually(t, func() bool { return broadcaster.Subscribers() == 1 }, time.Second, 10*time.Millisecond)
+		broadcaster.Close()
+
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+}
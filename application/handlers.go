@@ -1,55 +1,108 @@
 package application
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"path"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yescorihuela/agrak/domain/entity"
 	"github.com/yescorihuela/agrak/domain/factory"
+	"github.com/yescorihuela/agrak/domain/repository"
 	"github.com/yescorihuela/agrak/infrastructure/response"
+	"github.com/yescorihuela/agrak/shared/identity"
 	"github.com/yescorihuela/agrak/usecase"
 )
 
-var request = struct {
-	Sku            string   `json:"sku"`
-	Name           string   `json:"name"`
-	Brand          string   `json:"brand"`
-	Size           string   `json:"size"`
-	Price          float64  `json:"price"`
-	PrincipalImage string   `json:"principal_image"`
-	OtherImages    []string `json:"other_images"`
-}{}
+// productRequest is the body of a product creation or update. The window
+// bounds are optional, so every request binds to a fresh value.
+type productRequest struct {
+	Sku            string     `json:"sku"`
+	Name           string     `json:"name"`
+	Brand          string     `json:"brand"`
+	Size           string     `json:"size"`
+	Price          float64    `json:"price"`
+	PrincipalImage string     `json:"principal_image"`
+	OtherImages    []string   `json:"other_images"`
+	AvailableFrom  *time.Time `json:"available_from"`
+	AvailableUntil *time.Time `json:"available_until"`
+}
+
+// DefaultMaxBatchSize is the number of SKUs a batch get accepts unless
+// WithMaxBatchSize says otherwise.
+const DefaultMaxBatchSize = 100
 
 type ProductHandlers struct {
-	service usecase.Service
+	service      usecase.Service
+	maxBatchSize int
 }
 
 func NewProductHandlers(service usecase.Service) *ProductHandlers {
 	return &ProductHandlers{
-		service: service,
+		service:      service,
+		maxBatchSize: DefaultMaxBatchSize,
 	}
 }
 
+// WithMaxBatchSize sets the number of SKUs a batch get accepts.
+func (ph *ProductHandlers) WithMaxBatchSize(size int) *ProductHandlers {
+	ph.maxBatchSize = size
+	return ph
+}
+
 // GetProductBySku godoc
 // @Summary Retrieve a product by SKU
 // @Description get product by SKU as json
 // @Accept json
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @param sku path string true "Product unique SKU"
+// @param as_of query string false "RFC 3339 date-time to check the availability window at instead of now (admin only)"
+// @param If-None-Match header string false "ETag of the cached representation"
+// @param If-Modified-Since header string false "Date of the cached representation"
 // @Success 200 {object} response.DTOProduct
+// @Header 200 {string} ETag "Strong entity tag of the product"
+// @Header 200 {string} Last-Modified "Last update date of the product"
+// @Success 304 {object} nil
+// @Header 301 {string} Location "URL of the product a renamed SKU now belongs to"
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
 // @Failure 404 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
 // @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
 // @Router /api/v1/products/{sku} [get]
 func (ph *ProductHandlers) GetProductBySku(ctx *gin.Context) {
 	sku := ctx.Param("sku")
-	product, err := ph.service.FindBySku(sku)
+	visible, err := visibleFilter(ctx.Request.Context(), ctx.Query("as_of"))
+	if abortOnVisibilityError(ctx, err) {
+		return
+	}
+	product, err := ph.service.FindBySku(ctx.Request.Context(), sku)
+	if abortOnContextError(ctx, err) {
+		return
+	}
+	if errors.Is(err, repository.ErrProductNotFound) && ph.redirectAlias(ctx, sku, visible) {
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, response.NewErrorResponse(err.Error()))
 		return
 	}
+	if !visible.Matches(*product) {
+		ctx.JSON(http.StatusNotFound, response.NewErrorResponse(repository.ErrProductNotFound.Error()))
+		return
+	}
 
 	response := response.ConvertFromEntityToResponse(*product)
-	ctx.JSON(http.StatusOK, response)
+	conditionalJSON(ctx, http.StatusOK, response, product.UpdatedAt)
 }
 
 // CreateProduct godoc
@@ -57,12 +110,19 @@ func (ph *ProductHandlers) GetProductBySku(ctx *gin.Context) {
 // @Description add by json product
 // @Accept json
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @param product body response.DTOProduct true "Add new product with unique SKU"
 // @Success 201 {object} response.DTOProduct
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
 // @Failure 422 {object} response.ErrorResponse
 // @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
 // @Router /api/v1/products/ [post]
 func (ph *ProductHandlers) CreateProduct(ctx *gin.Context) {
+	request := productRequest{}
 	err := ctx.ShouldBindJSON(&request)
 	if err != nil {
 		ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(err.Error()))
@@ -78,8 +138,13 @@ func (ph *ProductHandlers) CreateProduct(ctx *gin.Context) {
 	)
 
 	if product != nil {
+		product.AvailableFrom = request.AvailableFrom
+		product.AvailableUntil = request.AvailableUntil
 		if validProduct, err := product.IsValid(); validProduct {
-			err = ph.service.CreateProduct(*product)
+			product, err = ph.service.CreateProduct(ctx.Request.Context(), *product)
+			if abortOnContextError(ctx, err) {
+				return
+			}
 			if err != nil {
 				ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(err.Error()))
 				return
@@ -98,25 +163,152 @@ func (ph *ProductHandlers) CreateProduct(ctx *gin.Context) {
 
 // GetAllProducts godoc
 // @Summary List all the stored products
-// @Description list all the products as an array
+// @Description list the active products whose availability window is open as an array. Admins see every product unless they ask for a status, or preview the catalog with as_of.
 // @Accept json
 // @Produce json
-// @param none query string false "Not required."
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @param status query string false "Only list products in this status (draft, active or discontinued), any other than active needs the admin role"
+// @param as_of query string false "RFC 3339 date-time to preview the catalog at, listing the products active and available then (admin only)"
+// @param skus query string false "Comma separated SKUs to batch get instead, answered as response.DTOProductBatch"
+// @param If-None-Match header string false "ETag of the cached representation"
 // @Success 200 {array} response.DTOProduct
+// @Header 200 {string} ETag "Strong entity tag of the product list"
+// @Success 304 {object} nil
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
 // @Failure 404 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
 // @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
 // @Router /api/v1/products/ [get]
 func (ph *ProductHandlers) GetAllProducts(ctx *gin.Context) {
-	products, err := ph.service.FindAll()
+	if skus, ok := ctx.GetQuery("skus"); ok {
+		ph.batchGet(ctx, strings.Split(skus, ","), collectionJSON)
+		return
+	}
+	filter, err := listingFilter(ctx.Request.Context(), ctx.Query("status"), ctx.Query("as_of"))
+	if abortOnVisibilityError(ctx, err) {
+		return
+	}
+	products, err := ph.service.FindAll(ctx.Request.Context())
+	if abortOnContextError(ctx, err) {
+		return
+	}
 	responseJSON := make([]response.DTOProduct, 0)
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, response.NewErrorResponse(err.Error()))
 		return
 	}
 	for _, product := range products {
+		if !filter.Matches(product) {
+			continue
+		}
 		responseJSON = append(responseJSON, *response.ConvertFromEntityToResponse(product))
 	}
-	ctx.JSON(http.StatusOK, responseJSON)
+	collectionJSON(ctx, http.StatusOK, responseJSON)
+}
+
+type batchGetRequest struct {
+	Skus []string `json:"skus" binding:"required"`
+}
+
+// ProductsMethod serves the custom methods of the product collection,
+// POST /products:<method>. Gin reads the colon as the start of a path
+// parameter, so the method parameter holds ":<method>".
+func (ph *ProductHandlers) ProductsMethod(ctx *gin.Context) {
+	switch ctx.Param("method") {
+	case ":batchGet":
+		ph.BatchGetProducts(ctx)
+	default:
+		ctx.JSON(http.StatusNotFound, response.NewErrorResponse("unknown method "+ctx.Param("method")))
+	}
+}
+
+// BatchGetProducts godoc
+// @Summary Retrieve several products by SKU
+// @Description get the products of up to PRODUCTS_MAX_BATCH_SIZE SKUs with a single lookup, in the order asked, and the SKUs that matched none. Products outside their availability window count as missing, except for admins. GET /api/v1/products/?skus=a,b,c answers the same.
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @param request body batchGetRequest true "SKUs to retrieve"
+// @param as_of query string false "RFC 3339 date-time to check the availability windows at instead of now (admin only)"
+// @Success 200 {object} response.DTOProductBatch
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
+// @Router /api/v1/products:batchGet [post]
+func (ph *ProductHandlers) BatchGetProducts(ctx *gin.Context) {
+	request := batchGetRequest{}
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(err.Error()))
+		return
+	}
+	ph.batchGet(ctx, request.Skus, func(ctx *gin.Context, status int, body interface{}) {
+		ctx.JSON(status, body)
+	})
+}
+
+// batchGet looks skus up, ignoring blanks and repetitions, and writes the
+// result with write. The products the caller cannot see are missing.
+func (ph *ProductHandlers) batchGet(ctx *gin.Context, skus []string, write func(ctx *gin.Context, status int, body interface{})) {
+	visible, err := visibleFilter(ctx.Request.Context(), ctx.Query("as_of"))
+	if abortOnVisibilityError(ctx, err) {
+		return
+	}
+	requested := make([]string, 0, len(skus))
+	seen := make(map[string]bool, len(skus))
+	for _, sku := range skus {
+		sku = strings.TrimSpace(sku)
+		if sku == "" || seen[sku] {
+			continue
+		}
+		seen[sku] = true
+		requested = append(requested, sku)
+	}
+	if len(requested) == 0 {
+		ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse("at least one sku is required"))
+		return
+	}
+	if len(requested) > ph.maxBatchSize {
+		ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(
+			fmt.Sprintf("at most %d skus can be retrieved at once, got %d", ph.maxBatchSize, len(requested)),
+		))
+		return
+	}
+
+	products, err := ph.service.FindBySkus(ctx.Request.Context(), requested)
+	if abortOnContextError(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, response.NewErrorResponse(err.Error()))
+		return
+	}
+	found := make(map[string]entity.Product, len(products))
+	for _, product := range products {
+		if visible.Matches(product) {
+			found[product.Sku] = product
+		}
+	}
+	batch := response.DTOProductBatch{
+		Products: make([]response.DTOProduct, 0, len(products)),
+		Missing:  make([]string, 0),
+	}
+	for _, sku := range requested {
+		product, ok := found[sku]
+		if !ok {
+			batch.Missing = append(batch.Missing, sku)
+			continue
+		}
+		batch.Products = append(batch.Products, *response.ConvertFromEntityToResponse(product))
+	}
+	write(ctx, http.StatusOK, batch)
 }
 
 // UpdateProduct godoc
@@ -124,20 +316,30 @@ func (ph *ProductHandlers) GetAllProducts(ctx *gin.Context) {
 // @Description delete product by SKU
 // @Accept json
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @param sku path string true "Product unique SKU"
 // @param product body response.DTOProduct true "Product body with unique SKU"
 // @Success 200 {object} response.DTOProduct
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
 // @Failure 404 {object} response.ErrorResponse
 // @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
 // @Router /api/v1/products/{sku} [put]
 func (ph *ProductHandlers) UpdateProduct(ctx *gin.Context) {
 	sku := ctx.Param("sku")
-	product, err := ph.service.FindBySku(sku)
+	product, err := ph.service.FindBySku(ctx.Request.Context(), sku)
+	if abortOnContextError(ctx, err) {
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusNotFound, response.NewErrorResponse(err.Error()))
 		return
 	}
 
+	request := productRequest{}
 	err = ctx.ShouldBindJSON(&request)
 	if err != nil {
 		ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(err.Error()))
@@ -158,9 +360,14 @@ func (ph *ProductHandlers) UpdateProduct(ctx *gin.Context) {
 		ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(err.Error()))
 		return
 	}
+	newProduct.AvailableFrom = request.AvailableFrom
+	newProduct.AvailableUntil = request.AvailableUntil
 
 	if !reflect.DeepEqual(product, newProduct) {
-		product, err = ph.service.UpdateProduct(sku, *newProduct)
+		product, err = ph.service.UpdateProduct(ctx.Request.Context(), sku, *newProduct)
+		if abortOnContextError(ctx, err) {
+			return
+		}
 		if err != nil {
 			ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(err.Error()))
 			return
@@ -177,19 +384,182 @@ func (ph *ProductHandlers) UpdateProduct(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, response.ConvertFromEntityToResponse(*newProduct))
 }
 
+// abortOnVisibilityError answers 403 when the caller asked for products
+// it cannot see and 422 when it asked for them the wrong way, and tells
+// whether it did.
+func abortOnVisibilityError(ctx *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, errStatusForbidden), errors.Is(err, errAsOfForbidden):
+		ctx.JSON(http.StatusForbidden, response.NewErrorResponse(err.Error()))
+	default:
+		ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(err.Error()))
+	}
+	return true
+}
+
+// redirectAlias answers with a permanent redirect to the product sku was
+// renamed to, when it matches visible, and tells whether it answered. A
+// product the caller cannot see is not found under its old SKU either, so
+// the redirect never reveals its new one.
+func (ph *ProductHandlers) redirectAlias(ctx *gin.Context, sku string, visible repository.ProductFilter) bool {
+	target, err := ph.service.ResolveAlias(ctx.Request.Context(), sku)
+	if errors.Is(err, repository.ErrSkuAliasNotFound) {
+		return false
+	}
+	if abortOnContextError(ctx, err) {
+		return true
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, response.NewErrorResponse(err.Error()))
+		return true
+	}
+	product, err := ph.service.FindBySku(ctx.Request.Context(), target)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		return false
+	}
+	if abortOnContextError(ctx, err) {
+		return true
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, response.NewErrorResponse(err.Error()))
+		return true
+	}
+	if !visible.Matches(*product) {
+		return false
+	}
+	location := url.URL{
+		Path:     path.Join(path.Dir(ctx.Request.URL.Path), target),
+		RawQuery: ctx.Request.URL.RawQuery,
+	}
+	ctx.Redirect(http.StatusMovedPermanently, location.String())
+	return true
+}
+
+type renameRequest struct {
+	NewSku string `json:"new_sku" binding:"required"`
+}
+
+// RenameProduct godoc
+// @Summary Rename a product
+// @Description change the SKU of a product. The old SKU is kept as an alias, so reading it redirects to the new one.
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @param sku path string true "Current SKU of the product"
+// @param request body renameRequest true "New SKU of the product"
+// @Success 200 {object} response.DTOProduct
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
+// @Router /api/v1/products/{sku}/rename [post]
+func (ph *ProductHandlers) RenameProduct(ctx *gin.Context) {
+	request := renameRequest{}
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(err.Error()))
+		return
+	}
+	product, err := ph.service.RenameProduct(ctx.Request.Context(), ctx.Param("sku"), request.NewSku)
+	if abortOnContextError(ctx, err) {
+		return
+	}
+	if errors.Is(err, repository.ErrProductNotFound) {
+		ctx.JSON(http.StatusNotFound, response.NewErrorResponse(err.Error()))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(err.Error()))
+		return
+	}
+	ctx.JSON(http.StatusOK, response.ConvertFromEntityToResponse(*product))
+}
+
+// PublishProduct godoc
+// @Summary Publish a product
+// @Description make a draft product active, or reactivate a discontinued one (admin only). The product must pass every validation.
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @param sku path string true "Product unique SKU"
+// @Success 200 {object} response.DTOProduct
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
+// @Router /api/v1/products/{sku}/publish [post]
+func (ph *ProductHandlers) PublishProduct(ctx *gin.Context) {
+	ph.changeStatus(ctx, entity.StatusActive)
+}
+
+// DiscontinueProduct godoc
+// @Summary Discontinue a product
+// @Description take an active product out of the public listing
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @param sku path string true "Product unique SKU"
+// @Success 200 {object} response.DTOProduct
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
+// @Router /api/v1/products/{sku}/discontinue [post]
+func (ph *ProductHandlers) DiscontinueProduct(ctx *gin.Context) {
+	ph.changeStatus(ctx, entity.StatusDiscontinued)
+}
+
+func (ph *ProductHandlers) changeStatus(ctx *gin.Context, status entity.ProductStatus) {
+	requestCtx := ctx.Request.Context()
+	product, err := ph.service.ChangeStatus(requestCtx, ctx.Param("sku"), status, identity.FromContext(requestCtx))
+	if abortOnContextError(ctx, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, repository.ErrProductNotFound):
+		ctx.JSON(http.StatusNotFound, response.NewErrorResponse(err.Error()))
+	case errors.Is(err, entity.ErrTransitionForbidden):
+		ctx.JSON(http.StatusForbidden, response.NewErrorResponse(err.Error()))
+	case err != nil:
+		ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(err.Error()))
+	default:
+		ctx.JSON(http.StatusOK, response.ConvertFromEntityToResponse(*product))
+	}
+}
+
 // Delete godoc
 // @Summary Delete a product by SKU
 // @Description delete product by SKU
 // @Accept json
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @param sku path string true "Product unique SKU"
 // @Success 204 {object} nil
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
 // @Failure 404 {object} response.ErrorResponse
 // @Failure 500 {object} response.ErrorResponse
+// @Failure 504 {object} response.ErrorResponse
 // @Router /api/v1/products/{sku} [delete]
 func (ph *ProductHandlers) Delete(ctx *gin.Context) {
 	sku := ctx.Param("sku")
-	err := ph.service.DeleteProduct(sku)
+	err := ph.service.DeleteProduct(ctx.Request.Context(), sku)
+	if abortOnContextError(ctx, err) {
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(err.Error()))
 		return
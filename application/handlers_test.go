@@ -2,18 +2,24 @@ package application
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/yescorihuela/agrak/domain/entity"
 	"github.com/yescorihuela/agrak/domain/factory"
+	"github.com/yescorihuela/agrak/domain/repository"
 	"github.com/yescorihuela/agrak/infrastructure/response"
+	"github.com/yescorihuela/agrak/shared/identity"
 	"github.com/yescorihuela/agrak/usecase"
 )
 
@@ -52,7 +58,9 @@ func TestCreateProduct(t *testing.T) {
 
 		mockUsecase := new(usecase.UseCaseMock)
 
-		mockUsecase.On("CreateProduct", *mockEntityProduct).Return(nil)
+		storedProduct := *mockEntityProduct
+		storedProduct.Status = entity.StatusDraft
+		mockUsecase.On("CreateProduct", *mockEntityProduct).Return(&storedProduct, nil)
 		rr := httptest.NewRecorder()
 		router := gin.Default()
 		router.Group("api/v1")
@@ -63,11 +71,15 @@ func TestCreateProduct(t *testing.T) {
 		assert.NoError(t, err)
 
 		router.ServeHTTP(rr, request)
-		response, err := json.Marshal(mockProductPayload)
+		payload, _ = json.Marshal(mockProductPayload)
+		expected := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(payload, &expected))
+		expected["status"] = string(entity.StatusDraft)
+		created := map[string]interface{}{}
 
-		assert.NoError(t, err)
 		assert.Equal(t, http.StatusCreated, rr.Code)
-		assert.Equal(t, response, rr.Body.Bytes())
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+		assert.Equal(t, expected, created, "the body is the stored draft")
 		mockUsecase.AssertExpectations(t)
 	})
 
@@ -104,7 +116,7 @@ func TestCreateProduct(t *testing.T) {
 
 		mockUsecase := new(usecase.UseCaseMock)
 
-		mockUsecase.On("CreateProduct", *mockEntityProduct).Return(errors.New("invalid sku format (right format: FAL-XXXXXXX)"))
+		mockUsecase.On("CreateProduct", *mockEntityProduct).Return(nil, errors.New("invalid sku format (right format: FAL-XXXXXXX)"))
 		rr := httptest.NewRecorder()
 		router := gin.Default()
 		router.Group("api/v1")
@@ -143,6 +155,7 @@ func TestGetProductBySku(t *testing.T) {
 				"https://placehold.jp/24/cccccc/ffffff/250x50.png?text=placehold.jp",
 			},
 		)
+		mockEntityProduct.Status = entity.StatusActive
 		mockProductReturned := response.ConvertFromEntityToResponse(*mockEntityProduct)
 		mockUsecase := new(usecase.UseCaseMock)
 
@@ -192,6 +205,119 @@ func TestGetProductBySku(t *testing.T) {
 		mockUsecase.AssertExpectations(t)
 	})
 }
+func TestGetProductBySkuConditional(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sku := "FAL-1000000"
+	updatedAt := time.Date(2022, time.September, 1, 10, 30, 15, 500, time.UTC)
+	mockEntityProduct, _ := factory.NewProduct(
+		sku,
+		"Polera",
+		"CAT",
+		"XL",
+		20000.00,
+		"https://placehold.jp/3d4070/ffffff/150x150.png",
+		[]string{},
+	)
+	mockEntityProduct.Status = entity.StatusActive
+	mockEntityProduct.UpdatedAt = updatedAt
+
+	newRouter := func() *gin.Engine {
+		mockUsecase := new(usecase.UseCaseMock)
+		mockUsecase.On("FindBySku", sku).Return(mockEntityProduct, nil)
+		router := gin.Default()
+		router.GET("/products/:sku", CacheControl("public, max-age=60"), NewProductHandlers(mockUsecase).GetProductBySku)
+		return router
+	}
+
+	t.Run("GetProductBySku - 200 OK with validators", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/products/"+sku, nil)
+		assert.NoError(t, err)
+
+		newRouter().ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NotEmpty(t, rr.Header().Get("ETag"))
+		assert.Equal(t, "Thu, 01 Sep 2022 10:30:15 GMT", rr.Header().Get("Last-Modified"))
+		assert.Equal(t, "public, max-age=60", rr.Header().Get("Cache-Control"))
+	})
+
+	t.Run("GetProductBySku - 304 Not Modified (If-None-Match)", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/products/"+sku, nil)
+		newRouter().ServeHTTP(rr, request)
+		etag := rr.Header().Get("ETag")
+
+		rr = httptest.NewRecorder()
+		request, _ = http.NewRequest(http.MethodGet, "/products/"+sku, nil)
+		request.Header.Set("If-None-Match", etag)
+		newRouter().ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Equal(t, etag, rr.Header().Get("ETag"))
+		assert.Empty(t, rr.Body.Bytes())
+	})
+
+	t.Run("GetProductBySku - 200 OK (stale If-None-Match)", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/products/"+sku, nil)
+		request.Header.Set("If-None-Match", `"stale"`)
+		request.Header.Set("If-Modified-Since", updatedAt.Format(http.TimeFormat))
+		newRouter().ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("GetProductBySku - 304 Not Modified (If-Modified-Since)", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/products/"+sku, nil)
+		request.Header.Set("If-Modified-Since", updatedAt.Format(http.TimeFormat))
+		newRouter().ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+	})
+
+	t.Run("GetProductBySku - 200 OK (modified since)", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/products/"+sku, nil)
+		request.Header.Set("If-Modified-Since", updatedAt.Add(-time.Minute).Format(http.TimeFormat))
+		newRouter().ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
+
+func TestGetAllProductsConditional(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := newGraphQLTestService(t, "FAL-1000000", "FAL-1000001")
+	router := gin.Default()
+	router.GET("/products/", NewProductHandlers(service).GetAllProducts)
+	list := func(header, value string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/products/", nil)
+		if header != "" {
+			request.Header.Set(header, value)
+		}
+		router.ServeHTTP(rr, request)
+		return rr
+	}
+
+	t.Run("GetAllProducts - 200 OK (If-Modified-Since after a delete)", func(t *testing.T) {
+		rr := list("", "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("Last-Modified"), "collections are only validated by ETag")
+		etag := rr.Header().Get("ETag")
+		assert.Equal(t, http.StatusNotModified, list("If-None-Match", etag).Code)
+
+		assert.NoError(t, service.DeleteProduct(context.Background(), "FAL-1000001"))
+
+		rr = list("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NotContains(t, rr.Body.String(), "FAL-1000001")
+		assert.Equal(t, http.StatusOK, list("If-None-Match", etag).Code)
+	})
+}
+
 func TestGetAllProducts(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -222,6 +348,9 @@ func TestGetAllProducts(t *testing.T) {
 			},
 		)
 
+		mockEntityProduct1.Status = entity.StatusActive
+		mockEntityProduct2.Status = entity.StatusActive
+
 		mockProductReturned1 := response.ConvertFromEntityToResponse(*mockEntityProduct1)
 		mockProductReturned2 := response.ConvertFromEntityToResponse(*mockEntityProduct2)
 		mockUsecase := new(usecase.UseCaseMock)
@@ -278,6 +407,106 @@ func TestGetAllProducts(t *testing.T) {
 	})
 }
 
+func TestBatchGetProducts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(service usecase.Service, maxBatchSize int) *gin.Engine {
+		handlers := NewProductHandlers(service).WithMaxBatchSize(maxBatchSize)
+		router := gin.New()
+		router.GET("/api/v1/products/", handlers.GetAllProducts)
+		router.POST("/api/v1/products:method", handlers.ProductsMethod)
+		return router
+	}
+	activeProduct := func(sku string) entity.Product {
+		product := commandProduct(sku)
+		product.Status = entity.StatusActive
+		return product
+	}
+
+	t.Run("BatchGetProducts - 200 OK with the missing skus", func(t *testing.T) {
+		mockUsecase := new(usecase.UseCaseMock)
+		mockUsecase.On("FindBySkus", []string{"FAL-1000001", "FAL-9999999", "FAL-1000000"}).Return(
+			[]entity.Product{activeProduct("FAL-1000000"), activeProduct("FAL-1000001")}, nil).Once()
+		rr := httptest.NewRecorder()
+
+		body := []byte(`{"skus": ["FAL-1000001", "FAL-9999999", "FAL-1000000", "FAL-1000001", " "]}`)
+		request, err := http.NewRequest(http.MethodPost, "/api/v1/products:batchGet", bytes.NewReader(body))
+		assert.NoError(t, err)
+		newRouter(mockUsecase, 10).ServeHTTP(rr, request)
+
+		expected, err := json.Marshal(response.DTOProductBatch{
+			Products: []response.DTOProduct{
+				*response.ConvertFromEntityToResponse(activeProduct("FAL-1000001")),
+				*response.ConvertFromEntityToResponse(activeProduct("FAL-1000000")),
+			},
+			Missing: []string{"FAL-9999999"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, expected, rr.Body.Bytes())
+		mockUsecase.AssertExpectations(t)
+	})
+
+	t.Run("GetAllProducts - 200 OK with skus", func(t *testing.T) {
+		mockUsecase := new(usecase.UseCaseMock)
+		mockUsecase.On("FindBySkus", []string{"FAL-1000000", "FAL-9999999"}).Return(
+			[]entity.Product{activeProduct("FAL-1000000")}, nil).Once()
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodGet, "/api/v1/products/?skus=FAL-1000000,FAL-9999999", nil)
+		assert.NoError(t, err)
+		newRouter(mockUsecase, 10).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"products": [{"sku": "FAL-1000000", "name": "Polera", "brand": "CAT", "size": "XL", "price": 20000, "principal_image": "https://placehold.jp/3d4070/ffffff/150x150.png", "other_images": [], "status": "active"}], "missing": ["FAL-9999999"]}`, rr.Body.String())
+		mockUsecase.AssertNotCalled(t, "FindAll")
+	})
+
+	t.Run("BatchGetProducts - drafts are missing for non-admins", func(t *testing.T) {
+		mockUsecase := new(usecase.UseCaseMock)
+		mockUsecase.On("FindBySkus", []string{"FAL-1000000", "FAL-1000001"}).Return(
+			[]entity.Product{activeProduct("FAL-1000000"), commandProduct("FAL-1000001")}, nil).Once()
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodGet, "/api/v1/products/?skus=FAL-1000000,FAL-1000001", nil)
+		assert.NoError(t, err)
+		newRouter(mockUsecase, 10).ServeHTTP(rr, request)
+
+		batch := response.DTOProductBatch{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &batch))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, batch.Products, 1)
+		assert.Equal(t, []string{"FAL-1000001"}, batch.Missing)
+	})
+
+	t.Run("BatchGetProducts - 422 Unprocessable entity", func(t *testing.T) {
+		for name, body := range map[string]string{
+			"too many skus": `{"skus": ["FAL-1000000", "FAL-1000001", "FAL-1000002"]}`,
+			"no skus":       `{"skus": []}`,
+			"invalid body":  `{"skus": "FAL-1000000"}`,
+		} {
+			mockUsecase := new(usecase.UseCaseMock)
+			rr := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/api/v1/products:batchGet", bytes.NewReader([]byte(body)))
+			assert.NoError(t, err)
+			newRouter(mockUsecase, 2).ServeHTTP(rr, request)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, name)
+			mockUsecase.AssertNotCalled(t, "FindBySkus", mock.Anything)
+		}
+	})
+
+	t.Run("ProductsMethod - 404 Not found", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodPost, "/api/v1/products:batchDelete", bytes.NewReader([]byte(`{}`)))
+		assert.NoError(t, err)
+		newRouter(new(usecase.UseCaseMock), 2).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
 func TestUpdateProduct(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -504,3 +733,264 @@ func TestDelete(t *testing.T) {
 	})
 
 }
+
+func TestRenameProduct(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(t *testing.T) *gin.Engine {
+		handlers := NewProductHandlers(newGraphQLTestService(t, "FAL-1000000", "FAL-1000001"))
+		router := gin.New()
+		router.GET("/api/v1/products/:sku", handlers.GetProductBySku)
+		router.PUT("/api/v1/products/:sku", handlers.UpdateProduct)
+		router.POST("/api/v1/products/:sku/rename", handlers.RenameProduct)
+		return router
+	}
+	rename := func(router *gin.Engine, sku string, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/products/"+sku+"/rename", bytes.NewReader([]byte(body)))
+		router.ServeHTTP(rr, request)
+		return rr
+	}
+
+	t.Run("RenameProduct - 200 OK and 301 Moved Permanently on the old sku", func(t *testing.T) {
+		router := newRouter(t)
+
+		rr := rename(router, "FAL-1000000", `{"new_sku": "FAL-2000000"}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"sku":"FAL-2000000"`)
+
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/products/FAL-1000000?fields=sku", nil))
+		assert.Equal(t, http.StatusMovedPermanently, rr.Code)
+		assert.Equal(t, "/api/v1/products/FAL-2000000?fields=sku", rr.Header().Get("Location"))
+	})
+
+	t.Run("GetProductBySku - 404 Not found on the old sku of a draft", func(t *testing.T) {
+		handlers := NewProductHandlers(newGraphQLTestService(t))
+		router := gin.New()
+		router.GET("/api/v1/products/:sku", handlers.GetProductBySku)
+		router.POST("/api/v1/products/:sku/rename", handlers.RenameProduct)
+		_, err := handlers.service.CreateProduct(context.Background(), commandProduct("FAL-1000000"))
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, rename(router, "FAL-1000000", `{"new_sku": "FAL-2000000"}`).Code)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/products/FAL-1000000", nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Empty(t, rr.Header().Get("Location"))
+		assert.NotContains(t, rr.Body.String(), "FAL-2000000")
+	})
+
+	t.Run("GetProductBySku - 500 and 504 when the alias cannot be resolved", func(t *testing.T) {
+		for err, status := range map[error]int{
+			errors.New("connection refused"): http.StatusInternalServerError,
+			context.DeadlineExceeded:         http.StatusGatewayTimeout,
+		} {
+			serviceMock := new(usecase.UseCaseMock)
+			serviceMock.On("FindBySku", "FAL-1000000").Return(nil, repository.ErrProductNotFound)
+			serviceMock.On("ResolveAlias", "FAL-1000000").Return("", err)
+			router := gin.New()
+			router.GET("/api/v1/products/:sku", NewProductHandlers(serviceMock).GetProductBySku)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/products/FAL-1000000", nil))
+			assert.Equal(t, status, rr.Code, err.Error())
+		}
+	})
+
+	t.Run("RenameProduct - 404 Not found", func(t *testing.T) {
+		rr := rename(newRouter(t), "FAL-9999999", `{"new_sku": "FAL-2000000"}`)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("RenameProduct - 422 Unprocessable entity", func(t *testing.T) {
+		router := newRouter(t)
+		_ = rename(router, "FAL-1000001", `{"new_sku": "FAL-2000001"}`)
+		for name, body := range map[string]string{
+			"invalid sku":      `{"new_sku": "ABC-1"}`,
+			"same sku":         `{"new_sku": "FAL-1000000"}`,
+			"alias of another": `{"new_sku": "FAL-2000001"}`,
+			"sku of another":   `{"new_sku": "FAL-1000001"}`,
+			"no sku":           `{}`,
+		} {
+			rr := rename(router, "FAL-1000000", body)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, name)
+		}
+	})
+
+	t.Run("UpdateProduct - 422 Unprocessable entity (SKU change)", func(t *testing.T) {
+		body, err := json.Marshal(response.ConvertFromEntityToResponse(commandProduct("FAL-2000000")))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+
+		newRouter(t).ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/api/v1/products/FAL-1000000", bytes.NewReader(body)))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Contains(t, rr.Body.String(), usecase.ErrSkuChange.Error())
+	})
+}
+
+func TestProductStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(service usecase.Service, role entity.Role) *gin.Engine {
+		handlers := NewProductHandlers(service)
+		router := gin.New()
+		router.Use(func(ctx *gin.Context) {
+			principal := &entity.Principal{Subject: "test", Method: entity.AuthMethodAPIKey, Role: role}
+			ctx.Request = ctx.Request.WithContext(identity.WithPrincipal(ctx.Request.Context(), principal))
+		})
+		router.GET("/api/v1/products/", handlers.GetAllProducts)
+		router.GET("/api/v1/products/:sku", handlers.GetProductBySku)
+		router.POST("/api/v1/products/:sku/publish", handlers.PublishProduct)
+		router.POST("/api/v1/products/:sku/discontinue", handlers.DiscontinueProduct)
+		return router
+	}
+	send := func(router *gin.Engine, method string, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+		return rr
+	}
+	listed := func(t *testing.T, rr *httptest.ResponseRecorder) []string {
+		products := make([]response.DTOProduct, 0)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &products))
+		skus := make([]string, 0, len(products))
+		for _, product := range products {
+			skus = append(skus, product.Sku+" "+product.Status)
+		}
+		return skus
+	}
+
+	t.Run("PublishProduct and DiscontinueProduct - 200 OK", func(t *testing.T) {
+		service := newGraphQLTestService(t)
+		_, err := service.CreateProduct(context.Background(), commandProduct("FAL-1000000"))
+		assert.NoError(t, err)
+		editor := newRouter(service, entity.RoleEditor)
+
+		rr := send(editor, http.MethodPost, "/api/v1/products/FAL-1000000/publish")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"status":"active"`)
+
+		rr = send(editor, http.MethodPost, "/api/v1/products/FAL-1000000/discontinue")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"status":"discontinued"`)
+	})
+
+	t.Run("PublishProduct - 403 Forbidden reactivating without the admin role", func(t *testing.T) {
+		service := newGraphQLTestService(t, "FAL-1000000")
+		editor := newRouter(service, entity.RoleEditor)
+		assert.Equal(t, http.StatusOK, send(editor, http.MethodPost, "/api/v1/products/FAL-1000000/discontinue").Code)
+
+		rr := send(editor, http.MethodPost, "/api/v1/products/FAL-1000000/publish")
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		rr = send(newRouter(service, entity.RoleAdmin), http.MethodPost, "/api/v1/products/FAL-1000000/publish")
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("DiscontinueProduct - 422 Unprocessable entity for a draft", func(t *testing.T) {
+		service := newGraphQLTestService(t)
+		_, err := service.CreateProduct(context.Background(), commandProduct("FAL-1000000"))
+		assert.NoError(t, err)
+
+		rr := send(newRouter(service, entity.RoleEditor), http.MethodPost, "/api/v1/products/FAL-1000000/discontinue")
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Contains(t, rr.Body.String(), entity.ErrInvalidTransition.Error())
+	})
+
+	t.Run("PublishProduct - 404 Not found", func(t *testing.T) {
+		rr := send(newRouter(newGraphQLTestService(t), entity.RoleEditor), http.MethodPost, "/api/v1/products/FAL-1000000/publish")
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("GetProductBySku - 404 Not found for drafts unless an admin asks", func(t *testing.T) {
+		service := newGraphQLTestService(t)
+		_, err := service.CreateProduct(context.Background(), commandProduct("FAL-1000000"))
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusNotFound, send(newRouter(service, entity.RoleReader), http.MethodGet, "/api/v1/products/FAL-1000000").Code)
+		assert.Equal(t, http.StatusOK, send(newRouter(service, entity.RoleAdmin), http.MethodGet, "/api/v1/products/FAL-1000000").Code)
+	})
+
+	t.Run("GetAllProducts - only active products unless an admin asks", func(t *testing.T) {
+		service := newGraphQLTestService(t, "FAL-1000000", "FAL-1000001")
+		_, err := service.CreateProduct(context.Background(), commandProduct("FAL-1000002"))
+		assert.NoError(t, err)
+		_, err = service.ChangeStatus(context.Background(), "FAL-1000001", entity.StatusDiscontinued, nil)
+		assert.NoError(t, err)
+		reader := newRouter(service, entity.RoleReader)
+		admin := newRouter(service, entity.RoleAdmin)
+
+		assert.Equal(t, []string{"FAL-1000000 active"}, listed(t, send(reader, http.MethodGet, "/api/v1/products/")))
+		assert.Equal(t, []string{"FAL-1000000 active"}, listed(t, send(reader, http.MethodGet, "/api/v1/products/?status=active")))
+		assert.Equal(t, http.StatusForbidden, send(reader, http.MethodGet, "/api/v1/products/?status=draft").Code)
+		assert.Equal(t, []string{"FAL-1000000 active", "FAL-1000001 discontinued", "FAL-1000002 draft"}, listed(t, send(admin, http.MethodGet, "/api/v1/products/")))
+		assert.Equal(t, []string{"FAL-1000002 draft"}, listed(t, send(admin, http.MethodGet, "/api/v1/products/?status=draft")))
+		assert.Equal(t, http.StatusUnprocessableEntity, send(admin, http.MethodGet, "/api/v1/products/?status=archived").Code)
+	})
+}
+
+func TestProductAvailability(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(service usecase.Service, role entity.Role) *gin.Engine {
+		handlers := NewProductHandlers(service)
+		router := gin.New()
+		router.Use(func(ctx *gin.Context) {
+			principal := &entity.Principal{Subject: "test", Method: entity.AuthMethodAPIKey, Role: role}
+			ctx.Request = ctx.Request.WithContext(identity.WithPrincipal(ctx.Request.Context(), principal))
+		})
+		router.GET("/api/v1/products/", handlers.GetAllProducts)
+		router.GET("/api/v1/products/:sku", handlers.GetProductBySku)
+		return router
+	}
+	send := func(router *gin.Engine, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		return rr
+	}
+	listed := func(t *testing.T, rr *httptest.ResponseRecorder) []string {
+		assert.Equal(t, http.StatusOK, rr.Code)
+		products := make([]response.DTOProduct, 0)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &products))
+		skus := make([]string, 0, len(products))
+		for _, product := range products {
+			skus = append(skus, product.Sku)
+		}
+		return skus
+	}
+	launch := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	service := newGraphQLTestService(t, "FAL-1000000")
+	scheduled := commandProduct("FAL-1000001")
+	scheduled.AvailableFrom = &launch
+	_, err := service.CreateProduct(context.Background(), scheduled)
+	assert.NoError(t, err)
+	_, err = service.ChangeStatus(context.Background(), scheduled.Sku, entity.StatusActive, nil)
+	assert.NoError(t, err)
+	reader := newRouter(service, entity.RoleReader)
+	admin := newRouter(service, entity.RoleAdmin)
+	asOf := url.QueryEscape(launch.Format(time.RFC3339))
+
+	t.Run("GetAllProducts - hides the products whose window is not open", func(t *testing.T) {
+		assert.Equal(t, []string{"FAL-1000000"}, listed(t, send(reader, "/api/v1/products/")))
+		assert.Equal(t, []string{"FAL-1000000", "FAL-1000001"}, listed(t, send(admin, "/api/v1/products/")))
+	})
+
+	t.Run("GetProductBySku - 404 Not found outside the window", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, send(reader, "/api/v1/products/FAL-1000001").Code)
+		rr := send(admin, "/api/v1/products/FAL-1000001")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"available_from":"`+launch.Format(time.RFC3339)+`"`)
+	})
+
+	t.Run("as_of - previews the catalog for admins", func(t *testing.T) {
+		assert.Equal(t, []string{"FAL-1000000", "FAL-1000001"}, listed(t, send(admin, "/api/v1/products/?as_of="+asOf)))
+		assert.Equal(t, []string{"FAL-1000000"}, listed(t, send(admin, "/api/v1/products/?as_of="+url.QueryEscape(launch.Add(-time.Second).Format(time.RFC3339)))))
+		assert.Equal(t, http.StatusOK, send(admin, "/api/v1/products/FAL-1000001?as_of="+asOf).Code)
+		assert.Equal(t, http.StatusForbidden, send(reader, "/api/v1/products/?as_of="+asOf).Code)
+		assert.Equal(t, http.StatusForbidden, send(reader, "/api/v1/products/FAL-1000001?as_of="+asOf).Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, send(admin, "/api/v1/products/?as_of=tomorrow").Code)
+	})
+}
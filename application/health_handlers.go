@@ -0,0 +1,111 @@
+package application
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+	statusDraining    = "draining"
+)
+
+var DefaultReadinessCheckTimeout = 2 * time.Second
+
+// DependencyCheck returns an error when the dependency it probes can't
+// serve requests.
+type DependencyCheck func(ctx context.Context) error
+
+type dependencyStatus struct {
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Latency string `json:"latency"`
+}
+
+type readinessResponse struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]dependencyStatus `json:"dependencies"`
+}
+
+type HealthHandlers struct {
+	checks     map[string]DependencyCheck
+	isDraining func() bool
+	timeout    time.Duration
+}
+
+func NewHealthHandlers(checks map[string]DependencyCheck, isDraining func() bool) *HealthHandlers {
+	return &HealthHandlers{
+		checks:     checks,
+		isDraining: isDraining,
+		timeout:    DefaultReadinessCheckTimeout,
+	}
+}
+
+// Liveness godoc
+// @Summary Liveness probe
+// @Description reports that the process is up, without checking dependencies
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /healthz [get]
+func (hh *HealthHandlers) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": statusOK})
+}
+
+// Readiness godoc
+// @Summary Readiness probe
+// @Description reports whether every dependency is available; fails while the server drains
+// @Produce json
+// @Success 200 {object} readinessResponse
+// @Failure 503 {object} readinessResponse
+// @Router /readyz [get]
+func (hh *HealthHandlers) Readiness(ctx *gin.Context) {
+	if hh.isDraining != nil && hh.isDraining() {
+		ctx.JSON(http.StatusServiceUnavailable, readinessResponse{
+			Status:       statusDraining,
+			Dependencies: map[string]dependencyStatus{},
+		})
+		return
+	}
+
+	result := readinessResponse{
+		Status:       statusOK,
+		Dependencies: make(map[string]dependencyStatus, len(hh.checks)),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range hh.checks {
+		wg.Add(1)
+		go func(name string, check DependencyCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), hh.timeout)
+			defer cancel()
+
+			started := time.Now()
+			err := check(checkCtx)
+			status := dependencyStatus{Status: statusOK, Latency: time.Since(started).String()}
+			if err != nil {
+				status.Status = statusUnavailable
+				status.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			result.Dependencies[name] = status
+			if err != nil {
+				result.Status = statusUnavailable
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	if result.Status != statusOK {
+		ctx.JSON(http.StatusServiceUnavailable, result)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
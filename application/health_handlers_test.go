@@ -0,0 +1,71 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(checks map[string]DependencyCheck, draining bool) *gin.Engine {
+		hh := NewHealthHandlers(checks, func() bool { return draining })
+		router := gin.Default()
+		router.GET("/healthz", hh.Liveness)
+		router.GET("/readyz", hh.Readiness)
+		return router
+	}
+	healthy := func(ctx context.Context) error { return nil }
+	failing := func(ctx context.Context) error { return errors.New("connection refused") }
+
+	t.Run("Liveness - 200 OK", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+		newRouter(map[string]DependencyCheck{"database": failing}, false).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"status":"ok"}`, rr.Body.String())
+	})
+
+	t.Run("Readiness - 200 OK", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+		newRouter(map[string]DependencyCheck{"database": healthy, "migrations": healthy}, false).ServeHTTP(rr, request)
+
+		body := readinessResponse{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, statusOK, body.Status)
+		assert.Equal(t, statusOK, body.Dependencies["database"].Status)
+		assert.Equal(t, statusOK, body.Dependencies["migrations"].Status)
+	})
+
+	t.Run("Readiness - 503 Service Unavailable (failing dependency)", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+		newRouter(map[string]DependencyCheck{"database": failing, "migrations": healthy}, false).ServeHTTP(rr, request)
+
+		body := readinessResponse{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Equal(t, statusUnavailable, body.Status)
+		assert.Equal(t, "connection refused", body.Dependencies["database"].Error)
+		assert.Equal(t, statusOK, body.Dependencies["migrations"].Status)
+	})
+
+	t.Run("Readiness - 503 Service Unavailable (draining)", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+		newRouter(map[string]DependencyCheck{"database": healthy}, true).ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.JSONEq(t, `{"status":"draining","dependencies":{}}`, rr.Body.String())
+	})
+}
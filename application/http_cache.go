@@ -0,0 +1,131 @@
+package application
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	DefaultProductCacheControl  = "private, max-age=60, must-revalidate"
+	DefaultProductsCacheControl = "private, max-age=30, must-revalidate"
+)
+
+// noStore is sent instead of the policy of a route by the responses that
+// must not be reused: errors and what only admins or previews get to see.
+const noStore = "no-store"
+
+// CachePolicies holds the Cache-Control value sent by each cacheable route.
+type CachePolicies struct {
+	Product  string
+	Products string
+}
+
+func NewCachePolicies(product, products string) CachePolicies {
+	policies := CachePolicies{
+		Product:  DefaultProductCacheControl,
+		Products: DefaultProductsCacheControl,
+	}
+	if strings.TrimSpace(product) != "" {
+		policies.Product = product
+	}
+	if strings.TrimSpace(products) != "" {
+		policies.Products = products
+	}
+	return policies
+}
+
+// CacheControl sends policy with the successful responses of a route. As
+// every route answers according to the credentials of the caller, responses
+// vary on them; admin callers and listings narrowed by status or previewed
+// as of another time, which admins alone may ask for, are never stored.
+func CacheControl(policy string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Vary", "Authorization, "+APIKeyHeader)
+		sent := policy
+		if isAdmin(ctx.Request.Context()) || ctx.Query("status") != "" || ctx.Query("as_of") != "" {
+			sent = noStore
+		}
+		ctx.Header("Cache-Control", sent)
+		ctx.Writer = &cacheControlWriter{ResponseWriter: ctx.Writer}
+		ctx.Next()
+	}
+}
+
+// cacheControlWriter replaces the Cache-Control of error responses before
+// they are written, so they are never stored with the policy of the route.
+type cacheControlWriter struct {
+	gin.ResponseWriter
+}
+
+func (w *cacheControlWriter) WriteHeader(code int) {
+	if code >= http.StatusMultipleChoices && code != http.StatusNotModified {
+		w.Header().Set("Cache-Control", noStore)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// conditionalJSON writes body with strong validators and answers with
+// 304 Not Modified when the request preconditions show the client copy is
+// still current.
+func conditionalJSON(ctx *gin.Context, status int, body interface{}, lastModified time.Time) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, body)
+		return
+	}
+
+	etag := strongETag(payload)
+	ctx.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		ctx.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(ctx.Request, etag, lastModified) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	ctx.Data(status, "application/json; charset=utf-8", payload)
+}
+
+// collectionJSON writes a collection of products like conditionalJSON, but
+// without Last-Modified: deleting a product or making one visible does not
+// move the newest UpdatedAt, so If-Modified-Since would keep answering 304
+// with the old collection. Collections are only revalidated by ETag.
+func collectionJSON(ctx *gin.Context, status int, body interface{}) {
+	conditionalJSON(ctx, status, body, time.Time{})
+}
+
+func strongETag(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified follows RFC 7232 section 6: If-None-Match takes precedence and,
+// when present, If-Modified-Since is ignored.
+func notModified(request *http.Request, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := request.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	ifModifiedSince := request.Header.Get("If-Modified-Since")
+	if ifModifiedSince == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
@@ -0,0 +1,55 @@
+package application
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/shared/identity"
+)
+
+func TestCacheControl(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := "private, max-age=30"
+
+	serve := func(role entity.Role, target string, status int) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.Use(func(ctx *gin.Context) {
+			principal := &entity.Principal{Subject: "test", Method: entity.AuthMethodAPIKey, Role: role}
+			ctx.Request = ctx.Request.WithContext(identity.WithPrincipal(ctx.Request.Context(), principal))
+		})
+		router.GET("/products/", CacheControl(policy), func(ctx *gin.Context) {
+			ctx.JSON(status, gin.H{})
+		})
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, target, nil)
+		router.ServeHTTP(rr, request)
+		return rr
+	}
+
+	t.Run("should send the policy and vary on credentials", func(t *testing.T) {
+		rr := serve(entity.RoleReader, "/products/", http.StatusOK)
+		assert.Equal(t, policy, rr.Header().Get("Cache-Control"))
+		assert.Equal(t, "Authorization, X-API-Key", rr.Header().Get("Vary"))
+
+		rr = serve(entity.RoleReader, "/products/", http.StatusNotModified)
+		assert.Equal(t, policy, rr.Header().Get("Cache-Control"))
+	})
+
+	t.Run("should not store errors", func(t *testing.T) {
+		for _, status := range []int{http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusForbidden} {
+			rr := serve(entity.RoleReader, "/products/", status)
+			assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"), http.StatusText(status))
+		}
+	})
+
+	t.Run("should not store admin views", func(t *testing.T) {
+		assert.Equal(t, "no-store", serve(entity.RoleAdmin, "/products/", http.StatusOK).Header().Get("Cache-Control"))
+		assert.Equal(t, "no-store", serve(entity.RoleReader, "/products/?status=active", http.StatusOK).Header().Get("Cache-Control"))
+		assert.Equal(t, "no-store", serve(entity.RoleAdmin, "/products/?as_of=2030-01-01T00:00:00Z", http.StatusOK).Header().Get("Cache-Control"))
+		assert.Equal(t, policy, serve(entity.RoleReader, "/products/", http.StatusOK).Header().Get("Cache-Control"))
+	})
+}
@@ -0,0 +1,169 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// HTTPTimeouts configures the HTTP server. Request is the deadline given to
+// handlers, see RequestDeadline. DrainDelay is how long /readyz reports
+// draining before the server stops accepting connections, giving load
+// balancers time to take the instance out of rotation.
+type HTTPTimeouts struct {
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+	Request    time.Duration
+	Shutdown   time.Duration
+	DrainDelay time.Duration
+}
+
+// IsDraining reports whether the server is shutting down.
+func (s *Server) IsDraining() bool {
+	return atomic.LoadInt32(&s.draining) == 1
+}
+
+// ShutdownHook releases a resource when the server stops. Hooks run after
+// in-flight requests have drained, in reverse registration order, and must
+// return once ctx is done.
+type ShutdownHook func(ctx context.Context) error
+
+func (s *Server) OnShutdown(hook ShutdownHook) {
+	s.shutdownHooks = append(s.shutdownHooks, hook)
+}
+
+// StartWorker runs worker in the background until the server shuts down.
+// Its shutdown hook cancels the worker's context and waits for it to return.
+func (s *Server) StartWorker(name string, worker func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		worker(ctx)
+	}()
+	s.OnShutdown(func(shutdownCtx context.Context) error {
+		cancel()
+		select {
+		case <-done:
+			log.WithField("worker", name).Infoln("background worker stopped")
+			return nil
+		case <-shutdownCtx.Done():
+			return fmt.Errorf("%s did not stop: %w", name, shutdownCtx.Err())
+		}
+	})
+}
+
+// Run serves HTTP until SIGINT or SIGTERM is received and then shuts the
+// server down gracefully.
+func (s *Server) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	listener, err := net.Listen("tcp", s.httpAddr)
+	if err != nil {
+		return err
+	}
+	if s.grpcServer != nil {
+		grpcListener, err := net.Listen("tcp", s.grpcAddr)
+		if err != nil {
+			_ = listener.Close()
+			return err
+		}
+		go s.serveGRPC(grpcListener)
+	}
+	return s.Serve(ctx, listener)
+}
+
+func (s *Server) serveGRPC(listener net.Listener) {
+	log.WithField("addr", listener.Addr().String()).Infoln("listening for gRPC requests")
+	if err := s.grpcServer.Serve(listener); err != nil {
+		log.WithError(err).Errorln("error serving gRPC requests")
+	}
+}
+
+// stopGRPC waits for in-flight gRPC calls until ctx expires and then
+// closes the remaining ones. Watch calls end when the stream broadcaster is
+// closed, as the HTTP server shuts down.
+func (s *Server) stopGRPC(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		return fmt.Errorf("gRPC server did not stop: %w", ctx.Err())
+	}
+}
+
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		log.WithField("addr", listener.Addr().String()).Infoln("listening for HTTP requests")
+		serveErr <- s.httpServer.Serve(listener)
+	}()
+
+	var err error
+	select {
+	case err = <-serveErr:
+	case <-ctx.Done():
+		log.Infoln("shutdown signal received, draining in-flight requests")
+		atomic.StoreInt32(&s.draining, 1)
+		if s.timeouts.DrainDelay > 0 {
+			time.Sleep(s.timeouts.DrainDelay)
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.timeouts.Shutdown)
+	defer cancel()
+	if shutdownErr := s.Shutdown(shutdownCtx); err == nil {
+		err = shutdownErr
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting connections, waits for in-flight requests until
+// ctx expires, runs the shutdown hooks and finally closes the database pool.
+func (s *Server) Shutdown(ctx context.Context) error {
+	atomic.StoreInt32(&s.draining, 1)
+	err := s.httpServer.Shutdown(ctx)
+	if err != nil {
+		log.WithError(err).Errorln("error trying to drain in-flight requests")
+	}
+
+	for i := len(s.shutdownHooks) - 1; i >= 0; i-- {
+		if hookErr := s.shutdownHooks[i](ctx); hookErr != nil {
+			log.WithError(hookErr).Errorln("error running shutdown hook")
+			if err == nil {
+				err = hookErr
+			}
+		}
+	}
+
+	if s.dbClient != nil {
+		if closeErr := s.dbClient.Close(); closeErr != nil {
+			log.WithError(closeErr).Errorln("error trying to close DB connections")
+			if err == nil {
+				err = closeErr
+			}
+		}
+	}
+	log.Infoln("server stopped")
+	return err
+}
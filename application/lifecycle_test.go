@@ -0,0 +1,76 @@
+package application
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerGracefulShutdown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	started := make(chan struct{})
+	engine := gin.New()
+	engine.GET("/slow", func(ctx *gin.Context) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		ctx.String(http.StatusOK, "done")
+	})
+	server := &Server{
+		engine:     engine,
+		timeouts:   HTTPTimeouts{Shutdown: time.Second},
+		httpServer: &http.Server{Handler: engine},
+	}
+	hookCalls := make([]string, 0)
+	server.OnShutdown(func(ctx context.Context) error {
+		hookCalls = append(hookCalls, "first")
+		return nil
+	})
+	server.OnShutdown(func(ctx context.Context) error {
+		hookCalls = append(hookCalls, "second")
+		return nil
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ctx, listener) }()
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		response, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer response.Body.Close()
+		body, _ := io.ReadAll(response.Body)
+		responses <- result{status: response.StatusCode, body: string(body)}
+	}()
+
+	<-started
+	cancel()
+
+	got := <-responses
+	assert.NoError(t, got.err)
+	assert.Equal(t, http.StatusOK, got.status)
+	assert.Equal(t, "done", got.body)
+	assert.NoError(t, <-served)
+	assert.Equal(t, []string{"second", "first"}, hookCalls)
+	assert.True(t, server.IsDraining())
+
+	_, err = http.Get("http://" + listener.Addr().String() + "/slow")
+	assert.Error(t, err)
+}
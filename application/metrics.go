@@ -0,0 +1,25 @@
+package application
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yescorihuela/agrak/infrastructure/metrics"
+)
+
+const unmatchedRoute = "unmatched"
+
+// Instrument records the count and latency of every request by route
+// template, so /products/FAL-1000000 and /products/FAL-1000001 share a series.
+func Instrument(m *metrics.Metrics) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		started := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		m.ObserveHTTPRequest(ctx.Request.Method, route, ctx.Writer.Status(), time.Since(started))
+	}
+}
@@ -0,0 +1,50 @@
+package application
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yescorihuela/agrak/infrastructure/metrics"
+)
+
+func TestInstrument(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	serverMetrics := metrics.New()
+	router := gin.New()
+	router.Use(Instrument(serverMetrics))
+	router.GET("/api/v1/products/:sku", func(ctx *gin.Context) {
+		if ctx.Param("sku") == "FAL-9999999" {
+			ctx.Status(http.StatusNotFound)
+			return
+		}
+		ctx.Status(http.StatusOK)
+	})
+	router.GET("/metrics", gin.WrapH(serverMetrics.Handler()))
+
+	for _, resource := range []string{
+		"/api/v1/products/FAL-1000000",
+		"/api/v1/products/FAL-1000001",
+		"/api/v1/products/FAL-9999999",
+		"/unknown/path",
+	} {
+		request, _ := http.NewRequest(http.MethodGet, resource, nil)
+		router.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	rr := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	router.ServeHTTP(rr, request)
+	body := rr.Body.String()
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, body, `products_api_http_requests_total{method="GET",route="/api/v1/products/:sku",status="200"} 2`)
+	assert.Contains(t, body, `products_api_http_requests_total{method="GET",route="/api/v1/products/:sku",status="404"} 1`)
+	assert.Contains(t, body, `products_api_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `products_api_http_request_duration_seconds_bucket{method="GET",route="/api/v1/products/:sku",status="200"`)
+	assert.Contains(t, body, "go_goroutines")
+	assert.False(t, strings.Contains(body, "FAL-1000000"))
+}
@@ -0,0 +1,168 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/factory"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/response"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+// productCommands implements the product subcommands of the CLI on top of
+// the same use case the HTTP handlers use. Products are read and written as
+// the JSON documents of the API.
+type productCommands struct {
+	service usecase.Service
+	out     io.Writer
+}
+
+func (pc productCommands) get(ctx context.Context, sku string) error {
+	product, err := pc.service.FindBySku(ctx, sku)
+	if err != nil {
+		return fmt.Errorf("%s: %w", sku, err)
+	}
+	return pc.write(pc.out, response.ConvertFromEntityToResponse(*product))
+}
+
+func (pc productCommands) create(ctx context.Context, input response.DTOProduct) error {
+	product, err := newProduct(input)
+	if err != nil {
+		return err
+	}
+	createdProduct, err := pc.service.CreateProduct(ctx, *product)
+	if err != nil {
+		return fmt.Errorf("%s: %w", product.Sku, err)
+	}
+	return pc.write(pc.out, response.ConvertFromEntityToResponse(*createdProduct))
+}
+
+func (pc productCommands) delete(ctx context.Context, sku string) error {
+	if err := pc.service.DeleteProduct(ctx, sku); err != nil {
+		return fmt.Errorf("%s: %w", sku, err)
+	}
+	fmt.Fprintf(pc.out, "deleted %s\n", sku)
+	return nil
+}
+
+func (pc productCommands) exportTo(ctx context.Context, out io.Writer) error {
+	products, err := pc.service.FindAll(ctx)
+	if err != nil && !errors.Is(err, repository.ErrProductNotFound) {
+		return err
+	}
+	documents := make([]response.DTOProduct, 0, len(products))
+	for _, product := range products {
+		documents = append(documents, *response.ConvertFromEntityToResponse(product))
+	}
+	return pc.write(out, documents)
+}
+
+// importFrom creates the products of a JSON array that do not exist and
+// updates the others, moving them to the status they were exported with
+// like the API would: products are only published when they pass every
+// validation, and the command has no admin rights to reactivate
+// discontinued ones. Every document is validated before anything is
+// written, so a bad document leaves the catalog untouched.
+func (pc productCommands) importFrom(ctx context.Context, input io.Reader) error {
+	documents := make([]response.DTOProduct, 0)
+	if err := json.NewDecoder(input).Decode(&documents); err != nil {
+		return fmt.Errorf("error trying to read the products: %w", err)
+	}
+	products := make([]entity.Product, 0, len(documents))
+	for i, document := range documents {
+		product, err := newProduct(document)
+		if err != nil {
+			return fmt.Errorf("product %d (%s): %w", i, document.Sku, err)
+		}
+		products = append(products, *product)
+	}
+
+	created, updated := 0, 0
+	for _, product := range products {
+		isNew, err := pc.service.RestoreProduct(ctx, product, nil)
+		if err != nil {
+			return fmt.Errorf("%s: %w", product.Sku, err)
+		}
+		if isNew {
+			created++
+		} else {
+			updated++
+		}
+	}
+	fmt.Fprintf(pc.out, "imported %d products: %d created, %d updated\n", len(products), created, updated)
+	return nil
+}
+
+// seed creates count sample products with consecutive SKUs from
+// FAL-1000000, leaving existing ones untouched.
+func (pc productCommands) seed(ctx context.Context, count int) error {
+	if count < 0 || count > entity.SkuMax-entity.SkuMin+1 {
+		return fmt.Errorf("invalid count %d", count)
+	}
+	created := 0
+	for i := 0; i < count; i++ {
+		sample := sampleProducts[i%len(sampleProducts)]
+		sample.Sku = fmt.Sprintf("%s-%d", entity.SkuPrefix, entity.SkuMin+i)
+		product, err := newProduct(sample)
+		if err != nil {
+			return err
+		}
+		_, err = pc.service.FindBySku(ctx, product.Sku)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, repository.ErrProductNotFound) {
+			return fmt.Errorf("%s: %w", product.Sku, err)
+		}
+		if _, err := pc.service.CreateProduct(ctx, *product); err != nil {
+			return fmt.Errorf("%s: %w", product.Sku, err)
+		}
+		created++
+	}
+	fmt.Fprintf(pc.out, "seeded %d products, %d already existed\n", created, count-created)
+	return nil
+}
+
+func (pc productCommands) write(out io.Writer, document interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+// newProduct applies the validations of the HTTP handlers to a document.
+func newProduct(document response.DTOProduct) (*entity.Product, error) {
+	product, err := factory.NewProduct(
+		document.Sku,
+		document.Name,
+		document.Brand,
+		document.Size,
+		document.Price,
+		document.PrincipalImage,
+		document.OtherImages,
+	)
+	if err != nil {
+		return nil, err
+	}
+	product.Status = entity.ProductStatus(document.Status)
+	if product.Status != "" && !product.Status.IsValid() {
+		return nil, usecase.ErrInvalidStatus
+	}
+	product.AvailableFrom = document.AvailableFrom
+	product.AvailableUntil = document.AvailableUntil
+	if _, err := product.IsValid(); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+var sampleProducts = []response.DTOProduct{
+	{Name: "500 Zapatilla Urbana Mujer", Brand: "New Balance", Size: "37", Price: 42990, PrincipalImage: "https://placehold.jp/3d4070/ffffff/150x150.png"},
+	{Name: "Bicicleta Baltoro Aro 29", Brand: "Jeep", Size: "ST", Price: 399990, PrincipalImage: "https://placehold.jp/3d4070/ffffff/150x150.png"},
+	{Name: "Polera Manga Corta", Brand: "CAT", Size: "XL", Price: 20000, PrincipalImage: "https://placehold.jp/3d4070/ffffff/150x150.png"},
+	{Name: "Mochila Urbana", Brand: "Samsonite", Size: "ST", Price: 59990, PrincipalImage: "https://placehold.jp/3d4070/ffffff/150x150.png"},
+}
@@ -0,0 +1,163 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/response"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+func commandProduct(sku string) entity.Product {
+	return entity.Product{
+		Sku:            sku,
+		Name:           "Polera",
+		Brand:          "CAT",
+		Size:           "XL",
+		Price:          20000,
+		PrincipalImage: "https://placehold.jp/3d4070/ffffff/150x150.png",
+		OtherImages:    []string{},
+	}
+}
+
+func TestProductCommands(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("get should print the product as JSON", func(t *testing.T) {
+		mockUsecase := new(usecase.UseCaseMock)
+		product := commandProduct("FAL-1000000")
+		mockUsecase.On("FindBySku", "FAL-1000000").Return(&product, nil)
+		out := new(bytes.Buffer)
+
+		err := productCommands{service: mockUsecase, out: out}.get(ctx, "FAL-1000000")
+
+		assert.NoError(t, err)
+		document := response.DTOProduct{}
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &document))
+		assert.Equal(t, "FAL-1000000", document.Sku)
+	})
+
+	t.Run("create should validate like the HTTP API", func(t *testing.T) {
+		mockUsecase := new(usecase.UseCaseMock)
+		input := *response.ConvertFromEntityToResponse(commandProduct("FAL-1"))
+
+		err := productCommands{service: mockUsecase, out: new(bytes.Buffer)}.create(ctx, input)
+
+		assert.Error(t, err)
+		mockUsecase.AssertNotCalled(t, "CreateProduct", mock.Anything)
+	})
+
+	t.Run("import should create missing products and update existing ones", func(t *testing.T) {
+		mockUsecase := new(usecase.UseCaseMock)
+		existing, missing := commandProduct("FAL-1000000"), commandProduct("FAL-1000001")
+		mockUsecase.On("RestoreProduct", existing, (*entity.Principal)(nil)).Return(false, nil)
+		mockUsecase.On("RestoreProduct", missing, (*entity.Principal)(nil)).Return(true, nil)
+		input, _ := json.Marshal([]response.DTOProduct{
+			*response.ConvertFromEntityToResponse(existing),
+			*response.ConvertFromEntityToResponse(missing),
+		})
+		out := new(bytes.Buffer)
+
+		err := productCommands{service: mockUsecase, out: out}.importFrom(ctx, bytes.NewReader(input))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "imported 2 products: 1 created, 1 updated\n", out.String())
+		mockUsecase.AssertExpectations(t)
+	})
+
+	t.Run("import should write nothing when a product is invalid", func(t *testing.T) {
+		mockUsecase := new(usecase.UseCaseMock)
+		input := `[{"sku":"FAL-1000000","name":"Polera","brand":"CAT","price":20000,"principal_image":"https://placehold.jp/a.png"},{"sku":"FAL-1000001"}]`
+
+		err := productCommands{service: mockUsecase, out: new(bytes.Buffer)}.importFrom(ctx, strings.NewReader(input))
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "product 1 (FAL-1000001)")
+		mockUsecase.AssertNotCalled(t, "RestoreProduct", mock.Anything, mock.Anything)
+	})
+
+	t.Run("import should restore an export with its statuses", func(t *testing.T) {
+		source := newGraphQLTestService(t, "FAL-1000000", "FAL-1000001")
+		_, err := source.CreateProduct(ctx, commandProduct("FAL-1000002"))
+		assert.NoError(t, err)
+		_, err = source.ChangeStatus(ctx, "FAL-1000001", entity.StatusDiscontinued, nil)
+		assert.NoError(t, err)
+		exported := new(bytes.Buffer)
+		assert.NoError(t, productCommands{service: source, out: new(bytes.Buffer)}.exportTo(ctx, exported))
+
+		target := newGraphQLTestService(t, "FAL-1000001")
+		err = productCommands{service: target, out: new(bytes.Buffer)}.importFrom(ctx, bytes.NewReader(exported.Bytes()))
+		assert.NoError(t, err)
+
+		products, err := target.FindAll(ctx)
+		assert.NoError(t, err)
+		statuses := make(map[string]entity.ProductStatus, len(products))
+		for _, product := range products {
+			statuses[product.Sku] = product.Status
+		}
+		assert.Equal(t, map[string]entity.ProductStatus{
+			"FAL-1000000": entity.StatusActive,
+			"FAL-1000001": entity.StatusDiscontinued,
+			"FAL-1000002": entity.StatusDraft,
+		}, statuses)
+	})
+
+	t.Run("import should change statuses like the API", func(t *testing.T) {
+		target := newGraphQLTestService(t, "FAL-1000000", "FAL-1000001")
+		_, err := target.ChangeStatus(ctx, "FAL-1000001", entity.StatusDiscontinued, nil)
+		assert.NoError(t, err)
+		for document, expected := range map[string]error{
+			`[{"sku":"FAL-1000000","name":"Polera","brand":"CAT","size":"XL","price":20000,"principal_image":"https://placehold.jp/a.png","status":"draft"}]`:  entity.ErrInvalidTransition,
+			`[{"sku":"FAL-1000001","name":"Polera","brand":"CAT","size":"XL","price":20000,"principal_image":"https://placehold.jp/a.png","status":"active"}]`: entity.ErrTransitionForbidden,
+		} {
+			err := productCommands{service: target, out: new(bytes.Buffer)}.importFrom(ctx, strings.NewReader(document))
+
+			assert.ErrorIs(t, err, expected)
+		}
+		product, err := target.FindBySku(ctx, "FAL-1000001")
+		assert.NoError(t, err)
+		assert.Equal(t, entity.StatusDiscontinued, product.Status)
+	})
+
+	t.Run("import should reject an unknown status", func(t *testing.T) {
+		mockUsecase := new(usecase.UseCaseMock)
+		input := `[{"sku":"FAL-1000000","name":"Polera","brand":"CAT","price":20000,"principal_image":"https://placehold.jp/a.png","status":"archived"}]`
+
+		err := productCommands{service: mockUsecase, out: new(bytes.Buffer)}.importFrom(ctx, strings.NewReader(input))
+
+		assert.ErrorIs(t, err, usecase.ErrInvalidStatus)
+		mockUsecase.AssertNotCalled(t, "RestoreProduct", mock.Anything, mock.Anything)
+	})
+
+	t.Run("seed should skip existing products", func(t *testing.T) {
+		mockUsecase := new(usecase.UseCaseMock)
+		existing := commandProduct("FAL-1000000")
+		mockUsecase.On("FindBySku", "FAL-1000000").Return(&existing, nil)
+		mockUsecase.On("FindBySku", "FAL-1000001").Return(nil, repository.ErrProductNotFound)
+		mockUsecase.On("CreateProduct", mock.Anything).Return(nil, nil)
+		out := new(bytes.Buffer)
+
+		err := productCommands{service: mockUsecase, out: out}.seed(ctx, 2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "seeded 1 products, 1 already existed\n", out.String())
+		mockUsecase.AssertNumberOfCalls(t, "CreateProduct", 1)
+	})
+
+	t.Run("export should fail when the service fails", func(t *testing.T) {
+		mockUsecase := new(usecase.UseCaseMock)
+		mockUsecase.On("FindAll").Return(nil, errors.New("connection refused"))
+
+		err := productCommands{service: mockUsecase, out: new(bytes.Buffer)}.exportTo(ctx, new(bytes.Buffer))
+
+		assert.Error(t, err)
+	})
+}
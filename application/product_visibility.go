@@ -0,0 +1,94 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/shared/identity"
+)
+
+var (
+	errInvalidStatus   = errors.New("status must be draft, active or discontinued")
+	errStatusForbidden = errors.New("only admins can list products that are not active")
+	errInvalidAsOf     = errors.New("as_of must be an RFC 3339 date-time")
+	errAsOfForbidden   = errors.New("only admins can preview the catalog at another time")
+)
+
+// isAdmin tells whether the caller of ctx holds the admin role or scope.
+func isAdmin(ctx context.Context) bool {
+	principal := identity.FromContext(ctx)
+	return principal != nil && principal.Allows(entity.RoleAdmin, entity.ScopeAdmin)
+}
+
+// listedStatus returns the status a product listing is narrowed to when
+// requested is asked for. Admins see every status unless they ask for one;
+// everybody else only sees active products.
+func listedStatus(ctx context.Context, requested string) (entity.ProductStatus, error) {
+	status := entity.ProductStatus(requested)
+	if status != "" && !status.IsValid() {
+		return "", errInvalidStatus
+	}
+	if isAdmin(ctx) {
+		return status, nil
+	}
+	if status != "" && status != entity.StatusActive {
+		return "", errStatusForbidden
+	}
+	return entity.StatusActive, nil
+}
+
+// availableAt returns the time the availability windows of products are
+// checked at for the caller of ctx: now for everybody but admins, who see
+// every product unless they preview the catalog as of asOf. The zero time
+// skips the check.
+func availableAt(ctx context.Context, asOf string) (time.Time, error) {
+	if asOf == "" {
+		if isAdmin(ctx) {
+			return time.Time{}, nil
+		}
+		return time.Now(), nil
+	}
+	if !isAdmin(ctx) {
+		return time.Time{}, errAsOfForbidden
+	}
+	at, err := time.Parse(time.RFC3339, asOf)
+	if err != nil {
+		return time.Time{}, errInvalidAsOf
+	}
+	return at, nil
+}
+
+// listingFilter returns the filter of a product listing asked for status as
+// of asOf, see listedStatus and availableAt. A preview lists the active
+// products, like the public would see them, unless a status is asked for.
+func listingFilter(ctx context.Context, status string, asOf string) (repository.ProductFilter, error) {
+	listed, err := listedStatus(ctx, status)
+	if err != nil {
+		return repository.ProductFilter{}, err
+	}
+	at, err := availableAt(ctx, asOf)
+	if err != nil {
+		return repository.ProductFilter{}, err
+	}
+	if asOf != "" && status == "" {
+		listed = entity.StatusActive
+	}
+	return repository.ProductFilter{Status: listed, AvailableAt: at}, nil
+}
+
+// visibleEvent tells whether the caller of ctx may receive event from the
+// product streams: admins receive every event, everybody else the public
+// ones, about products they could look up when it occurred.
+func visibleEvent(ctx context.Context, event entity.ProductEvent) bool {
+	return isAdmin(ctx) || event.IsPublic()
+}
+
+// visibleFilter returns the filter a single product looked up as of asOf
+// must match for the caller of ctx to see it: the listing filter without a
+// requested status, so lookups by SKU never show what listings hide.
+func visibleFilter(ctx context.Context, asOf string) (repository.ProductFilter, error) {
+	return listingFilter(ctx, "", asOf)
+}
@@ -0,0 +1,136 @@
+package application
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yescorihuela/agrak/infrastructure/ratelimit"
+	"github.com/yescorihuela/agrak/infrastructure/response"
+)
+
+var (
+	DefaultReadRateLimit  = ratelimit.PerMinute(600, 100)
+	DefaultWriteRateLimit = ratelimit.PerMinute(60, 10)
+	DefaultBulkRateLimit  = ratelimit.PerMinute(10, 2)
+	// DefaultClientRateLimit is higher than the others, as the callers
+	// behind a NAT or proxy share it.
+	DefaultClientRateLimit = ratelimit.PerMinute(1200, 200)
+)
+
+// RateLimits holds the limit applied to each route group, and the one
+// applied to each client IP before its credentials are checked.
+type RateLimits struct {
+	Read   ratelimit.Limit
+	Write  ratelimit.Limit
+	Bulk   ratelimit.Limit
+	Client ratelimit.Limit
+}
+
+// NewRateLimits parses limits written as "<requests per minute>[:<burst>]".
+// Empty values keep the defaults and "0" disables limiting for the group.
+func NewRateLimits(read, write, bulk, client string) (RateLimits, error) {
+	limits := RateLimits{
+		Read:   DefaultReadRateLimit,
+		Write:  DefaultWriteRateLimit,
+		Bulk:   DefaultBulkRateLimit,
+		Client: DefaultClientRateLimit,
+	}
+	for _, field := range []struct {
+		name  string
+		value string
+		limit *ratelimit.Limit
+	}{
+		{"read", read, &limits.Read},
+		{"write", write, &limits.Write},
+		{"bulk", bulk, &limits.Bulk},
+		{"client", client, &limits.Client},
+	} {
+		if strings.TrimSpace(field.value) == "" {
+			continue
+		}
+		limit, err := parseRateLimit(field.value)
+		if err != nil {
+			return RateLimits{}, fmt.Errorf("invalid %s rate limit %q: %w", field.name, field.value, err)
+		}
+		*field.limit = limit
+	}
+	return limits, nil
+}
+
+func parseRateLimit(value string) (ratelimit.Limit, error) {
+	parts := strings.SplitN(strings.TrimSpace(value), ":", 2)
+	requests, err := strconv.Atoi(parts[0])
+	if err != nil || requests < 0 {
+		return ratelimit.Limit{}, fmt.Errorf("requests per minute must be a positive number")
+	}
+	burst := 0
+	if len(parts) == 2 {
+		burst, err = strconv.Atoi(parts[1])
+		if err != nil || burst <= 0 {
+			return ratelimit.Limit{}, fmt.Errorf("burst must be a positive number")
+		}
+	}
+	return ratelimit.PerMinute(requests, burst), nil
+}
+
+// RateLimit throttles each caller of the routes it is attached to. Callers
+// are told apart by their credentials once authenticated and by client IP
+// otherwise, so attached before Authenticate it throttles clients before
+// their credentials are looked up; group keeps the buckets of different
+// route groups apart.
+func RateLimit(limiter ratelimit.Limiter, group string, limit ratelimit.Limit) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !allowRequest(ctx, limiter, group, limit) {
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// ListingRateLimit throttles product listings with the read limit, but
+// batch gets asking for ?skus= with the bulk one, as they fetch many
+// products at once, like POST :batchGet.
+func ListingRateLimit(limiter ratelimit.Limiter, limits RateLimits) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		group, limit := "read", limits.Read
+		if _, batch := ctx.GetQuery("skus"); batch {
+			group, limit = "bulk", limits.Bulk
+		}
+		if !allowRequest(ctx, limiter, group, limit) {
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// allowRequest takes a request of the caller from the bucket of group. When
+// the bucket is empty it aborts the request and returns false.
+func allowRequest(ctx *gin.Context, limiter ratelimit.Limiter, group string, limit ratelimit.Limit) bool {
+	if limit.IsUnlimited() {
+		return true
+	}
+
+	result := limiter.Allow(group+"|"+rateLimitKey(ctx), limit)
+	ctx.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	ctx.Header("RateLimit-Reset", strconv.Itoa(result.ResetSeconds()))
+	if !result.Allowed {
+		retryAfter := result.RetryAfterSeconds()
+		ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+		ctx.AbortWithStatusJSON(http.StatusTooManyRequests, response.NewErrorResponse(
+			fmt.Sprintf("rate limit exceeded, retry in %d seconds", retryAfter),
+		))
+		return false
+	}
+	return true
+}
+
+func rateLimitKey(ctx *gin.Context) string {
+	if principal := CurrentPrincipal(ctx); principal != nil && principal.Subject != "" {
+		return string(principal.Method) + ":" + principal.Subject
+	}
+	return "ip:" + ctx.ClientIP()
+}
@@ -0,0 +1,97 @@
+package application
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yescorihuela/agrak/infrastructure/ratelimit"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("RateLimit - 429 Too Many Requests", func(t *testing.T) {
+		router := gin.Default()
+		router.GET("/products/", RateLimit(ratelimit.NewMemoryLimiter(), "read", ratelimit.PerMinute(60, 1)), func(ctx *gin.Context) {
+			ctx.Status(http.StatusOK)
+		})
+
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/products/", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "1", rr.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, request)
+		response, _ := json.Marshal(gin.H{"message": "rate limit exceeded, retry in 1 seconds"})
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+		assert.Equal(t, response, rr.Body.Bytes())
+	})
+
+	t.Run("RateLimit - 429 Too Many Requests before checking invalid keys", func(t *testing.T) {
+		keyServiceMock := new(usecase.KeyServiceMock)
+		keyServiceMock.On("Authenticate", "invalid-key").Return(nil, usecase.ErrInvalidAPIKey)
+		router := gin.Default()
+		router.GET("/products/", RateLimit(ratelimit.NewMemoryLimiter(), "client", ratelimit.PerMinute(60, 2)), Authenticate(keyServiceMock, nil), func(ctx *gin.Context) {
+			ctx.Status(http.StatusOK)
+		})
+
+		codes := make([]int, 0, 4)
+		for i := 0; i < 4; i++ {
+			rr := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodGet, "/products/", nil)
+			request.Header.Set(APIKeyHeader, "invalid-key")
+			router.ServeHTTP(rr, request)
+			codes = append(codes, rr.Code)
+		}
+
+		assert.Equal(t, []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusTooManyRequests}, codes)
+		keyServiceMock.AssertNumberOfCalls(t, "Authenticate", 2)
+	})
+
+	t.Run("ListingRateLimit - batch gets take from the bulk limit", func(t *testing.T) {
+		router := gin.Default()
+		limits := RateLimits{Read: ratelimit.PerMinute(60, 2), Bulk: ratelimit.PerMinute(60, 1)}
+		router.GET("/products/", ListingRateLimit(ratelimit.NewMemoryLimiter(), limits), func(ctx *gin.Context) {
+			ctx.Status(http.StatusOK)
+		})
+		get := func(target string) int {
+			rr := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodGet, target, nil)
+			router.ServeHTTP(rr, request)
+			return rr.Code
+		}
+
+		assert.Equal(t, http.StatusOK, get("/products/?skus=FAL-1000000,FAL-1000001"))
+		assert.Equal(t, http.StatusTooManyRequests, get("/products/?skus=FAL-1000000"))
+		assert.Equal(t, http.StatusOK, get("/products/"))
+		assert.Equal(t, http.StatusOK, get("/products/"))
+		assert.Equal(t, http.StatusTooManyRequests, get("/products/"))
+	})
+}
+
+func TestNewRateLimits(t *testing.T) {
+	t.Run("should parse requests and burst", func(t *testing.T) {
+		limits, err := NewRateLimits("120", "30:5", "", "0")
+		assert.NoError(t, err)
+		assert.Equal(t, ratelimit.PerMinute(120, 120), limits.Read)
+		assert.Equal(t, ratelimit.PerMinute(30, 5), limits.Write)
+		assert.Equal(t, DefaultBulkRateLimit, limits.Bulk)
+		assert.True(t, limits.Client.IsUnlimited())
+	})
+
+	t.Run("should reject malformed limits", func(t *testing.T) {
+		_, err := NewRateLimits("many", "", "", "")
+		assert.EqualError(t, err, `invalid read rate limit "many": requests per minute must be a positive number`)
+	})
+}
@@ -0,0 +1,95 @@
+package application
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"github.com/yescorihuela/agrak/shared/logging"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	RequestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+// RequestLogger assigns each request an ID, reusing a valid X-Request-ID
+// sent by the client or a proxy, and echoes it in the response. A logger
+// carrying the ID is put in the request context for the layers below, see
+// logging.FromContext, and one line is written per request once it is done.
+func RequestLogger(logger *log.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		started := time.Now()
+		requestID := ctx.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		ctx.Header(RequestIDHeader, requestID)
+
+		fields := log.Fields{"request_id": requestID}
+		if spanContext := trace.SpanContextFromContext(ctx.Request.Context()); spanContext.IsValid() {
+			fields["trace_id"] = spanContext.TraceID().String()
+		}
+		requestLogger := logger.WithFields(fields)
+		ctx.Request = ctx.Request.WithContext(logging.WithLogger(ctx.Request.Context(), requestLogger))
+
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := ctx.Writer.Status()
+		entry := requestLogger.WithFields(log.Fields{
+			"method":     ctx.Request.Method,
+			"route":      route,
+			"path":       ctx.Request.URL.Path,
+			"status":     status,
+			"latency_ms": float64(time.Since(started).Microseconds()) / 1000,
+			"client":     ctx.ClientIP(),
+			"bytes":      ctx.Writer.Size(),
+		})
+		if sku := ctx.Param("sku"); sku != "" {
+			entry = entry.WithField("sku", sku)
+		}
+		if len(ctx.Errors) > 0 {
+			entry = entry.WithField("errors", ctx.Errors.String())
+		}
+
+		switch {
+		case status >= http.StatusInternalServerError:
+			entry.Errorln("request failed")
+		case status >= http.StatusBadRequest:
+			entry.Warnln("request rejected")
+		default:
+			entry.Infoln("request completed")
+		}
+	}
+}
+
+// validRequestID accepts short printable ASCII IDs, so a client cannot
+// inject newlines or oversized values into the logs.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	buffer := make([]byte, 16)
+	if _, err := rand.Read(buffer); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(buffer)
+}
@@ -0,0 +1,62 @@
+package application
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/yescorihuela/agrak/shared/logging"
+)
+
+func TestRequestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, hook := test.NewNullLogger()
+	router := gin.New()
+	router.Use(RequestLogger(logger))
+	router.GET("/api/v1/products/:sku", func(ctx *gin.Context) {
+		logging.FromContext(ctx.Request.Context()).Infoln("looking up product")
+		ctx.Status(http.StatusNotFound)
+	})
+
+	t.Run("should propagate the request id", func(t *testing.T) {
+		hook.Reset()
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/api/v1/products/FAL-1000000", nil)
+		request.Header.Set(RequestIDHeader, "checkout-42")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, "checkout-42", rr.Header().Get(RequestIDHeader))
+		entries := hook.AllEntries()
+		assert.Len(t, entries, 2)
+		assert.Equal(t, "checkout-42", entries[0].Data["request_id"])
+
+		requestLine := entries[1]
+		assert.Equal(t, log.WarnLevel, requestLine.Level)
+		assert.Equal(t, "checkout-42", requestLine.Data["request_id"])
+		assert.Equal(t, http.MethodGet, requestLine.Data["method"])
+		assert.Equal(t, "/api/v1/products/:sku", requestLine.Data["route"])
+		assert.Equal(t, http.StatusNotFound, requestLine.Data["status"])
+		assert.Equal(t, "FAL-1000000", requestLine.Data["sku"])
+		assert.Contains(t, requestLine.Data, "latency_ms")
+		assert.Contains(t, requestLine.Data, "client")
+	})
+
+	t.Run("should assign a request id when missing or invalid", func(t *testing.T) {
+		for _, requestID := range []string{"", "forged\nline"} {
+			hook.Reset()
+			rr := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodGet, "/unknown", nil)
+			request.Header.Set(RequestIDHeader, requestID)
+			router.ServeHTTP(rr, request)
+
+			assigned := rr.Header().Get(RequestIDHeader)
+			assert.Len(t, assigned, 32)
+			assert.Equal(t, assigned, hook.LastEntry().Data["request_id"])
+			assert.Equal(t, unmatchedRoute, hook.LastEntry().Data["route"])
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package application
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/infrastructure/events"
+	"github.com/yescorihuela/agrak/infrastructure/response"
+)
+
+const (
+	// EventReset tells a resuming client that events were missed, so it
+	// should reload the products it shows.
+	EventReset = "reset"
+
+	DefaultHeartbeatInterval = 15 * time.Second
+)
+
+type StreamHandlers struct {
+	broadcaster  *events.Broadcaster
+	heartbeat    time.Duration
+	writeTimeout time.Duration
+}
+
+// NewStreamHandlers streams the events of broadcaster, sending a heartbeat
+// comment every heartbeat. Each write must complete within writeTimeout,
+// which replaces the server write timeout on streaming connections.
+func NewStreamHandlers(broadcaster *events.Broadcaster, heartbeat, writeTimeout time.Duration) *StreamHandlers {
+	if heartbeat <= 0 {
+		heartbeat = DefaultHeartbeatInterval
+	}
+	return &StreamHandlers{
+		broadcaster:  broadcaster,
+		heartbeat:    heartbeat,
+		writeTimeout: writeTimeout,
+	}
+}
+
+// StreamProducts godoc
+// @Summary Stream product changes
+// @Description stream product events as Server-Sent Events, named after the event type with the event id as SSE id. Reconnecting clients send Last-Event-ID to resume from the recent events kept in memory; a "reset" event means events were missed. Comments are sent as heartbeats.
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @param brand query string false "Only events of products of this brand"
+// @param sku_prefix query string false "Only events of SKUs starting with this prefix"
+// @param Last-Event-ID header string false "Id of the last event received"
+// @Success 200 {object} response.DTOEvent
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Router /api/v1/products/stream [get]
+func (sh *StreamHandlers) StreamProducts(ctx *gin.Context) {
+	requestCtx := ctx.Request.Context()
+	subscription := sh.broadcaster.Subscribe(ctx.GetHeader("Last-Event-ID"), events.Filter{
+		Brand:     ctx.Query("brand"),
+		SkuPrefix: ctx.Query("sku_prefix"),
+		Allowed: func(event entity.ProductEvent) bool {
+			return visibleEvent(requestCtx, event)
+		},
+	})
+	defer sh.broadcaster.Unsubscribe(subscription)
+
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	extendWriteDeadline(ctx.Request.Context(), sh.writeTimeout)
+	ctx.Writer.Flush()
+
+	if !subscription.Resumed {
+		if !sh.send(ctx, func(w io.Writer) error {
+			return sse.Encode(w, sse.Event{Event: EventReset, Data: "events after Last-Event-ID are no longer available"})
+		}) {
+			return
+		}
+	}
+	for _, event := range subscription.Backlog {
+		if !sh.send(ctx, encodeEvent(event)) {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(sh.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case event, ok := <-subscription.Events:
+			// Closed when the client fell behind or the server shuts down.
+			if !ok || !sh.send(ctx, encodeEvent(event)) {
+				return
+			}
+		case <-heartbeat.C:
+			if !sh.send(ctx, func(w io.Writer) error {
+				_, err := io.WriteString(w, ": heartbeat\n\n")
+				return err
+			}) {
+				return
+			}
+		}
+	}
+}
+
+// send writes to the client and flushes, reporting whether it succeeded.
+func (sh *StreamHandlers) send(ctx *gin.Context, write func(w io.Writer) error) bool {
+	extendWriteDeadline(ctx.Request.Context(), sh.writeTimeout)
+	if err := write(ctx.Writer); err != nil {
+		return false
+	}
+	ctx.Writer.Flush()
+	return ctx.Request.Context().Err() == nil
+}
+
+func encodeEvent(event entity.ProductEvent) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		return sse.Encode(w, sse.Event{
+			Id:    event.ID,
+			Event: string(event.Type),
+			Data:  response.ConvertFromEventToResponse(event),
+		})
+	}
+}
+
+type connContextKey struct{}
+
+// withConn keeps the connection of a request in its context, see
+// extendWriteDeadline. It is the http.Server ConnContext.
+func withConn(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connContextKey{}, conn)
+}
+
+// extendWriteDeadline gives the next writes on the connection of a
+// long-lived response timeout to complete, instead of the server write
+// timeout counted from the start of the request.
+func extendWriteDeadline(ctx context.Context, timeout time.Duration) {
+	conn, ok := ctx.Value(connContextKey{}).(net.Conn)
+	if !ok || timeout <= 0 {
+		return
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(timeout))
+}
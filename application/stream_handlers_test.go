@@ -0,0 +1,143 @@
+package application
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/infrastructure/events"
+)
+
+func TestStreamProducts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	event := func(id, sku, brand string) entity.ProductEvent {
+		return entity.ProductEvent{ID: id, Type: entity.EventProductCreated, Sku: sku, Product: &entity.Product{Sku: sku, Brand: brand, Status: entity.StatusActive}}
+	}
+
+	newStream := func(broadcaster *events.Broadcaster, heartbeat time.Duration) *httptest.Server {
+		engine := gin.New()
+		engine.GET("/api/v1/products/stream", NewStreamHandlers(broadcaster, heartbeat, time.Second).StreamProducts)
+		server := httptest.NewUnstartedServer(engine)
+		server.Config.ConnContext = withConn
+		server.Config.WriteTimeout = 50 * time.Millisecond
+		server.Start()
+		return server
+	}
+	open := func(t *testing.T, server *httptest.Server, query, lastEventID string) (*http.Response, *bufio.Reader) {
+		request, _ := http.NewRequest(http.MethodGet, server.URL+"/api/v1/products/stream"+query, nil)
+		if lastEventID != "" {
+			request.Header.Set("Last-Event-ID", lastEventID)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return response, bufio.NewReader(response.Body)
+	}
+	// readEvent returns the next message, without its trailing blank line.
+	readEvent := func(t *testing.T, reader *bufio.Reader) string {
+		lines := make([]string, 0)
+		for {
+			line, err := reader.ReadString('\n')
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	t.Run("should stream matching visible events past the server write timeout", func(t *testing.T) {
+		broadcaster := events.NewBroadcaster(10)
+		server := newStream(broadcaster, time.Minute)
+		defer server.Close()
+
+		response, reader := open(t, server, "?brand=Nike", "")
+		defer response.Body.Close()
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+		time.Sleep(100 * time.Millisecond)
+		draft := event("draft", "FAL-3000000", "Nike")
+		draft.Product.Status = entity.StatusDraft
+		_ = broadcaster.Publish(ctx, event("a", "FAL-1000000", "Adidas"))
+		_ = broadcaster.Publish(ctx, draft)
+		_ = broadcaster.Publish(ctx, event("b", "FAL-2000000", "Nike"))
+
+		message := readEvent(t, reader)
+		assert.Contains(t, message, "id:b\n")
+		assert.Contains(t, message, "event:product.created\n")
+		assert.Contains(t, message, `"sku":"FAL-2000000"`)
+	})
+
+	t.Run("should resume after the last event ID", func(t *testing.T) {
+		broadcaster := events.NewBroadcaster(10)
+		for _, id := range []string{"a", "b", "c"} {
+			_ = broadcaster.Publish(ctx, event(id, "FAL-1000000", "Nike"))
+		}
+		server := newStream(broadcaster, time.Minute)
+		defer server.Close()
+
+		response, reader := open(t, server, "", "a")
+		defer response.Body.Close()
+
+		assert.Contains(t, readEvent(t, reader), "id:b\n")
+		assert.Contains(t, readEvent(t, reader), "id:c\n")
+	})
+
+	t.Run("should send a reset when events were missed", func(t *testing.T) {
+		server := newStream(events.NewBroadcaster(10), time.Minute)
+		defer server.Close()
+
+		response, reader := open(t, server, "", "evicted")
+		defer response.Body.Close()
+
+		assert.Contains(t, readEvent(t, reader), "event:"+EventReset+"\n")
+	})
+
+	t.Run("should send heartbeats", func(t *testing.T) {
+		server := newStream(events.NewBroadcaster(10), 10*time.Millisecond)
+		defer server.Close()
+
+		response, reader := open(t, server, "", "")
+		defer response.Body.Close()
+
+		assert.Equal(t, ": heartbeat\n", readEvent(t, reader))
+	})
+
+	t.Run("should unsubscribe when the client disconnects", func(t *testing.T) {
+		broadcaster := events.NewBroadcaster(10)
+		server := newStream(broadcaster, time.Minute)
+		defer server.Close()
+
+		response, _ := open(t, server, "", "")
+		assert.Eventually(t, func() bool { return broadcaster.Subscribers() == 1 }, time.Second, 10*time.Millisecond)
+		response.Body.Close()
+
+		assert.Eventually(t, func() bool { return broadcaster.Subscribers() == 0 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should end streams when the broadcaster closes", func(t *testing.T) {
+		broadcaster := events.NewBroadcaster(10)
+		server := newStream(broadcaster, time.Minute)
+		defer server.Close()
+
+		response, reader := open(t, server, "", "")
+		defer response.Body.Close()
+		assert.Eventually(t, func() bool { return broadcaster.Subscribers() == 1 }, time.Second, 10*time.Millisecond)
+		broadcaster.Close()
+
+		_, err := reader.ReadString('\n')
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,225 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/response"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+type createWebhookRequest struct {
+	URL        string   `json:"url" binding:"required"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+}
+
+type WebhookHandlers struct {
+	service usecase.WebhookService
+}
+
+func NewWebhookHandlers(service usecase.WebhookService) *WebhookHandlers {
+	return &WebhookHandlers{
+		service: service,
+	}
+}
+
+// CreateWebhook godoc
+// @Summary Subscribe a webhook
+// @Description subscribe an URL to product events (all of them when event_types is empty); a secret is generated when none is given and only returned in this response
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @param webhook body createWebhookRequest true "Receiver URL, signing secret and event types"
+// @Success 201 {object} response.DTOCreatedWebhook
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Router /api/v1/webhooks [post]
+func (wh *WebhookHandlers) CreateWebhook(ctx *gin.Context) {
+	request := createWebhookRequest{}
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(err.Error()))
+		return
+	}
+	eventTypes := make([]entity.EventType, 0, len(request.EventTypes))
+	for _, eventType := range request.EventTypes {
+		eventTypes = append(eventTypes, entity.EventType(eventType))
+	}
+	subscription, err := wh.service.CreateSubscription(ctx.Request.Context(), request.URL, request.Secret, eventTypes)
+	if abortOnContextError(ctx, err) {
+		return
+	}
+	if errors.Is(err, usecase.ErrInvalidWebhookURL) || errors.Is(err, usecase.ErrInvalidEventType) {
+		ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(err.Error()))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, response.NewErrorResponse(err.Error()))
+		return
+	}
+	ctx.JSON(http.StatusCreated, response.DTOCreatedWebhook{
+		DTOWebhook: *response.ConvertFromWebhookToResponse(*subscription),
+		Secret:     subscription.Secret,
+	})
+}
+
+// ListWebhooks godoc
+// @Summary List webhooks
+// @Description list webhook subscriptions, without their secret
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {array} response.DTOWebhook
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /api/v1/webhooks [get]
+func (wh *WebhookHandlers) ListWebhooks(ctx *gin.Context) {
+	subscriptions, err := wh.service.ListSubscriptions(ctx.Request.Context())
+	if abortOnContextError(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, response.NewErrorResponse(err.Error()))
+		return
+	}
+	responseJSON := make([]response.DTOWebhook, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		responseJSON = append(responseJSON, *response.ConvertFromWebhookToResponse(subscription))
+	}
+	ctx.JSON(http.StatusOK, responseJSON)
+}
+
+// GetWebhook godoc
+// @Summary Get a webhook
+// @Description get a webhook subscription by id, without its secret
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @param id path string true "Webhook id"
+// @Success 200 {object} response.DTOWebhook
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /api/v1/webhooks/{id} [get]
+func (wh *WebhookHandlers) GetWebhook(ctx *gin.Context) {
+	subscription, err := wh.service.GetSubscription(ctx.Request.Context(), ctx.Param("id"))
+	if abortOnContextError(ctx, err) {
+		return
+	}
+	if errors.Is(err, repository.ErrWebhookNotFound) {
+		ctx.JSON(http.StatusNotFound, response.NewErrorResponse(err.Error()))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, response.NewErrorResponse(err.Error()))
+		return
+	}
+	ctx.JSON(http.StatusOK, response.ConvertFromWebhookToResponse(*subscription))
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description delete a webhook subscription and its deliveries
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @param id path string true "Webhook id"
+// @Success 204 {object} nil
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /api/v1/webhooks/{id} [delete]
+func (wh *WebhookHandlers) DeleteWebhook(ctx *gin.Context) {
+	err := wh.service.DeleteSubscription(ctx.Request.Context(), ctx.Param("id"))
+	if abortOnContextError(ctx, err) {
+		return
+	}
+	if errors.Is(err, repository.ErrWebhookNotFound) {
+		ctx.JSON(http.StatusNotFound, response.NewErrorResponse(err.Error()))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, response.NewErrorResponse(err.Error()))
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// ListDeliveries godoc
+// @Summary List webhook deliveries
+// @Description list the latest 100 deliveries of a webhook, newest first
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @param id path string true "Webhook id"
+// @param status query string false "Delivery status (pending, succeeded or dead)"
+// @Success 200 {array} response.DTOWebhookDelivery
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 422 {object} response.ErrorResponse
+// @Router /api/v1/webhooks/{id}/deliveries [get]
+func (wh *WebhookHandlers) ListDeliveries(ctx *gin.Context) {
+	deliveries, err := wh.service.ListDeliveries(ctx.Request.Context(), ctx.Param("id"), entity.DeliveryStatus(ctx.Query("status")))
+	if abortOnContextError(ctx, err) {
+		return
+	}
+	if errors.Is(err, usecase.ErrInvalidDeliveryStatus) {
+		ctx.JSON(http.StatusUnprocessableEntity, response.NewErrorResponse(err.Error()))
+		return
+	}
+	if errors.Is(err, repository.ErrWebhookNotFound) {
+		ctx.JSON(http.StatusNotFound, response.NewErrorResponse(err.Error()))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, response.NewErrorResponse(err.Error()))
+		return
+	}
+	responseJSON := make([]response.DTOWebhookDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		responseJSON = append(responseJSON, *response.ConvertFromDeliveryToResponse(delivery))
+	}
+	ctx.JSON(http.StatusOK, responseJSON)
+}
+
+// Redeliver godoc
+// @Summary Redeliver a webhook delivery
+// @Description send a delivery again as soon as possible, with a fresh retry budget, whatever its status
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @param id path string true "Webhook id"
+// @param delivery_id path string true "Delivery id"
+// @Success 202 {object} response.DTOWebhookDelivery
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /api/v1/webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (wh *WebhookHandlers) Redeliver(ctx *gin.Context) {
+	delivery, err := wh.service.Redeliver(ctx.Request.Context(), ctx.Param("id"), ctx.Param("delivery_id"))
+	if abortOnContextError(ctx, err) {
+		return
+	}
+	if errors.Is(err, repository.ErrDeliveryNotFound) {
+		ctx.JSON(http.StatusNotFound, response.NewErrorResponse(err.Error()))
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, response.NewErrorResponse(err.Error()))
+		return
+	}
+	ctx.JSON(http.StatusAccepted, response.ConvertFromDeliveryToResponse(*delivery))
+}
@@ -0,0 +1,42 @@
+package entity
+
+import "time"
+
+type Role string
+
+const (
+	RoleReader Role = "reader"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleReader: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+func (r Role) IsValid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Includes reports whether r grants at least the permissions of required,
+// e.g. an admin may do everything an editor or a reader may do.
+func (r Role) Includes(required Role) bool {
+	return r.IsValid() && roleRanks[r] >= roleRanks[required]
+}
+
+type APIKey struct {
+	ID        string
+	Name      string
+	Prefix    string
+	Hash      string
+	Role      Role
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
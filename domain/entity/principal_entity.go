@@ -0,0 +1,43 @@
+package entity
+
+type AuthMethod string
+
+const (
+	AuthMethodAPIKey AuthMethod = "api_key"
+	AuthMethodJWT    AuthMethod = "jwt"
+)
+
+const (
+	ScopeProductsRead   = "products:read"
+	ScopeProductsWrite  = "products:write"
+	ScopeProductsDelete = "products:delete"
+	ScopeAdmin          = "admin"
+)
+
+// Principal is the authenticated caller of a request. API keys are
+// authorized by role and bearer tokens by scope.
+type Principal struct {
+	Subject string
+	Method  AuthMethod
+	Role    Role
+	Scopes  []string
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Principal) Allows(role Role, scope string) bool {
+	switch p.Method {
+	case AuthMethodAPIKey:
+		return p.Role.Includes(role)
+	case AuthMethodJWT:
+		return p.HasScope(scope)
+	}
+	return false
+}
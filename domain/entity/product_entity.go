@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -16,6 +17,33 @@ const (
 	SkuPrefix = "FAL"
 )
 
+// ProductStatus is the stage of a product in its lifecycle. Products are
+// created as drafts and only active ones are listed publicly.
+type ProductStatus string
+
+const (
+	StatusDraft        ProductStatus = "draft"
+	StatusActive       ProductStatus = "active"
+	StatusDiscontinued ProductStatus = "discontinued"
+)
+
+var ProductStatuses = []ProductStatus{StatusDraft, StatusActive, StatusDiscontinued}
+
+func (s ProductStatus) IsValid() bool {
+	for _, status := range ProductStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrInvalidTransition   = errors.New("invalid status transition")
+	ErrTransitionForbidden = errors.New("only an admin can reactivate a discontinued product")
+	ErrNotPublishable      = errors.New("product cannot be published")
+)
+
 type Product struct {
 	Sku            string
 	Name           string
@@ -24,6 +52,45 @@ type Product struct {
 	Price          float64
 	PrincipalImage string
 	OtherImages    []string
+	Status         ProductStatus
+	AvailableFrom  *time.Time
+	AvailableUntil *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// TransitionTo moves p to status on behalf of principal. Drafts can be
+// published, active products discontinued, and discontinued products
+// reactivated by an admin. A product only becomes active when it passes
+// IsValid.
+func (p *Product) TransitionTo(status ProductStatus, principal *Principal) error {
+	switch {
+	case p.Status == StatusDraft && status == StatusActive:
+	case p.Status == StatusActive && status == StatusDiscontinued:
+	case p.Status == StatusDiscontinued && status == StatusActive:
+		if principal == nil || !principal.Allows(RoleAdmin, ScopeAdmin) {
+			return ErrTransitionForbidden
+		}
+	default:
+		return fmt.Errorf("%w from %q to %q", ErrInvalidTransition, p.Status, status)
+	}
+	if status == StatusActive {
+		if _, err := p.IsValid(); err != nil {
+			return fmt.Errorf("%w: %s", ErrNotPublishable, err)
+		}
+	}
+	p.Status = status
+	return nil
+}
+
+// IsAvailableAt tells whether t falls in the availability window of p. The
+// window opens at AvailableFrom and closes at AvailableUntil; a missing bound
+// leaves that side open.
+func (p *Product) IsAvailableAt(t time.Time) bool {
+	if p.AvailableFrom != nil && t.Before(*p.AvailableFrom) {
+		return false
+	}
+	return p.AvailableUntil == nil || t.Before(*p.AvailableUntil)
 }
 
 func (p *Product) IsValid() (bool, error) {
@@ -51,6 +118,10 @@ func (p *Product) IsValid() (bool, error) {
 		return false, errors.New("invalid URL format for principal image")
 	}
 
+	if p.AvailableFrom != nil && p.AvailableUntil != nil && !p.AvailableUntil.After(*p.AvailableFrom) {
+		return false, errors.New("available until must be after available from")
+	}
+
 	if len(p.OtherImages) > 0 {
 		for _, url := range p.OtherImages {
 			if !IsValidUrl(url) {
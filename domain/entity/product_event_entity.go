@@ -0,0 +1,104 @@
+package entity
+
+import (
+	"reflect"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventProductCreated EventType = "product.created"
+	EventProductUpdated EventType = "product.updated"
+	EventProductDeleted EventType = "product.deleted"
+	// EventProductAvailable and EventProductUnavailable are emitted when the
+	// availability window of a product opens and closes.
+	EventProductAvailable   EventType = "product.available"
+	EventProductUnavailable EventType = "product.unavailable"
+)
+
+var EventTypes = []EventType{
+	EventProductCreated,
+	EventProductUpdated,
+	EventProductDeleted,
+	EventProductAvailable,
+	EventProductUnavailable,
+}
+
+func (t EventType) IsValid() bool {
+	for _, eventType := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// ProductEvent records a change of the catalog. Product is the state after
+// the change, or the last state for deletions. PreviousSku is set when an
+// update renamed the product, and ChangedFields lists the updated fields by
+// their API name.
+type ProductEvent struct {
+	ID            string
+	Type          EventType
+	Sku           string
+	PreviousSku   string
+	ChangedFields []string
+	Product       *Product
+	OccurredAt    time.Time
+}
+
+// IsPublic tells whether e may be told to anybody but admins: it is about a
+// product that was active and available when it occurred. Closing windows
+// are public for every active product, as it could be seen until then.
+func (e ProductEvent) IsPublic() bool {
+	if e.Product == nil || e.Product.Status != StatusActive {
+		return false
+	}
+	return e.Type == EventProductUnavailable || e.Product.IsAvailableAt(e.OccurredAt)
+}
+
+// OutboxEntry is an event of the outbox with its delivery bookkeeping.
+// DispatchedAt is nil until the event is dispatched.
+type OutboxEntry struct {
+	Event        ProductEvent
+	Attempts     int
+	LastError    string
+	DispatchedAt *time.Time
+}
+
+// ChangedFields returns the API names of the fields that differ between
+// before and after. Timestamps are not compared.
+func ChangedFields(before, after Product) []string {
+	changed := make([]string, 0)
+	compare := func(field string, a, b interface{}) {
+		if !reflect.DeepEqual(a, b) {
+			changed = append(changed, field)
+		}
+	}
+	compare("sku", before.Sku, after.Sku)
+	compare("name", before.Name, after.Name)
+	compare("brand", before.Brand, after.Brand)
+	compare("size", before.Size, after.Size)
+	compare("price", before.Price, after.Price)
+	compare("principal_image", before.PrincipalImage, after.PrincipalImage)
+	if len(before.OtherImages) != 0 || len(after.OtherImages) != 0 {
+		compare("other_images", before.OtherImages, after.OtherImages)
+	}
+	compare("status", before.Status, after.Status)
+	if !sameTime(before.AvailableFrom, after.AvailableFrom) {
+		changed = append(changed, "available_from")
+	}
+	if !sameTime(before.AvailableUntil, after.AvailableUntil) {
+		changed = append(changed, "available_until")
+	}
+	return changed
+}
+
+// sameTime compares optional instants regardless of their location.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+type APIKeyRepository interface {
+	Save(ctx context.Context, key entity.APIKey) error
+	GetByHash(ctx context.Context, hash string) (*entity.APIKey, error)
+	GetAll(ctx context.Context) ([]entity.APIKey, error)
+	Revoke(ctx context.Context, id string, revokedAt time.Time) error
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+)
+
+var ErrOutboxEventNotFound = errors.New("outbox event not found")
+
+// OutboxRepository stores product events until they are dispatched. Append
+// is used inside a Transaction, so events are only recorded when the change
+// they describe is committed.
+type OutboxRepository interface {
+	Append(ctx context.Context, events ...entity.ProductEvent) error
+	// Claim returns up to limit events due at now, oldest first, and hides
+	// them from other claims until now plus lease.
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entity.OutboxEntry, error)
+	MarkDispatched(ctx context.Context, id string, dispatchedAt time.Time) error
+	// MarkFailed counts a failed attempt and schedules the next one.
+	MarkFailed(ctx context.Context, id string, nextAttemptAt time.Time, reason string) error
+	// ListDispatched returns up to limit events dispatched after the event
+	// afterID dispatched at since, ordered by dispatch time and then ID,
+	// whichever relay did it. An empty afterID includes every event
+	// dispatched at since.
+	ListDispatched(ctx context.Context, since time.Time, afterID string, limit int) ([]entity.OutboxEntry, error)
+}
@@ -1,11 +1,72 @@
 package repository
 
-import "github.com/yescorihuela/agrak/domain/entity"
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+)
+
+var (
+	ErrProductNotFound   = errors.New("record not found")
+	ErrDuplicatedProduct = errors.New("duplicated sku")
+	ErrSkuAliasNotFound  = errors.New("sku alias not found")
+)
+
+// ProductFilter narrows a page of products. Empty fields match every
+// product; Brand is compared case-insensitively. AvailableAt keeps the
+// products whose availability window is open at that time.
+type ProductFilter struct {
+	Brand       string
+	SkuPrefix   string
+	Status      entity.ProductStatus
+	AvailableAt time.Time
+}
+
+func (f ProductFilter) Matches(product entity.Product) bool {
+	if f.Brand != "" && !strings.EqualFold(f.Brand, product.Brand) {
+		return false
+	}
+	if f.Status != "" && f.Status != product.Status {
+		return false
+	}
+	if !f.AvailableAt.IsZero() && !product.IsAvailableAt(f.AvailableAt) {
+		return false
+	}
+	return strings.HasPrefix(product.Sku, f.SkuPrefix)
+}
 
 type ProductRepository interface {
-	Save(p entity.Product) error
-	Update(oldSku string, product entity.Product) (*entity.Product, error)
-	GetBySku(sku string) (*entity.Product, error)
-	GetAllProducts() ([]entity.Product, error)
-	Delete(sku string) error
+	Save(ctx context.Context, p entity.Product) error
+	Update(ctx context.Context, oldSku string, product entity.Product) (*entity.Product, error)
+	GetBySku(ctx context.Context, sku string) (*entity.Product, error)
+	// GetBySkus returns the products stored under skus, ordered by SKU, in a
+	// single lookup. Unknown SKUs are left out rather than reported.
+	GetBySkus(ctx context.Context, skus []string) ([]entity.Product, error)
+	GetAllProducts(ctx context.Context) ([]entity.Product, error)
+	// GetPage returns up to limit products matching filter ordered by SKU,
+	// starting after afterSku. An empty page is not an error.
+	GetPage(ctx context.Context, filter ProductFilter, afterSku string, limit int) ([]entity.Product, error)
+	// Rename moves the product stored under sku to newSku and keeps sku as
+	// an alias of it. Aliases of sku follow the product to newSku, and an
+	// alias newSku was is dropped.
+	Rename(ctx context.Context, sku string, newSku string) (*entity.Product, error)
+	// GetAliasTarget returns the SKU of the product sku is a former SKU of.
+	GetAliasTarget(ctx context.Context, sku string) (string, error)
+	// Delete removes the product stored under sku and its aliases.
+	Delete(ctx context.Context, sku string) error
+	// GetAvailabilityChanges returns the products, ordered by SKU, whose
+	// availability at t differs from the one last announced for them. Inside
+	// a transaction the products are locked, and the ones locked by another
+	// transaction are skipped.
+	GetAvailabilityChanges(ctx context.Context, t time.Time) ([]entity.Product, error)
+	// SetAnnouncedAvailability records the availability last announced for
+	// the product stored under sku. Products are saved as announced with
+	// their availability at the time.
+	SetAnnouncedAvailability(ctx context.Context, sku string, available bool) error
+	// NextAvailabilityChange returns the earliest time after t at which an
+	// availability window opens or closes, or nil when none is scheduled.
+	NextAvailabilityChange(ctx context.Context, t time.Time) (*time.Time, error)
 }
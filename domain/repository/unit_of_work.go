@@ -0,0 +1,17 @@
+package repository
+
+import "context"
+
+// Transaction gives access to repositories whose changes are committed or
+// discarded together.
+type Transaction interface {
+	Products() ProductRepository
+	Outbox() OutboxRepository
+}
+
+// UnitOfWork runs fn in a transaction. Changes made through tx are committed
+// when fn returns nil and discarded otherwise. fn must not keep tx after it
+// returns.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, tx Transaction) error) error
+}
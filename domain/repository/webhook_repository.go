@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+type WebhookRepository interface {
+	Save(ctx context.Context, subscription entity.WebhookSubscription) error
+	GetByID(ctx context.Context, id string) (*entity.WebhookSubscription, error)
+	GetAll(ctx context.Context) ([]entity.WebhookSubscription, error)
+	// Delete removes the subscription and its deliveries.
+	Delete(ctx context.Context, id string) error
+}
+
+type WebhookDeliveryRepository interface {
+	// Enqueue stores new deliveries and ignores the ones already stored
+	// with the same ID, so an event published twice is delivered once.
+	Enqueue(ctx context.Context, deliveries ...entity.WebhookDelivery) error
+	// Claim returns up to limit pending deliveries due at now, oldest
+	// first, and hides them from other claims until now plus lease.
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entity.WebhookDelivery, error)
+	// Record stores the status and bookkeeping fields of delivery.
+	Record(ctx context.Context, delivery entity.WebhookDelivery) error
+	GetByID(ctx context.Context, subscriptionID, id string) (*entity.WebhookDelivery, error)
+	// GetBySubscription returns the latest deliveries of a subscription,
+	// newest first, optionally filtered by status.
+	GetBySubscription(ctx context.Context, subscriptionID string, status entity.DeliveryStatus, limit int) ([]entity.WebhookDelivery, error)
+}
@@ -0,0 +1,55 @@
+package cache
+
+import "time"
+
+var (
+	DefaultCapacity    = 1024
+	DefaultTTL         = 5 * time.Minute
+	DefaultNegativeTTL = 30 * time.Second
+)
+
+type CacheOptions struct {
+	capacity    *int
+	ttl         *time.Duration
+	negativeTTL *time.Duration
+}
+
+func Config() *CacheOptions {
+	return &CacheOptions{}
+}
+
+func (c *CacheOptions) Capacity(capacity int) *CacheOptions {
+	c.capacity = &capacity
+	return c
+}
+
+func (c *CacheOptions) TTL(ttl time.Duration) *CacheOptions {
+	c.ttl = &ttl
+	return c
+}
+
+// NegativeTTL sets how long a missing SKU is remembered as missing.
+func (c *CacheOptions) NegativeTTL(ttl time.Duration) *CacheOptions {
+	c.negativeTTL = &ttl
+	return c
+}
+
+func MergeOptions(opts ...*CacheOptions) *CacheOptions {
+	option := &CacheOptions{
+		capacity:    &DefaultCapacity,
+		ttl:         &DefaultTTL,
+		negativeTTL: &DefaultNegativeTTL,
+	}
+	for _, opt := range opts {
+		if opt.capacity != nil && *opt.capacity > 0 {
+			option.capacity = opt.capacity
+		}
+		if opt.ttl != nil {
+			option.ttl = opt.ttl
+		}
+		if opt.negativeTTL != nil {
+			option.negativeTTL = opt.negativeTTL
+		}
+	}
+	return option
+}
@@ -0,0 +1,229 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"golang.org/x/sync/singleflight"
+)
+
+// missingProduct marks a SKU the underlying repository reported as not found.
+type missingProduct struct{}
+
+type CacheStats struct {
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Entries int    `json:"entries"`
+}
+
+// CachedProductRepository is a read-through decorator for any
+// repository.ProductRepository. Lookups by SKU are served from an in-process
+// LRU, writes go straight to the wrapped repository and invalidate the
+// affected SKUs.
+type CachedProductRepository struct {
+	repository  repository.ProductRepository
+	entries     *LRU
+	group       singleflight.Group
+	ttl         time.Duration
+	negativeTTL time.Duration
+	// mutex makes checking the generation and storing an entry atomic with
+	// invalidations, which bump the generation and delete entries under it.
+	mutex      sync.Mutex
+	generation uint64
+	hits       uint64
+	misses     uint64
+	// storing, when set, is called between the generation check and the
+	// store of an entry.
+	storing func(sku string)
+}
+
+func NewCachedProductRepository(repository repository.ProductRepository, opts ...*CacheOptions) *CachedProductRepository {
+	cacheOptions := MergeOptions(opts...)
+	return &CachedProductRepository{
+		repository:  repository,
+		entries:     NewLRU(*cacheOptions.capacity),
+		ttl:         *cacheOptions.ttl,
+		negativeTTL: *cacheOptions.negativeTTL,
+	}
+}
+
+func (c *CachedProductRepository) Save(ctx context.Context, product entity.Product) error {
+	err := c.repository.Save(ctx, product)
+	c.invalidate(product.Sku)
+	return err
+}
+
+func (c *CachedProductRepository) Update(ctx context.Context, oldSku string, product entity.Product) (*entity.Product, error) {
+	updatedProduct, err := c.repository.Update(ctx, oldSku, product)
+	c.invalidate(oldSku, product.Sku)
+	return updatedProduct, err
+}
+
+func (c *CachedProductRepository) GetBySku(ctx context.Context, sku string) (*entity.Product, error) {
+	if value, ok := c.entries.Get(sku); ok {
+		atomic.AddUint64(&c.hits, 1)
+		if _, missing := value.(missingProduct); missing {
+			return nil, repository.ErrProductNotFound
+		}
+		return cloneProduct(value.(*entity.Product)), nil
+	}
+	atomic.AddUint64(&c.misses, 1)
+
+	// Concurrent misses on the same SKU share a single call to the wrapped
+	// repository. The generation guards against caching a value read before
+	// a write that invalidated it.
+	generation := atomic.LoadUint64(&c.generation)
+	value, err, _ := c.group.Do(sku, func() (interface{}, error) {
+		product, err := c.repository.GetBySku(ctx, sku)
+		if errors.Is(err, repository.ErrProductNotFound) {
+			c.store(generation, sku, missingProduct{}, c.negativeTTL)
+			return nil, err
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.store(generation, sku, cloneProduct(product), c.ttl)
+		return product, nil
+	})
+	if isContextError(err) && ctx.Err() == nil {
+		// The shared lookup ran with the context of another caller that
+		// gave up; this caller still has time to try on its own.
+		return c.repository.GetBySku(ctx, sku)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cloneProduct(value.(*entity.Product)), nil
+}
+
+func (c *CachedProductRepository) GetAllProducts(ctx context.Context) ([]entity.Product, error) {
+	return c.repository.GetAllProducts(ctx)
+}
+
+// GetBySkus serves the cached SKUs and fetches the rest with one call to the
+// wrapped repository, caching what it returns and what it leaves out.
+func (c *CachedProductRepository) GetBySkus(ctx context.Context, skus []string) ([]entity.Product, error) {
+	products := make([]entity.Product, 0, len(skus))
+	seen := make(map[string]bool, len(skus))
+	missed := make([]string, 0)
+	for _, sku := range skus {
+		if seen[sku] {
+			continue
+		}
+		seen[sku] = true
+		value, ok := c.entries.Get(sku)
+		if !ok {
+			missed = append(missed, sku)
+			continue
+		}
+		atomic.AddUint64(&c.hits, 1)
+		if product, found := value.(*entity.Product); found {
+			products = append(products, *cloneProduct(product))
+		}
+	}
+	if len(missed) > 0 {
+		atomic.AddUint64(&c.misses, uint64(len(missed)))
+		generation := atomic.LoadUint64(&c.generation)
+		fetched, err := c.repository.GetBySkus(ctx, missed)
+		if err != nil {
+			return nil, err
+		}
+		found := make(map[string]bool, len(fetched))
+		for i := range fetched {
+			found[fetched[i].Sku] = true
+			c.store(generation, fetched[i].Sku, cloneProduct(&fetched[i]), c.ttl)
+		}
+		for _, sku := range missed {
+			if !found[sku] {
+				c.store(generation, sku, missingProduct{}, c.negativeTTL)
+			}
+		}
+		products = append(products, fetched...)
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].Sku < products[j].Sku
+	})
+	return products, nil
+}
+
+func (c *CachedProductRepository) GetPage(ctx context.Context, filter repository.ProductFilter, afterSku string, limit int) ([]entity.Product, error) {
+	return c.repository.GetPage(ctx, filter, afterSku, limit)
+}
+
+func (c *CachedProductRepository) Rename(ctx context.Context, sku string, newSku string) (*entity.Product, error) {
+	renamedProduct, err := c.repository.Rename(ctx, sku, newSku)
+	c.invalidate(sku, newSku)
+	return renamedProduct, err
+}
+
+func (c *CachedProductRepository) GetAliasTarget(ctx context.Context, sku string) (string, error) {
+	return c.repository.GetAliasTarget(ctx, sku)
+}
+
+func (c *CachedProductRepository) Delete(ctx context.Context, sku string) error {
+	err := c.repository.Delete(ctx, sku)
+	c.invalidate(sku)
+	return err
+}
+
+// GetAvailabilityChanges, SetAnnouncedAvailability and NextAvailabilityChange
+// only deal with the announced availability, which is not cached.
+func (c *CachedProductRepository) GetAvailabilityChanges(ctx context.Context, t time.Time) ([]entity.Product, error) {
+	return c.repository.GetAvailabilityChanges(ctx, t)
+}
+
+func (c *CachedProductRepository) SetAnnouncedAvailability(ctx context.Context, sku string, available bool) error {
+	return c.repository.SetAnnouncedAvailability(ctx, sku, available)
+}
+
+func (c *CachedProductRepository) NextAvailabilityChange(ctx context.Context, t time.Time) (*time.Time, error) {
+	return c.repository.NextAvailabilityChange(ctx, t)
+}
+
+func (c *CachedProductRepository) Stats() CacheStats {
+	return CacheStats{
+		Hits:    atomic.LoadUint64(&c.hits),
+		Misses:  atomic.LoadUint64(&c.misses),
+		Entries: c.entries.Len(),
+	}
+}
+
+func (c *CachedProductRepository) store(generation uint64, sku string, value interface{}, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if ttl <= 0 || atomic.LoadUint64(&c.generation) != generation {
+		return
+	}
+	if c.storing != nil {
+		c.storing(sku)
+	}
+	c.entries.Set(sku, value, ttl)
+}
+
+func (c *CachedProductRepository) invalidate(skus ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	atomic.AddUint64(&c.generation, 1)
+	for _, sku := range skus {
+		c.group.Forget(sku)
+		c.entries.Delete(sku)
+	}
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func cloneProduct(product *entity.Product) *entity.Product {
+	clone := *product
+	if product.OtherImages != nil {
+		clone.OtherImages = append([]string(nil), product.OtherImages...)
+	}
+	return &clone
+}
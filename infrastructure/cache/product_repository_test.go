@@ -0,0 +1,222 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/product"
+)
+
+var ctx = context.Background()
+
+func newFakeProduct(sku string) *entity.Product {
+	return &entity.Product{
+		Sku:            sku,
+		Name:           "Polera",
+		Brand:          "CAT",
+		Size:           "XL",
+		Price:          20000.00,
+		PrincipalImage: "https://placehold.jp/3d4070/ffffff/150x150.png",
+		OtherImages:    []string{"https://placehold.jp/24/cccccc/ffffff/250x50.png"},
+	}
+}
+
+func TestCachedProductRepository_GetBySku(t *testing.T) {
+	t.Run("should serve repeated lookups from cache", func(t *testing.T) {
+		sku := "FAL-1000000"
+		repositoryMock := new(product.RepositoryMock)
+		repositoryMock.On("GetBySku", sku).Return(newFakeProduct(sku), nil).Once()
+
+		cachedRepository := NewCachedProductRepository(repositoryMock)
+		for i := 0; i < 3; i++ {
+			got, err := cachedRepository.GetBySku(ctx, sku)
+			assert.NoError(t, err)
+			assert.Equal(t, newFakeProduct(sku), got)
+		}
+
+		repositoryMock.AssertNumberOfCalls(t, "GetBySku", 1)
+		assert.Equal(t, CacheStats{Hits: 2, Misses: 1, Entries: 1}, cachedRepository.Stats())
+	})
+
+	t.Run("should cache misses", func(t *testing.T) {
+		sku := "FAL-9999999"
+		repositoryMock := new(product.RepositoryMock)
+		repositoryMock.On("GetBySku", sku).Return((*entity.Product)(nil), repository.ErrProductNotFound).Once()
+
+		cachedRepository := NewCachedProductRepository(repositoryMock)
+		for i := 0; i < 2; i++ {
+			got, err := cachedRepository.GetBySku(ctx, sku)
+			assert.Nil(t, got)
+			assert.ErrorIs(t, err, repository.ErrProductNotFound)
+		}
+		repositoryMock.AssertNumberOfCalls(t, "GetBySku", 1)
+	})
+
+	t.Run("should not cache repository failures", func(t *testing.T) {
+		sku := "FAL-1000000"
+		repositoryMock := new(product.RepositoryMock)
+		repositoryMock.On("GetBySku", sku).Return((*entity.Product)(nil), errors.New("connection refused"))
+
+		cachedRepository := NewCachedProductRepository(repositoryMock)
+		for i := 0; i < 2; i++ {
+			_, err := cachedRepository.GetBySku(ctx, sku)
+			assert.EqualError(t, err, "connection refused")
+		}
+		repositoryMock.AssertNumberOfCalls(t, "GetBySku", 2)
+	})
+
+	t.Run("should collapse concurrent misses into one lookup", func(t *testing.T) {
+		sku := "FAL-1000000"
+		repositoryMock := new(product.RepositoryMock)
+		repositoryMock.On("GetBySku", sku).After(50*time.Millisecond).Return(newFakeProduct(sku), nil)
+
+		cachedRepository := NewCachedProductRepository(repositoryMock)
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := cachedRepository.GetBySku(ctx, sku)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		repositoryMock.AssertNumberOfCalls(t, "GetBySku", 1)
+	})
+}
+
+func TestCachedProductRepository_GetBySkus(t *testing.T) {
+	repositoryMock := new(product.RepositoryMock)
+	repositoryMock.On("GetBySku", "FAL-1000000").Return(newFakeProduct("FAL-1000000"), nil).Once()
+	repositoryMock.On("GetBySkus", []string{"FAL-9999999", "FAL-1000001"}).
+		Return([]entity.Product{*newFakeProduct("FAL-1000001")}, nil).Once()
+
+	cachedRepository := NewCachedProductRepository(repositoryMock)
+	_, err := cachedRepository.GetBySku(ctx, "FAL-1000000")
+	assert.NoError(t, err)
+
+	// Only the SKUs missing from the cache reach the repository, and both
+	// what it found and what it did not are cached.
+	for i := 0; i < 2; i++ {
+		products, err := cachedRepository.GetBySkus(ctx, []string{"FAL-9999999", "FAL-1000001", "FAL-1000000", "FAL-1000001"})
+		assert.NoError(t, err)
+		assert.Equal(t, []entity.Product{*newFakeProduct("FAL-1000000"), *newFakeProduct("FAL-1000001")}, products)
+	}
+	repositoryMock.AssertExpectations(t)
+}
+
+func TestCachedProductRepository_Invalidation(t *testing.T) {
+	t.Run("should invalidate old and new sku on update", func(t *testing.T) {
+		oldSku, newSku := "FAL-1000000", "FAL-1000001"
+		updatedProduct := newFakeProduct(newSku)
+		repositoryMock := new(product.RepositoryMock)
+		repositoryMock.On("GetBySku", oldSku).Return(newFakeProduct(oldSku), nil).Once()
+		repositoryMock.On("GetBySku", newSku).Return((*entity.Product)(nil), repository.ErrProductNotFound).Once()
+		repositoryMock.On("Update", oldSku, *updatedProduct).Return(updatedProduct, nil)
+
+		cachedRepository := NewCachedProductRepository(repositoryMock)
+		_, _ = cachedRepository.GetBySku(ctx, oldSku)
+		_, _ = cachedRepository.GetBySku(ctx, newSku)
+		_, err := cachedRepository.Update(ctx, oldSku, *updatedProduct)
+		assert.NoError(t, err)
+
+		repositoryMock.On("GetBySku", oldSku).Return((*entity.Product)(nil), repository.ErrProductNotFound).Once()
+		repositoryMock.On("GetBySku", newSku).Return(updatedProduct, nil).Once()
+		_, err = cachedRepository.GetBySku(ctx, oldSku)
+		assert.ErrorIs(t, err, repository.ErrProductNotFound)
+		got, err := cachedRepository.GetBySku(ctx, newSku)
+		assert.NoError(t, err)
+		assert.Equal(t, updatedProduct, got)
+		repositoryMock.AssertNumberOfCalls(t, "GetBySku", 4)
+	})
+
+	t.Run("should invalidate sku on delete", func(t *testing.T) {
+		sku := "FAL-1000000"
+		repositoryMock := new(product.RepositoryMock)
+		repositoryMock.On("GetBySku", sku).Return(newFakeProduct(sku), nil).Once()
+		repositoryMock.On("Delete", sku).Return(nil)
+
+		cachedRepository := NewCachedProductRepository(repositoryMock)
+		_, _ = cachedRepository.GetBySku(ctx, sku)
+		assert.NoError(t, cachedRepository.Delete(ctx, sku))
+
+		repositoryMock.On("GetBySku", sku).Return((*entity.Product)(nil), repository.ErrProductNotFound).Once()
+		_, err := cachedRepository.GetBySku(ctx, sku)
+		assert.ErrorIs(t, err, repository.ErrProductNotFound)
+		repositoryMock.AssertNumberOfCalls(t, "GetBySku", 2)
+	})
+
+	t.Run("should not store a lookup a delete invalidated while storing it", func(t *testing.T) {
+		sku := "FAL-1000000"
+		repositoryMock := new(product.RepositoryMock)
+		repositoryMock.On("GetBySku", sku).Return(newFakeProduct(sku), nil).Once()
+		repositoryMock.On("Delete", sku).Return(nil)
+
+		cachedRepository := NewCachedProductRepository(repositoryMock)
+		deleted := make(chan struct{})
+		cachedRepository.storing = func(string) {
+			go func() {
+				assert.NoError(t, cachedRepository.Delete(ctx, sku))
+				close(deleted)
+			}()
+			// The delete must wait for the lookup to be stored, or it would
+			// be stored after the delete and served until it expired.
+			select {
+			case <-deleted:
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+		_, err := cachedRepository.GetBySku(ctx, sku)
+		assert.NoError(t, err)
+		<-deleted
+
+		_, cached := cachedRepository.entries.Get(sku)
+		assert.False(t, cached)
+	})
+}
+
+func TestLRU(t *testing.T) {
+	t.Run("should evict least recently used entry", func(t *testing.T) {
+		lru := NewLRU(2)
+		lru.Set("a", 1, time.Minute)
+		lru.Set("b", 2, time.Minute)
+		lru.Get("a")
+		lru.Set("c", 3, time.Minute)
+
+		_, ok := lru.Get("b")
+		assert.False(t, ok)
+		_, ok = lru.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 2, lru.Len())
+	})
+
+	t.Run("should expire entries after ttl", func(t *testing.T) {
+		now := time.Now()
+		lru := NewLRU(2)
+		lru.now = func() time.Time { return now }
+		lru.Set("a", 1, time.Second)
+
+		now = now.Add(2 * time.Second)
+		_, ok := lru.Get("a")
+		assert.False(t, ok)
+		assert.Equal(t, 0, lru.Len())
+	})
+}
+
+func TestCachedProductRepository_SharedLookupCancelled(t *testing.T) {
+	sku := "FAL-1000000"
+	repositoryMock := new(product.RepositoryMock)
+	repositoryMock.On("GetBySku", sku).Return((*entity.Product)(nil), context.Canceled).Once()
+	repositoryMock.On("GetBySku", sku).Return(newFakeProduct(sku), nil).Once()
+
+	got, err := NewCachedProductRepository(repositoryMock).GetBySku(ctx, sku)
+	assert.NoError(t, err)
+	assert.Equal(t, newFakeProduct(sku), got)
+	repositoryMock.AssertNumberOfCalls(t, "GetBySku", 2)
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+)
+
+// CachedUnitOfWork keeps a CachedProductRepository consistent with writes
+// made in transactions. Reads inside a transaction bypass the cache, and the
+// SKUs written are invalidated once the transaction ends.
+type CachedUnitOfWork struct {
+	unitOfWork repository.UnitOfWork
+	cache      *CachedProductRepository
+}
+
+func NewCachedUnitOfWork(unitOfWork repository.UnitOfWork, cache *CachedProductRepository) repository.UnitOfWork {
+	return &CachedUnitOfWork{
+		unitOfWork: unitOfWork,
+		cache:      cache,
+	}
+}
+
+func (u *CachedUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, tx repository.Transaction) error) error {
+	written := &writtenSkus{}
+	err := u.unitOfWork.Do(ctx, func(ctx context.Context, tx repository.Transaction) error {
+		return fn(ctx, &invalidatingTransaction{Transaction: tx, written: written})
+	})
+	u.cache.invalidate(written.list()...)
+	return err
+}
+
+type writtenSkus struct {
+	mutex sync.Mutex
+	skus  []string
+}
+
+func (w *writtenSkus) add(skus ...string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.skus = append(w.skus, skus...)
+}
+
+func (w *writtenSkus) list() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.skus
+}
+
+type invalidatingTransaction struct {
+	repository.Transaction
+	written *writtenSkus
+}
+
+func (t *invalidatingTransaction) Products() repository.ProductRepository {
+	return &invalidatingProductRepository{ProductRepository: t.Transaction.Products(), written: t.written}
+}
+
+// invalidatingProductRepository records the SKUs written through it.
+type invalidatingProductRepository struct {
+	repository.ProductRepository
+	written *writtenSkus
+}
+
+func (r *invalidatingProductRepository) Save(ctx context.Context, product entity.Product) error {
+	r.written.add(product.Sku)
+	return r.ProductRepository.Save(ctx, product)
+}
+
+func (r *invalidatingProductRepository) Update(ctx context.Context, oldSku string, product entity.Product) (*entity.Product, error) {
+	r.written.add(oldSku, product.Sku)
+	return r.ProductRepository.Update(ctx, oldSku, product)
+}
+
+func (r *invalidatingProductRepository) Rename(ctx context.Context, sku string, newSku string) (*entity.Product, error) {
+	r.written.add(sku, newSku)
+	return r.ProductRepository.Rename(ctx, sku, newSku)
+}
+
+func (r *invalidatingProductRepository) Delete(ctx context.Context, sku string) error {
+	r.written.add(sku)
+	return r.ProductRepository.Delete(ctx, sku)
+}
@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/product"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/transaction"
+)
+
+func TestCachedUnitOfWork(t *testing.T) {
+	t.Run("should invalidate the skus written in a transaction", func(t *testing.T) {
+		sku := "FAL-1000000"
+		repositoryMock := new(product.RepositoryMock)
+		repositoryMock.On("GetBySku", sku).Return(newFakeProduct(sku), nil).Twice()
+		repositoryMock.On("Delete", sku).Return(nil)
+		cachedRepository := NewCachedProductRepository(repositoryMock)
+		unitOfWork := NewCachedUnitOfWork(&transaction.UnitOfWorkMock{ProductRepository: repositoryMock}, cachedRepository)
+
+		_, _ = cachedRepository.GetBySku(ctx, sku)
+		err := unitOfWork.Do(ctx, func(ctx context.Context, tx repository.Transaction) error {
+			return tx.Products().Delete(ctx, sku)
+		})
+		assert.NoError(t, err)
+		_, _ = cachedRepository.GetBySku(ctx, sku)
+
+		repositoryMock.AssertNumberOfCalls(t, "GetBySku", 2)
+	})
+
+	t.Run("should invalidate even when the transaction fails", func(t *testing.T) {
+		sku := "FAL-1000000"
+		repositoryMock := new(product.RepositoryMock)
+		repositoryMock.On("GetBySku", sku).Return(newFakeProduct(sku), nil).Twice()
+		repositoryMock.On("Update", sku, *newFakeProduct(sku)).Return((*entity.Product)(nil), errors.New("any error"))
+		cachedRepository := NewCachedProductRepository(repositoryMock)
+		unitOfWork := NewCachedUnitOfWork(&transaction.UnitOfWorkMock{ProductRepository: repositoryMock}, cachedRepository)
+
+		_, _ = cachedRepository.GetBySku(ctx, sku)
+		err := unitOfWork.Do(ctx, func(ctx context.Context, tx repository.Transaction) error {
+			_, err := tx.Products().Update(ctx, sku, *newFakeProduct(sku))
+			return err
+		})
+		assert.Error(t, err)
+		_, _ = cachedRepository.GetBySku(ctx, sku)
+
+		repositoryMock.AssertNumberOfCalls(t, "GetBySku", 2)
+	})
+}
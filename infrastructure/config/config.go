@@ -0,0 +1,251 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	MigrationsCheck = "check"
+	MigrationsAuto  = "auto"
+
+	redacted = "******"
+)
+
+// Config is the effective configuration of the service, see Load.
+type Config struct {
+	Server    ServerConfig
+	GRPC      GRPCConfig
+	Database  DatabaseConfig
+	Auth      AuthConfig
+	Cache     CacheConfig
+	RateLimit RateLimitConfig
+	Log       LogConfig
+	Tracing   TracingConfig
+	Outbox    OutboxConfig
+	Webhook   WebhookConfig
+	Stream    StreamConfig
+	GraphQL   GraphQLConfig
+	Products  ProductsConfig
+
+	// PrintConfig is set by the --print-config flag.
+	PrintConfig bool
+
+	sources map[string]string
+}
+
+type ServerConfig struct {
+	Host            string
+	Port            int
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	RequestTimeout  time.Duration
+	ShutdownTimeout time.Duration
+	DrainDelay      time.Duration
+}
+
+// GRPCConfig configures the gRPC server, which listens on the host of the
+// HTTP server. A zero Port disables it.
+type GRPCConfig struct {
+	Port int
+}
+
+type DatabaseConfig struct {
+	Host             string
+	Port             int
+	Name             string
+	User             string
+	Password         string
+	SSLMode          string
+	SSLRootCert      string
+	SSLCert          string
+	SSLKey           string
+	ConnectTimeout   time.Duration
+	StatementTimeout time.Duration
+	MaxOpenConns     int
+	MaxIdleConns     int
+	ConnMaxLifetime  time.Duration
+	ConnMaxIdleTime  time.Duration
+	ConnectRetries   int
+	ConnectBackoff   time.Duration
+	// Migrations is "check" to refuse to serve when the schema is behind
+	// or "auto" to apply pending migrations on startup.
+	Migrations string
+}
+
+type AuthConfig struct {
+	AdminAPIKey         string
+	JWTHS256Secret      string
+	JWTRSAPublicKeyFile string
+	JWTJWKSFile         string
+	JWTAudience         string
+	JWTIssuer           string
+}
+
+// CacheConfig holds the Cache-Control values of the product routes. Empty
+// values keep the defaults of the HTTP layer.
+type CacheConfig struct {
+	ProductCacheControl  string
+	ProductsCacheControl string
+}
+
+// RateLimitConfig holds limits written as "<requests per minute>[:<burst>]".
+// Empty values keep the defaults of the HTTP layer.
+type RateLimitConfig struct {
+	Read   string
+	Write  string
+	Bulk   string
+	Client string
+}
+
+type LogConfig struct {
+	Level  string
+	Format string
+}
+
+type TracingConfig struct {
+	Exporter    string
+	ServiceName string
+	SampleRatio float64
+}
+
+// OutboxConfig controls the relay dispatching product events.
+type OutboxConfig struct {
+	RelayInterval time.Duration
+	BatchSize     int
+}
+
+// WebhookConfig controls the dispatcher sending webhook deliveries.
+type WebhookConfig struct {
+	DispatchInterval time.Duration
+	Timeout          time.Duration
+	MaxAttempts      int
+}
+
+// StreamConfig controls the Server-Sent Events stream of product changes.
+type StreamConfig struct {
+	BufferSize        int
+	HeartbeatInterval time.Duration
+}
+
+// GraphQLConfig bounds the operations run by the GraphQL endpoint.
+type GraphQLConfig struct {
+	MaxComplexity int
+	MaxDepth      int
+}
+
+// ProductsConfig bounds the product endpoints. AvailabilityCheckInterval is
+// the longest the availability scheduler sleeps, so windows changed in the
+// meantime are picked up.
+type ProductsConfig struct {
+	MaxBatchSize              int
+	AvailabilityCheckInterval time.Duration
+}
+
+// Default returns the configuration used when nothing is set.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            8000,
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			RequestTimeout:  15 * time.Second,
+			ShutdownTimeout: 25 * time.Second,
+		},
+		GRPC: GRPCConfig{
+			Port: 9090,
+		},
+		Database: DatabaseConfig{
+			Port:            5432,
+			SSLMode:         "disable",
+			ConnectTimeout:  5 * time.Second,
+			MaxOpenConns:    20,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectRetries:  5,
+			ConnectBackoff:  500 * time.Millisecond,
+			Migrations:      MigrationsCheck,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "products-api",
+			SampleRatio: 1,
+		},
+		Outbox: OutboxConfig{
+			RelayInterval: time.Second,
+			BatchSize:     100,
+		},
+		Webhook: WebhookConfig{
+			DispatchInterval: time.Second,
+			Timeout:          10 * time.Second,
+			MaxAttempts:      10,
+		},
+		Stream: StreamConfig{
+			BufferSize:        1000,
+			HeartbeatInterval: 15 * time.Second,
+		},
+		GraphQL: GraphQLConfig{
+			MaxComplexity: 1000,
+			MaxDepth:      10,
+		},
+		Products: ProductsConfig{
+			MaxBatchSize:              100,
+			AvailabilityCheckInterval: time.Second,
+		},
+		sources: map[string]string{},
+	}
+}
+
+// Redacted returns every setting by key with secrets masked.
+func (c *Config) Redacted() map[string]string {
+	values := make(map[string]string)
+	for _, s := range c.settings() {
+		value := s.String()
+		if s.secret && value != "" {
+			value = redacted
+		}
+		values[s.key] = value
+	}
+	return values
+}
+
+// Print writes the effective configuration, one setting per line with the
+// source it was taken from, with secrets masked.
+func (c *Config) Print(w io.Writer) error {
+	values := c.Redacted()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		source := c.sources[key]
+		if source == "" {
+			source = "default"
+		}
+		if _, err := fmt.Fprintf(w, "%s = %q (%s)\n", key, values[key], source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Error lists every problem found while loading the configuration, so they
+// can all be fixed at once.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
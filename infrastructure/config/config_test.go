@@ -0,0 +1,112 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func env(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+func requiredEnv() map[string]string {
+	return map[string]string{
+		"POSTGRES_HOST":     "products-db",
+		"POSTGRES_DB":       "products",
+		"POSTGRES_USER":     "user-products",
+		"POSTGRES_PASSWORD": "s3cr3tpr0ducts",
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("should apply defaults and environment variables", func(t *testing.T) {
+		values := requiredEnv()
+		values["BACKEND_PORT"] = "8001"
+		values["HTTP_READ_TIMEOUT"] = "5s"
+
+		cfg, err := load(nil, env(values))
+		assert.NoError(t, err)
+		assert.Equal(t, 8001, cfg.Server.Port)
+		assert.Equal(t, 5*time.Second, cfg.Server.ReadTimeout)
+		assert.Equal(t, 30*time.Second, cfg.Server.WriteTimeout)
+		assert.Equal(t, "products-db", cfg.Database.Host)
+		assert.Equal(t, 5432, cfg.Database.Port)
+		assert.Equal(t, "disable", cfg.Database.SSLMode)
+	})
+
+	t.Run("should let the file override the environment and flags override both", func(t *testing.T) {
+		values := requiredEnv()
+		values["BACKEND_PORT"] = "8001"
+		values["PGPORT"] = "5433"
+		values["LOG_LEVEL"] = "warn"
+		values[ConfigFileEnv] = writeFile(t, "config.yaml", "server:\n  port: 8002\ndatabase:\n  port: 5434\n  max_open_conns: 40\ntracing:\n  sample_ratio: 0.25\n")
+
+		cfg, err := load([]string{"--server-port", "8003"}, env(values))
+		assert.NoError(t, err)
+		assert.Equal(t, 8003, cfg.Server.Port)
+		assert.Equal(t, 5434, cfg.Database.Port)
+		assert.Equal(t, 40, cfg.Database.MaxOpenConns)
+		assert.Equal(t, 0.25, cfg.Tracing.SampleRatio)
+		assert.Equal(t, "warn", cfg.Log.Level)
+	})
+
+	t.Run("should read toml files", func(t *testing.T) {
+		path := writeFile(t, "config.toml", "[database]\nsslmode = \"verify-full\"\nconn_max_lifetime = \"1h\"\n")
+
+		cfg, err := load([]string{"--config", path}, env(requiredEnv()))
+		assert.NoError(t, err)
+		assert.Equal(t, "verify-full", cfg.Database.SSLMode)
+		assert.Equal(t, time.Hour, cfg.Database.ConnMaxLifetime)
+	})
+
+	t.Run("should report every problem at once", func(t *testing.T) {
+		values := map[string]string{
+			"BACKEND_PORT":         "http",
+			"PGSSLMODE":            "sometimes",
+			"HTTP_REQUEST_TIMEOUT": "1m",
+		}
+		path := writeFile(t, "config.yaml", "database:\n  hots: localhost\n")
+
+		_, err := load([]string{"--config", path}, env(values))
+		assert.IsType(t, &Error{}, err)
+		assert.Equal(t, []string{
+			`server.port: must be an integer, got "http" from env BACKEND_PORT`,
+			"database.hots: unknown setting in " + path,
+			"server.request_timeout: must be positive and shorter than server.write_timeout (30s), got 1m0s",
+			"database.host: is required, set POSTGRES_HOST or --database-host",
+			"database.name: is required, set POSTGRES_DB or --database-name",
+			"database.user: is required, set POSTGRES_USER or --database-user",
+			`database.sslmode: must be one of disable, allow, prefer, require, verify-ca, verify-full, got "sometimes"`,
+		}, err.(*Error).Problems)
+	})
+}
+
+func TestPrint(t *testing.T) {
+	values := requiredEnv()
+	values["JWT_HS256_SECRET"] = "top-secret"
+	cfg, err := load([]string{"--log-format", "text"}, env(values))
+	assert.NoError(t, err)
+
+	var output bytes.Buffer
+	assert.NoError(t, cfg.Print(&output))
+	assert.Contains(t, output.String(), `database.password = "******" (env POSTGRES_PASSWORD)`)
+	assert.Contains(t, output.String(), `auth.jwt_hs256_secret = "******" (env JWT_HS256_SECRET)`)
+	assert.Contains(t, output.String(), `auth.admin_api_key = "" (default)`)
+	assert.Contains(t, output.String(), `log.format = "text" (flag --log-format)`)
+	assert.NotContains(t, output.String(), "s3cr3tpr0ducts")
+	assert.NotContains(t, output.String(), "top-secret")
+}
@@ -0,0 +1,251 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const ConfigFileEnv = "CONFIG_FILE"
+
+var (
+	sslModes  = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	logFormat = []string{"json", "text"}
+	exporters = []string{"none", "stdout", "otlp"}
+	migrate   = []string{MigrationsCheck, MigrationsAuto}
+)
+
+// Load builds the configuration from, in increasing precedence, the
+// defaults, environment variables, an optional YAML or TOML file given by
+// --config or CONFIG_FILE, and CLI flags. args are the command line
+// arguments without the program name.
+func Load(args []string) (*Config, error) {
+	return load(args, os.LookupEnv)
+}
+
+func load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	c := Default()
+	settings := c.settings()
+	problems := make([]string, 0)
+
+	flags := flag.NewFlagSet("products-api", flag.ContinueOnError)
+	configFile := flags.String("config", "", "YAML or TOML configuration file (env "+ConfigFileEnv+")")
+	flags.BoolVar(&c.PrintConfig, "print-config", false, "print the effective configuration, with secrets redacted, and exit")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.key] = flags.String(s.flag(), s.String(), fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	setFlags := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	for _, s := range settings {
+		if value, ok := lookupEnv(s.env); ok && strings.TrimSpace(value) != "" {
+			c.set(s, value, "env "+s.env, &problems)
+		}
+	}
+
+	if !setFlags["config"] {
+		*configFile, _ = lookupEnv(ConfigFileEnv)
+	}
+	if *configFile != "" {
+		fileValues, err := readFile(*configFile)
+		if err != nil {
+			return nil, err
+		}
+		known := make(map[string]bool, len(settings))
+		for _, s := range settings {
+			known[s.key] = true
+			if value, ok := fileValues[s.key]; ok {
+				c.set(s, value, "file "+*configFile, &problems)
+			}
+		}
+		for _, key := range sortedKeys(fileValues) {
+			if !known[key] {
+				problems = append(problems, fmt.Sprintf("%s: unknown setting in %s", key, *configFile))
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if setFlags[s.flag()] {
+			c.set(s, *flagValues[s.key], "flag --"+s.flag(), &problems)
+		}
+	}
+
+	problems = append(problems, c.validate()...)
+	if len(problems) > 0 {
+		return nil, &Error{Problems: problems}
+	}
+	return c, nil
+}
+
+func (c *Config) set(s setting, value, source string, problems *[]string) {
+	if err := s.Set(value); err != nil {
+		*problems = append(*problems, fmt.Sprintf("%s: %v, got %q from %s", s.key, err, value, source))
+		return
+	}
+	c.sources[s.key] = source
+}
+
+func (c *Config) validate() []string {
+	problems := make([]string, 0)
+	require := func(key, value string) {
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, fmt.Sprintf("%s: is required, set %s", key, c.envOf(key)))
+		}
+	}
+	check := func(ok bool, key, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, key+": "+fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port", "must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.GRPC.Port >= 0 && c.GRPC.Port <= 65535, "grpc.port", "must be between 0 and 65535, got %d", c.GRPC.Port)
+	check(c.GRPC.Port == 0 || c.GRPC.Port != c.Server.Port, "grpc.port", "must differ from server.port")
+	check(c.Server.ReadTimeout > 0, "server.read_timeout", "must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout", "must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout", "must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout", "must be positive")
+	check(c.Server.DrainDelay >= 0, "server.drain_delay", "must not be negative")
+	check(c.Server.RequestTimeout > 0 && c.Server.RequestTimeout < c.Server.WriteTimeout, "server.request_timeout",
+		"must be positive and shorter than server.write_timeout (%s), got %s", c.Server.WriteTimeout, c.Server.RequestTimeout)
+
+	require("database.host", c.Database.Host)
+	require("database.name", c.Database.Name)
+	require("database.user", c.Database.User)
+	check(c.Database.Port > 0 && c.Database.Port <= 65535, "database.port", "must be between 1 and 65535, got %d", c.Database.Port)
+	check(oneOf(c.Database.SSLMode, sslModes), "database.sslmode", "must be one of %s, got %q", strings.Join(sslModes, ", "), c.Database.SSLMode)
+	check(c.Database.MaxOpenConns >= 0, "database.max_open_conns", "must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns", "must not be negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns, "database.max_idle_conns",
+		"must not exceed database.max_open_conns (%d), got %d", c.Database.MaxOpenConns, c.Database.MaxIdleConns)
+	check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime", "must not be negative")
+	check(c.Database.ConnMaxIdleTime >= 0, "database.conn_max_idle_time", "must not be negative")
+	check(c.Database.ConnectTimeout >= 0, "database.connect_timeout", "must not be negative")
+	check(c.Database.StatementTimeout >= 0, "database.statement_timeout", "must not be negative")
+	check(c.Database.ConnectRetries >= 0, "database.connect_retries", "must not be negative")
+	check(c.Database.ConnectBackoff > 0, "database.connect_backoff", "must be positive")
+	check(oneOf(c.Database.Migrations, migrate), "database.migrations", "must be one of %s, got %q", strings.Join(migrate, ", "), c.Database.Migrations)
+	check((c.Database.SSLCert == "") == (c.Database.SSLKey == ""), "database.sslcert", "must be set together with database.sslkey")
+	for _, file := range []struct{ key, path string }{
+		{"database.sslrootcert", c.Database.SSLRootCert},
+		{"database.sslcert", c.Database.SSLCert},
+		{"database.sslkey", c.Database.SSLKey},
+	} {
+		if file.path == "" {
+			continue
+		}
+		_, err := os.Stat(file.path)
+		check(err == nil, file.key, "cannot read %s", file.path)
+	}
+
+	_, err := log.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level", "must be one of trace, debug, info, warn, error, got %q", c.Log.Level)
+	check(oneOf(c.Log.Format, logFormat), "log.format", "must be one of %s, got %q", strings.Join(logFormat, ", "), c.Log.Format)
+
+	check(oneOf(c.Tracing.Exporter, exporters), "tracing.exporter", "must be one of %s, got %q", strings.Join(exporters, ", "), c.Tracing.Exporter)
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio", "must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+
+	check(c.Outbox.RelayInterval > 0, "outbox.relay_interval", "must be positive")
+	check(c.Outbox.BatchSize > 0, "outbox.batch_size", "must be positive")
+
+	check(c.Webhook.DispatchInterval > 0, "webhook.dispatch_interval", "must be positive")
+	check(c.Webhook.Timeout > 0, "webhook.timeout", "must be positive")
+	check(c.Webhook.MaxAttempts > 0, "webhook.max_attempts", "must be positive")
+
+	check(c.Stream.BufferSize > 0, "stream.buffer_size", "must be positive")
+	check(c.Stream.HeartbeatInterval > 0, "stream.heartbeat_interval", "must be positive")
+
+	check(c.GraphQL.MaxComplexity > 0, "graphql.max_complexity", "must be positive")
+	check(c.GraphQL.MaxDepth > 0, "graphql.max_depth", "must be positive")
+
+	check(c.Products.MaxBatchSize > 0, "products.max_batch_size", "must be positive")
+	check(c.Products.AvailabilityCheckInterval > 0, "products.availability_check_interval", "must be positive")
+	return problems
+}
+
+func (c *Config) envOf(key string) string {
+	for _, s := range c.settings() {
+		if s.key == key {
+			return s.env + " or --" + s.flag()
+		}
+	}
+	return key
+}
+
+// readFile flattens a YAML or TOML document into dotted keys, so
+// "database:\n  port: 5433" becomes "database.port" = "5433".
+func readFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error trying to read config file: %w", err)
+	}
+	document := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &document)
+	case ".toml":
+		err = toml.Unmarshal(content, &document)
+	default:
+		return nil, fmt.Errorf("unsupported config file %q: use a .yaml, .yml or .toml extension", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error trying to parse config file %s: %w", path, err)
+	}
+	values := make(map[string]string)
+	if err := flatten("", document, values); err != nil {
+		return nil, fmt.Errorf("error trying to parse config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+func flatten(prefix string, document map[string]interface{}, values map[string]string) error {
+	for key, value := range document {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if err := flatten(key, value, values); err != nil {
+				return err
+			}
+		case []interface{}:
+			return fmt.Errorf("%s: lists are not supported", key)
+		case nil:
+		default:
+			values[key] = fmt.Sprint(value)
+		}
+	}
+	return nil
+}
+
+func oneOf(value string, valid []string) bool {
+	for _, candidate := range valid {
+		if value == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting binds one configuration value to its key in the config file, its
+// environment variable and its CLI flag, which is the key with dashes.
+type setting struct {
+	key    string
+	env    string
+	target interface{}
+	usage  string
+	secret bool
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "server.host", env: "BACKEND_IP", target: &c.Server.Host, usage: "address the HTTP server listens on"},
+		{key: "server.port", env: "BACKEND_PORT", target: &c.Server.Port, usage: "port the HTTP server listens on"},
+		{key: "grpc.port", env: "GRPC_PORT", target: &c.GRPC.Port, usage: "port the gRPC server listens on, 0 to disable it"},
+		{key: "server.read_timeout", env: "HTTP_READ_TIMEOUT", target: &c.Server.ReadTimeout, usage: "maximum duration for reading a request"},
+		{key: "server.write_timeout", env: "HTTP_WRITE_TIMEOUT", target: &c.Server.WriteTimeout, usage: "maximum duration for writing a response"},
+		{key: "server.idle_timeout", env: "HTTP_IDLE_TIMEOUT", target: &c.Server.IdleTimeout, usage: "how long idle keep-alive connections are kept"},
+		{key: "server.request_timeout", env: "HTTP_REQUEST_TIMEOUT", target: &c.Server.RequestTimeout, usage: "deadline given to handlers for each API request"},
+		{key: "server.shutdown_timeout", env: "SHUTDOWN_TIMEOUT", target: &c.Server.ShutdownTimeout, usage: "how long in-flight requests may run after a shutdown signal"},
+		{key: "server.drain_delay", env: "SHUTDOWN_DRAIN_DELAY", target: &c.Server.DrainDelay, usage: "how long /readyz reports draining before the listener closes"},
+
+		{key: "database.host", env: "POSTGRES_HOST", target: &c.Database.Host, usage: "PostgreSQL host"},
+		{key: "database.port", env: "PGPORT", target: &c.Database.Port, usage: "PostgreSQL port"},
+		{key: "database.name", env: "POSTGRES_DB", target: &c.Database.Name, usage: "PostgreSQL database name"},
+		{key: "database.user", env: "POSTGRES_USER", target: &c.Database.User, usage: "PostgreSQL user"},
+		{key: "database.password", env: "POSTGRES_PASSWORD", target: &c.Database.Password, usage: "PostgreSQL password", secret: true},
+		{key: "database.sslmode", env: "PGSSLMODE", target: &c.Database.SSLMode, usage: "PostgreSQL sslmode (disable, allow, prefer, require, verify-ca, verify-full)"},
+		{key: "database.sslrootcert", env: "PGSSLROOTCERT", target: &c.Database.SSLRootCert, usage: "CA certificate verifying the server with verify-ca and verify-full"},
+		{key: "database.sslcert", env: "PGSSLCERT", target: &c.Database.SSLCert, usage: "client certificate presented to the server"},
+		{key: "database.sslkey", env: "PGSSLKEY", target: &c.Database.SSLKey, usage: "private key of the client certificate"},
+		{key: "database.connect_timeout", env: "PGCONNECT_TIMEOUT", target: &c.Database.ConnectTimeout, usage: "maximum wait for a new connection, 0 to wait indefinitely"},
+		{key: "database.statement_timeout", env: "DB_STATEMENT_TIMEOUT", target: &c.Database.StatementTimeout, usage: "server-side limit for each statement, 0 for none"},
+		{key: "database.max_open_conns", env: "DB_MAX_OPEN_CONNS", target: &c.Database.MaxOpenConns, usage: "maximum open connections, 0 for unlimited"},
+		{key: "database.max_idle_conns", env: "DB_MAX_IDLE_CONNS", target: &c.Database.MaxIdleConns, usage: "maximum idle connections kept in the pool"},
+		{key: "database.conn_max_lifetime", env: "DB_CONN_MAX_LIFETIME", target: &c.Database.ConnMaxLifetime, usage: "maximum lifetime of a connection, 0 to keep them forever"},
+		{key: "database.conn_max_idle_time", env: "DB_CONN_MAX_IDLE_TIME", target: &c.Database.ConnMaxIdleTime, usage: "maximum idle time of a connection, 0 to keep them forever"},
+		{key: "database.connect_retries", env: "DB_CONNECT_RETRIES", target: &c.Database.ConnectRetries, usage: "attempts to reach the database on startup after the first one"},
+		{key: "database.connect_backoff", env: "DB_CONNECT_BACKOFF", target: &c.Database.ConnectBackoff, usage: "wait before the first retry, doubled on every attempt"},
+		{key: "database.migrations", env: "DB_MIGRATIONS", target: &c.Database.Migrations, usage: "on startup, check that the schema is current or auto to apply pending migrations"},
+
+		{key: "auth.admin_api_key", env: "ADMIN_API_KEY", target: &c.Auth.AdminAPIKey, usage: "API key seeded with the admin role on startup", secret: true},
+		{key: "auth.jwt_hs256_secret", env: "JWT_HS256_SECRET", target: &c.Auth.JWTHS256Secret, usage: "secret accepting HS256 bearer tokens", secret: true},
+		{key: "auth.jwt_rsa_public_key_file", env: "JWT_RSA_PUBLIC_KEY_FILE", target: &c.Auth.JWTRSAPublicKeyFile, usage: "PEM public key accepting RS256 bearer tokens"},
+		{key: "auth.jwt_jwks_file", env: "JWT_JWKS_FILE", target: &c.Auth.JWTJWKSFile, usage: "JWKS document accepting bearer tokens by kid"},
+		{key: "auth.jwt_audience", env: "JWT_AUDIENCE", target: &c.Auth.JWTAudience, usage: "required aud claim of bearer tokens"},
+		{key: "auth.jwt_issuer", env: "JWT_ISSUER", target: &c.Auth.JWTIssuer, usage: "required iss claim of bearer tokens"},
+
+		{key: "cache.product_cache_control", env: "CACHE_CONTROL_PRODUCT", target: &c.Cache.ProductCacheControl, usage: "Cache-Control of GET /products/{sku}"},
+		{key: "cache.products_cache_control", env: "CACHE_CONTROL_PRODUCTS", target: &c.Cache.ProductsCacheControl, usage: "Cache-Control of GET /products/"},
+
+		{key: "rate_limit.read", env: "RATE_LIMIT_READ", target: &c.RateLimit.Read, usage: "read limit as <requests per minute>[:<burst>]"},
+		{key: "rate_limit.write", env: "RATE_LIMIT_WRITE", target: &c.RateLimit.Write, usage: "write limit as <requests per minute>[:<burst>]"},
+		{key: "rate_limit.bulk", env: "RATE_LIMIT_BULK", target: &c.RateLimit.Bulk, usage: "bulk limit as <requests per minute>[:<burst>]"},
+		{key: "rate_limit.client", env: "RATE_LIMIT_CLIENT", target: &c.RateLimit.Client, usage: "limit per client IP, checked before authenticating, as <requests per minute>[:<burst>]"},
+
+		{key: "log.level", env: "LOG_LEVEL", target: &c.Log.Level, usage: "log level (trace, debug, info, warn, error)"},
+		{key: "log.format", env: "LOG_FORMAT", target: &c.Log.Format, usage: "log format (json, text)"},
+
+		{key: "tracing.exporter", env: "TRACING_EXPORTER", target: &c.Tracing.Exporter, usage: "span exporter (none, stdout, otlp)"},
+		{key: "tracing.service_name", env: "OTEL_SERVICE_NAME", target: &c.Tracing.ServiceName, usage: "service name reported with spans"},
+		{key: "tracing.sample_ratio", env: "TRACING_SAMPLE_RATIO", target: &c.Tracing.SampleRatio, usage: "fraction of new traces recorded, between 0 and 1"},
+
+		{key: "outbox.relay_interval", env: "OUTBOX_RELAY_INTERVAL", target: &c.Outbox.RelayInterval, usage: "how often the relay checks the outbox for events"},
+		{key: "outbox.batch_size", env: "OUTBOX_BATCH_SIZE", target: &c.Outbox.BatchSize, usage: "events dispatched per outbox query"},
+
+		{key: "webhook.dispatch_interval", env: "WEBHOOK_DISPATCH_INTERVAL", target: &c.Webhook.DispatchInterval, usage: "how often the dispatcher checks for due webhook deliveries"},
+		{key: "webhook.timeout", env: "WEBHOOK_TIMEOUT", target: &c.Webhook.Timeout, usage: "timeout of a webhook delivery request"},
+		{key: "webhook.max_attempts", env: "WEBHOOK_MAX_ATTEMPTS", target: &c.Webhook.MaxAttempts, usage: "attempts before a webhook delivery is dead"},
+
+		{key: "stream.buffer_size", env: "STREAM_BUFFER_SIZE", target: &c.Stream.BufferSize, usage: "recent product events kept for clients resuming the stream"},
+		{key: "stream.heartbeat_interval", env: "STREAM_HEARTBEAT_INTERVAL", target: &c.Stream.HeartbeatInterval, usage: "how often an idle product stream sends a heartbeat"},
+
+		{key: "graphql.max_complexity", env: "GRAPHQL_MAX_COMPLEXITY", target: &c.GraphQL.MaxComplexity, usage: "most fields a GraphQL operation may resolve, counting each page item"},
+		{key: "graphql.max_depth", env: "GRAPHQL_MAX_DEPTH", target: &c.GraphQL.MaxDepth, usage: "deepest field nesting a GraphQL operation may select"},
+
+		{key: "products.max_batch_size", env: "PRODUCTS_MAX_BATCH_SIZE", target: &c.Products.MaxBatchSize, usage: "most SKUs a batch get of products accepts"},
+		{key: "products.availability_check_interval", env: "PRODUCTS_AVAILABILITY_CHECK_INTERVAL", target: &c.Products.AvailabilityCheckInterval, usage: "how often the scheduler checks for changed availability windows"},
+	}
+}
+
+func (s setting) flag() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+// Set parses value into the bound field.
+func (s setting) Set(value string) error {
+	value = strings.TrimSpace(value)
+	switch target := s.target.(type) {
+	case *string:
+		*target = value
+	case *int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		*target = parsed
+	case *float64:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		*target = parsed
+	case *time.Duration:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("must be a duration such as 15s or 1m")
+		}
+		*target = parsed
+	default:
+		return fmt.Errorf("unsupported setting type %T", s.target)
+	}
+	return nil
+}
+
+func (s setting) String() string {
+	switch target := s.target.(type) {
+	case *string:
+		return *target
+	case *int:
+		return strconv.Itoa(*target)
+	case *float64:
+		return strconv.FormatFloat(*target, 'g', -1, 64)
+	case *time.Duration:
+		return target.String()
+	}
+	return ""
+}
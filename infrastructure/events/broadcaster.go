@@ -0,0 +1,179 @@
+package events
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+)
+
+const (
+	DefaultBufferSize = 1000
+
+	// subscriptionBuffer is how many events a subscriber may fall behind
+	// before it is dropped. Dropped clients reconnect and resume from the
+	// buffer with their last event ID.
+	subscriptionBuffer = 64
+)
+
+// Filter selects the events a subscriber receives. Empty fields match every
+// event.
+type Filter struct {
+	Brand     string
+	SkuPrefix string
+	// Allowed, when set, tells whether the subscriber may receive event.
+	Allowed func(event entity.ProductEvent) bool
+}
+
+func (f Filter) Matches(event entity.ProductEvent) bool {
+	if f.Allowed != nil && !f.Allowed(event) {
+		return false
+	}
+	if f.Brand != "" && (event.Product == nil || !strings.EqualFold(event.Product.Brand, f.Brand)) {
+		return false
+	}
+	if f.SkuPrefix != "" && !strings.HasPrefix(event.Sku, f.SkuPrefix) &&
+		(event.PreviousSku == "" || !strings.HasPrefix(event.PreviousSku, f.SkuPrefix)) {
+		return false
+	}
+	return true
+}
+
+// Subscription receives the events published after it was opened on
+// Events, which is closed when the subscriber falls too far behind or the
+// broadcaster is closed.
+type Subscription struct {
+	// Backlog holds the buffered events following the last event ID given
+	// to Subscribe.
+	Backlog []entity.ProductEvent
+	// Resumed is false when the last event ID given to Subscribe is no
+	// longer buffered, so events may have been missed.
+	Resumed bool
+	Events  <-chan entity.ProductEvent
+
+	events chan entity.ProductEvent
+	filter Filter
+}
+
+// Broadcaster is a usecase.EventPublisher that fans product events out to the subscribers connected to this
+// instance and keeps the latest ones so they can resume after reconnecting. It is fed by a
+// usecase.OutboxFollower, so every instance gets the events dispatched by any of them.
+type Broadcaster struct {
+	mu          sync.Mutex
+	buffer      []entity.ProductEvent
+	bufferSize  int
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+func NewBroadcaster(bufferSize int) *Broadcaster {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	return &Broadcaster{
+		bufferSize:  bufferSize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+func (b *Broadcaster) Name() string {
+	return "stream"
+}
+
+// Publish never blocks on subscribers. Events published again by the relay
+// are ignored while they are still buffered.
+func (b *Broadcaster) Publish(ctx context.Context, event entity.ProductEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.indexOf(event.ID) >= 0 {
+		return nil
+	}
+	b.buffer = append(b.buffer, event)
+	if len(b.buffer) > b.bufferSize {
+		b.buffer = b.buffer[len(b.buffer)-b.bufferSize:]
+	}
+	for subscription := range b.subscribers {
+		if !subscription.filter.Matches(event) {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			b.remove(subscription)
+		}
+	}
+	return nil
+}
+
+// Subscribe opens a subscription to the events matching filter. When
+// lastEventID is given, the buffered events published after it are returned
+// in the subscription backlog.
+func (b *Broadcaster) Subscribe(lastEventID string, filter Filter) *Subscription {
+	events := make(chan entity.ProductEvent, subscriptionBuffer)
+	subscription := &Subscription{
+		Resumed: true,
+		Events:  events,
+		events:  events,
+		filter:  filter,
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if lastEventID != "" {
+		index := b.indexOf(lastEventID)
+		subscription.Resumed = index >= 0
+		if subscription.Resumed {
+			for _, event := range b.buffer[index+1:] {
+				if filter.Matches(event) {
+					subscription.Backlog = append(subscription.Backlog, event)
+				}
+			}
+		}
+	}
+	if b.closed {
+		close(events)
+		return subscription
+	}
+	b.subscribers[subscription] = struct{}{}
+	return subscription
+}
+
+func (b *Broadcaster) Unsubscribe(subscription *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[subscription]; ok {
+		b.remove(subscription)
+	}
+}
+
+// Close ends every subscription, so streaming requests return and the
+// server can shut down.
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for subscription := range b.subscribers {
+		b.remove(subscription)
+	}
+}
+
+// Subscribers returns the number of open subscriptions.
+func (b *Broadcaster) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
+
+func (b *Broadcaster) remove(subscription *Subscription) {
+	delete(b.subscribers, subscription)
+	close(subscription.events)
+}
+
+func (b *Broadcaster) indexOf(id string) int {
+	for i := len(b.buffer) - 1; i >= 0; i-- {
+		if b.buffer[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,114 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yescorihuela/agrak/domain/entity"
+)
+
+func productEvent(id, sku, brand string) entity.ProductEvent {
+	return entity.ProductEvent{
+		ID:      id,
+		Type:    entity.EventProductUpdated,
+		Sku:     sku,
+		Product: &entity.Product{Sku: sku, Brand: brand},
+	}
+}
+
+func eventIDs(events []entity.ProductEvent) []string {
+	ids := make([]string, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func TestBroadcaster_Publish(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should deliver matching events to subscribers", func(t *testing.T) {
+		broadcaster := NewBroadcaster(10)
+		all := broadcaster.Subscribe("", Filter{})
+		nike := broadcaster.Subscribe("", Filter{Brand: "nike"})
+
+		_ = broadcaster.Publish(ctx, productEvent("a", "FAL-1000000", "Nike"))
+		_ = broadcaster.Publish(ctx, productEvent("b", "FAL-2000000", "Adidas"))
+
+		assert.Equal(t, "a", (<-all.Events).ID)
+		assert.Equal(t, "b", (<-all.Events).ID)
+		assert.Equal(t, "a", (<-nike.Events).ID)
+		assert.Len(t, nike.Events, 0)
+	})
+
+	t.Run("should ignore events published again", func(t *testing.T) {
+		broadcaster := NewBroadcaster(10)
+		subscription := broadcaster.Subscribe("", Filter{})
+
+		_ = broadcaster.Publish(ctx, productEvent("a", "FAL-1000000", "Nike"))
+		_ = broadcaster.Publish(ctx, productEvent("a", "FAL-1000000", "Nike"))
+
+		assert.Len(t, subscription.Events, 1)
+	})
+
+	t.Run("should drop subscribers that fall behind", func(t *testing.T) {
+		broadcaster := NewBroadcaster(10)
+		subscription := broadcaster.Subscribe("", Filter{})
+
+		for i := 0; i <= subscriptionBuffer; i++ {
+			_ = broadcaster.Publish(ctx, productEvent(string(rune('a'+i)), "FAL-1000000", "Nike"))
+		}
+
+		assert.Equal(t, 0, broadcaster.Subscribers())
+		for range subscription.Events {
+		}
+	})
+}
+
+func TestBroadcaster_Subscribe(t *testing.T) {
+	ctx := context.Background()
+	broadcaster := NewBroadcaster(3)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		_ = broadcaster.Publish(ctx, productEvent(id, "FAL-1000000", "Nike"))
+	}
+
+	t.Run("should replay the events after the last event ID", func(t *testing.T) {
+		subscription := broadcaster.Subscribe("b", Filter{})
+		defer broadcaster.Unsubscribe(subscription)
+
+		assert.True(t, subscription.Resumed)
+		assert.Equal(t, []string{"c", "d"}, eventIDs(subscription.Backlog))
+	})
+
+	t.Run("should report events evicted from the buffer", func(t *testing.T) {
+		subscription := broadcaster.Subscribe("a", Filter{})
+		defer broadcaster.Unsubscribe(subscription)
+
+		assert.False(t, subscription.Resumed)
+		assert.Empty(t, subscription.Backlog)
+	})
+
+	t.Run("should end subscriptions on close", func(t *testing.T) {
+		subscription := broadcaster.Subscribe("", Filter{})
+		broadcaster.Close()
+
+		_, open := <-subscription.Events
+		assert.False(t, open)
+		_, open = <-broadcaster.Subscribe("", Filter{}).Events
+		assert.False(t, open)
+	})
+}
+
+func TestFilter_Matches(t *testing.T) {
+	renamed := productEvent("a", "FAL-2000000", "Nike")
+	renamed.PreviousSku = "FAL-1000000"
+
+	assert.True(t, Filter{}.Matches(renamed))
+	assert.True(t, Filter{Brand: "NIKE"}.Matches(renamed))
+	assert.False(t, Filter{Brand: "Adidas"}.Matches(renamed))
+	assert.True(t, Filter{SkuPrefix: "FAL-2"}.Matches(renamed))
+	assert.True(t, Filter{SkuPrefix: "FAL-1"}.Matches(renamed), "renamed products match their previous SKU")
+	assert.False(t, Filter{SkuPrefix: "FAL-3"}.Matches(renamed))
+	assert.False(t, Filter{Brand: "Nike"}.Matches(entity.ProductEvent{ID: "b", Sku: "FAL-1000000"}))
+}
@@ -0,0 +1,31 @@
+package events
+
+import (
+	"context"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/shared/logging"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+// LogPublisher writes one log line per product event. It is always
+// registered, so the relay drains the outbox even without other publishers.
+type LogPublisher struct{}
+
+func NewLogPublisher() usecase.EventPublisher {
+	return &LogPublisher{}
+}
+
+func (p *LogPublisher) Name() string {
+	return "log"
+}
+
+func (p *LogPublisher) Publish(ctx context.Context, event entity.ProductEvent) error {
+	logging.FromContext(ctx).
+		WithField("event_id", event.ID).
+		WithField("event_type", event.Type).
+		WithField("sku", event.Sku).
+		WithField("changed_fields", event.ChangedFields).
+		Infoln("product event")
+	return nil
+}
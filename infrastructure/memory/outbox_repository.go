@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+)
+
+type outboxRecord struct {
+	entry         entity.OutboxEntry
+	nextAttemptAt time.Time
+}
+
+// OutboxRepository keeps outbox events in a map. Like ProductRepository, it
+// takes part in UnitOfWork transactions.
+type OutboxRepository struct {
+	writer  sync.Mutex
+	mutex   sync.RWMutex
+	records map[string]outboxRecord
+}
+
+func NewOutboxRepository() *OutboxRepository {
+	return &OutboxRepository{
+		records: make(map[string]outboxRecord),
+	}
+}
+
+func (r *OutboxRepository) Append(ctx context.Context, events ...entity.ProductEvent) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, event := range events {
+		r.records[event.ID] = outboxRecord{
+			entry:         entity.OutboxEntry{Event: event},
+			nextAttemptAt: event.OccurredAt,
+		}
+	}
+	return nil
+}
+
+func (r *OutboxRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entity.OutboxEntry, error) {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	due := make([]outboxRecord, 0)
+	for _, record := range r.records {
+		if record.entry.DispatchedAt == nil && !record.nextAttemptAt.After(now) {
+			due = append(due, record)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].entry.Event.OccurredAt.Before(due[j].entry.Event.OccurredAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	entries := make([]entity.OutboxEntry, 0, len(due))
+	for _, record := range due {
+		record.nextAttemptAt = now.Add(lease)
+		r.records[record.entry.Event.ID] = record
+		entries = append(entries, record.entry)
+	}
+	return entries, nil
+}
+
+func (r *OutboxRepository) MarkDispatched(ctx context.Context, id string, dispatchedAt time.Time) error {
+	return r.update(id, func(record *outboxRecord) {
+		record.entry.DispatchedAt = &dispatchedAt
+		record.entry.LastError = ""
+	})
+}
+
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id string, nextAttemptAt time.Time, reason string) error {
+	return r.update(id, func(record *outboxRecord) {
+		record.entry.Attempts++
+		record.entry.LastError = reason
+		record.nextAttemptAt = nextAttemptAt
+	})
+}
+
+func (r *OutboxRepository) ListDispatched(ctx context.Context, since time.Time, afterID string, limit int) ([]entity.OutboxEntry, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	entries := make([]entity.OutboxEntry, 0)
+	for _, record := range r.records {
+		dispatchedAt := record.entry.DispatchedAt
+		if dispatchedAt == nil || dispatchedAt.Before(since) {
+			continue
+		}
+		if dispatchedAt.After(since) || record.entry.Event.ID > afterID {
+			entries = append(entries, record.entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].DispatchedAt.Equal(*entries[j].DispatchedAt) {
+			return entries[i].Event.ID < entries[j].Event.ID
+		}
+		return entries[i].DispatchedAt.Before(*entries[j].DispatchedAt)
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// Dispatched reports whether the event id was dispatched.
+func (r *OutboxRepository) Dispatched(id string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	record, ok := r.records[id]
+	return ok && record.entry.DispatchedAt != nil
+}
+
+func (r *OutboxRepository) update(id string, change func(record *outboxRecord)) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	record, ok := r.records[id]
+	if !ok {
+		return repository.ErrOutboxEventNotFound
+	}
+	change(&record)
+	r.records[id] = record
+	return nil
+}
+
+func (r *OutboxRepository) stage() *OutboxRepository {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	staged := &OutboxRepository{
+		records: make(map[string]outboxRecord, len(r.records)),
+	}
+	for id, record := range r.records {
+		staged.records[id] = record
+	}
+	return staged
+}
+
+func (r *OutboxRepository) commit(staged *OutboxRepository) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.records = staged.records
+}
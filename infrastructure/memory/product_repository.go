@@ -0,0 +1,289 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+)
+
+// ProductRepository keeps products in a map. It is safe for concurrent use
+// and, with UnitOfWork, supports transactions.
+type ProductRepository struct {
+	// writer serializes writes and transactions, mutex guards products.
+	writer   sync.Mutex
+	mutex    sync.RWMutex
+	products map[string]entity.Product
+	// aliases maps former SKUs to the SKU of their product.
+	aliases map[string]string
+	// announced holds the availability last announced for each product.
+	announced map[string]bool
+	now       func() time.Time
+}
+
+func NewProductRepository() *ProductRepository {
+	return &ProductRepository{
+		products:  make(map[string]entity.Product),
+		aliases:   make(map[string]string),
+		announced: make(map[string]bool),
+		now:       time.Now,
+	}
+}
+
+func (r *ProductRepository) Save(ctx context.Context, product entity.Product) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.products[product.Sku]; ok {
+		return repository.ErrDuplicatedProduct
+	}
+	if _, err := product.IsValid(); err != nil {
+		return err
+	}
+	product.CreatedAt = r.now()
+	product.UpdatedAt = product.CreatedAt
+	r.products[product.Sku] = cloneProduct(product)
+	r.announced[product.Sku] = product.IsAvailableAt(product.CreatedAt)
+	return nil
+}
+
+func (r *ProductRepository) Update(ctx context.Context, oldSku string, product entity.Product) (*entity.Product, error) {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	oldProduct, ok := r.products[oldSku]
+	if !ok {
+		return nil, repository.ErrProductNotFound
+	}
+	if _, ok := r.products[product.Sku]; ok && product.Sku != oldSku {
+		return nil, repository.ErrDuplicatedProduct
+	}
+	product.CreatedAt = oldProduct.CreatedAt
+	product.UpdatedAt = r.now()
+	delete(r.products, oldSku)
+	r.products[product.Sku] = cloneProduct(product)
+	if product.Sku != oldSku {
+		r.announced[product.Sku] = r.announced[oldSku]
+		delete(r.announced, oldSku)
+	}
+	updatedProduct := cloneProduct(product)
+	return &updatedProduct, nil
+}
+
+func (r *ProductRepository) GetBySku(ctx context.Context, sku string) (*entity.Product, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	product, ok := r.products[sku]
+	if !ok {
+		return nil, repository.ErrProductNotFound
+	}
+	product = cloneProduct(product)
+	return &product, nil
+}
+
+// GetAllProducts returns the products ordered by SKU.
+func (r *ProductRepository) GetAllProducts(ctx context.Context) ([]entity.Product, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if len(r.products) == 0 {
+		return nil, repository.ErrProductNotFound
+	}
+	products := make([]entity.Product, 0, len(r.products))
+	for _, product := range r.products {
+		products = append(products, cloneProduct(product))
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].Sku < products[j].Sku
+	})
+	return products, nil
+}
+
+func (r *ProductRepository) GetBySkus(ctx context.Context, skus []string) ([]entity.Product, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	products := make([]entity.Product, 0, len(skus))
+	seen := make(map[string]bool, len(skus))
+	for _, sku := range skus {
+		product, ok := r.products[sku]
+		if !ok || seen[sku] {
+			continue
+		}
+		seen[sku] = true
+		products = append(products, cloneProduct(product))
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].Sku < products[j].Sku
+	})
+	return products, nil
+}
+
+func (r *ProductRepository) GetPage(ctx context.Context, filter repository.ProductFilter, afterSku string, limit int) ([]entity.Product, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	products := make([]entity.Product, 0)
+	for sku, product := range r.products {
+		if sku > afterSku && filter.Matches(product) {
+			products = append(products, cloneProduct(product))
+		}
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].Sku < products[j].Sku
+	})
+	if len(products) > limit {
+		products = products[:limit]
+	}
+	return products, nil
+}
+
+func (r *ProductRepository) Rename(ctx context.Context, sku string, newSku string) (*entity.Product, error) {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	product, ok := r.products[sku]
+	if !ok {
+		return nil, repository.ErrProductNotFound
+	}
+	if _, ok := r.products[newSku]; ok {
+		return nil, repository.ErrDuplicatedProduct
+	}
+	delete(r.products, sku)
+	product.Sku = newSku
+	product.UpdatedAt = r.now()
+	r.products[newSku] = product
+	r.announced[newSku] = r.announced[sku]
+	delete(r.announced, sku)
+
+	delete(r.aliases, newSku)
+	for alias, target := range r.aliases {
+		if target == sku {
+			r.aliases[alias] = newSku
+		}
+	}
+	r.aliases[sku] = newSku
+	product = cloneProduct(product)
+	return &product, nil
+}
+
+func (r *ProductRepository) GetAliasTarget(ctx context.Context, sku string) (string, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	target, ok := r.aliases[sku]
+	if !ok {
+		return "", repository.ErrSkuAliasNotFound
+	}
+	return target, nil
+}
+
+func (r *ProductRepository) Delete(ctx context.Context, sku string) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.products, sku)
+	delete(r.announced, sku)
+	for alias, target := range r.aliases {
+		if target == sku {
+			delete(r.aliases, alias)
+		}
+	}
+	return nil
+}
+
+func (r *ProductRepository) GetAvailabilityChanges(ctx context.Context, t time.Time) ([]entity.Product, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	products := make([]entity.Product, 0)
+	for sku, product := range r.products {
+		if product.IsAvailableAt(t) != r.announced[sku] {
+			products = append(products, cloneProduct(product))
+		}
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].Sku < products[j].Sku
+	})
+	return products, nil
+}
+
+func (r *ProductRepository) SetAnnouncedAvailability(ctx context.Context, sku string, available bool) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.products[sku]; !ok {
+		return repository.ErrProductNotFound
+	}
+	r.announced[sku] = available
+	return nil
+}
+
+func (r *ProductRepository) NextAvailabilityChange(ctx context.Context, t time.Time) (*time.Time, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var next *time.Time
+	for _, product := range r.products {
+		for _, boundary := range []*time.Time{product.AvailableFrom, product.AvailableUntil} {
+			if boundary != nil && boundary.After(t) && (next == nil || boundary.Before(*next)) {
+				at := *boundary
+				next = &at
+			}
+		}
+	}
+	return next, nil
+}
+
+// stage returns a copy of the repository that a transaction can change
+// without other callers seeing it.
+func (r *ProductRepository) stage() *ProductRepository {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	staged := &ProductRepository{
+		products:  make(map[string]entity.Product, len(r.products)),
+		aliases:   make(map[string]string, len(r.aliases)),
+		announced: make(map[string]bool, len(r.announced)),
+		now:       r.now,
+	}
+	for sku, product := range r.products {
+		staged.products[sku] = product
+	}
+	for alias, target := range r.aliases {
+		staged.aliases[alias] = target
+	}
+	for sku, available := range r.announced {
+		staged.announced[sku] = available
+	}
+	return staged
+}
+
+func (r *ProductRepository) commit(staged *ProductRepository) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.products = staged.products
+	r.aliases = staged.aliases
+	r.announced = staged.announced
+}
+
+func cloneProduct(product entity.Product) entity.Product {
+	if product.OtherImages != nil {
+		product.OtherImages = append([]string{}, product.OtherImages...)
+	}
+	return product
+}
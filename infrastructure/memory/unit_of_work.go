@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/yescorihuela/agrak/domain/repository"
+)
+
+// UnitOfWork runs transactions against in-memory repositories. A
+// transaction holds their write locks, so transactions and writes run one at
+// a time, and works on copies that replace the stored data on commit.
+// Readers keep seeing the committed data meanwhile.
+type UnitOfWork struct {
+	products *ProductRepository
+	outbox   *OutboxRepository
+}
+
+func NewUnitOfWork(products *ProductRepository, outbox *OutboxRepository) repository.UnitOfWork {
+	return &UnitOfWork{
+		products: products,
+		outbox:   outbox,
+	}
+}
+
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, tx repository.Transaction) error) error {
+	u.products.writer.Lock()
+	defer u.products.writer.Unlock()
+	u.outbox.writer.Lock()
+	defer u.outbox.writer.Unlock()
+
+	staged := &transaction{products: u.products.stage(), outbox: u.outbox.stage()}
+	if err := fn(ctx, staged); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	u.products.commit(staged.products)
+	u.outbox.commit(staged.outbox)
+	return nil
+}
+
+type transaction struct {
+	products *ProductRepository
+	outbox   *OutboxRepository
+}
+
+func (t *transaction) Products() repository.ProductRepository {
+	return t.products
+}
+
+func (t *transaction) Outbox() repository.OutboxRepository {
+	return t.outbox
+}
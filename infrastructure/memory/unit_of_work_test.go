@@ -0,0 +1,224 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+)
+
+var ctx = context.Background()
+
+func newFakeProduct(sku string) entity.Product {
+	return entity.Product{
+		Sku:            sku,
+		Name:           "Polera",
+		Brand:          "CAT",
+		Size:           "XL",
+		Price:          20000.00,
+		PrincipalImage: "https://placehold.jp/3d4070/ffffff/150x150.png",
+		OtherImages:    []string{"https://placehold.jp/24/cccccc/ffffff/250x50.png"},
+	}
+}
+
+func TestUnitOfWork(t *testing.T) {
+	t.Run("should commit the changes when the work succeeds", func(t *testing.T) {
+		products := NewProductRepository()
+		unitOfWork := NewUnitOfWork(products, NewOutboxRepository())
+
+		err := unitOfWork.Do(ctx, func(ctx context.Context, tx repository.Transaction) error {
+			if err := tx.Products().Save(ctx, newFakeProduct("FAL-1000000")); err != nil {
+				return err
+			}
+			return tx.Products().Save(ctx, newFakeProduct("FAL-1000001"))
+		})
+
+		assert.NoError(t, err)
+		stored, err := products.GetAllProducts(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, stored, 2)
+	})
+
+	t.Run("should discard every change when the work fails", func(t *testing.T) {
+		products, outbox := NewProductRepository(), NewOutboxRepository()
+		assert.NoError(t, products.Save(ctx, newFakeProduct("FAL-1000000")))
+		unitOfWork := NewUnitOfWork(products, outbox)
+
+		err := unitOfWork.Do(ctx, func(ctx context.Context, tx repository.Transaction) error {
+			if err := tx.Products().Delete(ctx, "FAL-1000000"); err != nil {
+				return err
+			}
+			if err := tx.Outbox().Append(ctx, entity.ProductEvent{ID: "deleted", Type: entity.EventProductDeleted}); err != nil {
+				return err
+			}
+			if err := tx.Products().Save(ctx, newFakeProduct("FAL-1000001")); err != nil {
+				return err
+			}
+			return errors.New("any error")
+		})
+
+		assert.EqualError(t, err, "any error")
+		stored, err := products.GetAllProducts(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, stored, 1)
+		assert.Equal(t, "FAL-1000000", stored[0].Sku)
+		pending, err := outbox.Claim(ctx, time.Now(), time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+
+	t.Run("should let only one concurrent check-then-insert win", func(t *testing.T) {
+		products := NewProductRepository()
+		unitOfWork := NewUnitOfWork(products, NewOutboxRepository())
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- unitOfWork.Do(ctx, func(ctx context.Context, tx repository.Transaction) error {
+					if _, err := tx.Products().GetBySku(ctx, "FAL-1000000"); err == nil {
+						return repository.ErrDuplicatedProduct
+					}
+					return tx.Products().Save(ctx, newFakeProduct("FAL-1000000"))
+				})
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		created := 0
+		for err := range errs {
+			if err == nil {
+				created++
+				continue
+			}
+			assert.ErrorIs(t, err, repository.ErrDuplicatedProduct)
+		}
+		assert.Equal(t, 1, created)
+	})
+}
+
+func TestProductRepository_Update(t *testing.T) {
+	t.Run("should reject renaming to a taken sku", func(t *testing.T) {
+		products := NewProductRepository()
+		assert.NoError(t, products.Save(ctx, newFakeProduct("FAL-1000000")))
+		assert.NoError(t, products.Save(ctx, newFakeProduct("FAL-1000001")))
+
+		_, err := products.Update(ctx, "FAL-1000000", newFakeProduct("FAL-1000001"))
+
+		assert.ErrorIs(t, err, repository.ErrDuplicatedProduct)
+	})
+
+	t.Run("should return not found for a missing product", func(t *testing.T) {
+		_, err := NewProductRepository().Update(ctx, "FAL-1000000", newFakeProduct("FAL-1000000"))
+
+		assert.ErrorIs(t, err, repository.ErrProductNotFound)
+	})
+}
+
+func TestProductRepository_Rename(t *testing.T) {
+	t.Run("should keep every former sku as an alias of the product", func(t *testing.T) {
+		products := NewProductRepository()
+		assert.NoError(t, products.Save(ctx, newFakeProduct("FAL-1000000")))
+
+		_, err := products.Rename(ctx, "FAL-1000000", "FAL-1000001")
+		assert.NoError(t, err)
+		renamed, err := products.Rename(ctx, "FAL-1000001", "FAL-1000002")
+		assert.NoError(t, err)
+
+		assert.Equal(t, "FAL-1000002", renamed.Sku)
+		for _, alias := range []string{"FAL-1000000", "FAL-1000001"} {
+			target, err := products.GetAliasTarget(ctx, alias)
+			assert.NoError(t, err)
+			assert.Equal(t, "FAL-1000002", target)
+		}
+		_, err = products.GetBySku(ctx, "FAL-1000000")
+		assert.ErrorIs(t, err, repository.ErrProductNotFound)
+	})
+
+	t.Run("should drop the alias a product is renamed back to", func(t *testing.T) {
+		products := NewProductRepository()
+		assert.NoError(t, products.Save(ctx, newFakeProduct("FAL-1000000")))
+
+		_, err := products.Rename(ctx, "FAL-1000000", "FAL-1000001")
+		assert.NoError(t, err)
+		_, err = products.Rename(ctx, "FAL-1000001", "FAL-1000000")
+		assert.NoError(t, err)
+
+		_, err = products.GetAliasTarget(ctx, "FAL-1000000")
+		assert.ErrorIs(t, err, repository.ErrSkuAliasNotFound)
+		target, err := products.GetAliasTarget(ctx, "FAL-1000001")
+		assert.NoError(t, err)
+		assert.Equal(t, "FAL-1000000", target)
+	})
+
+	t.Run("should delete the aliases with the product", func(t *testing.T) {
+		products := NewProductRepository()
+		assert.NoError(t, products.Save(ctx, newFakeProduct("FAL-1000000")))
+		_, err := products.Rename(ctx, "FAL-1000000", "FAL-1000001")
+		assert.NoError(t, err)
+
+		assert.NoError(t, products.Delete(ctx, "FAL-1000001"))
+
+		_, err = products.GetAliasTarget(ctx, "FAL-1000000")
+		assert.ErrorIs(t, err, repository.ErrSkuAliasNotFound)
+	})
+
+	t.Run("should reject renaming to a taken sku", func(t *testing.T) {
+		products := NewProductRepository()
+		assert.NoError(t, products.Save(ctx, newFakeProduct("FAL-1000000")))
+		assert.NoError(t, products.Save(ctx, newFakeProduct("FAL-1000001")))
+
+		_, err := products.Rename(ctx, "FAL-1000000", "FAL-1000001")
+
+		assert.ErrorIs(t, err, repository.ErrDuplicatedProduct)
+	})
+}
+
+func TestProductRepository_GetPage(t *testing.T) {
+	products := NewProductRepository()
+	for _, sku := range []string{"FAL-1000002", "FAL-1000000", "FAL-1000001"} {
+		assert.NoError(t, products.Save(ctx, newFakeProduct(sku)))
+	}
+
+	page, err := products.GetPage(ctx, repository.ProductFilter{}, "FAL-1000000", 1)
+	assert.NoError(t, err)
+	assert.Len(t, page, 1)
+	assert.Equal(t, "FAL-1000001", page[0].Sku)
+
+	page, err = products.GetPage(ctx, repository.ProductFilter{}, "FAL-1000002", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, page)
+
+	other := newFakeProduct("FAL-2000000")
+	other.Brand = "Nike"
+	assert.NoError(t, products.Save(ctx, other))
+	page, err = products.GetPage(ctx, repository.ProductFilter{Brand: "nike"}, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, page, 1)
+	assert.Equal(t, "FAL-2000000", page[0].Sku)
+
+	page, err = products.GetPage(ctx, repository.ProductFilter{SkuPrefix: "FAL-1"}, "FAL-1000000", 10)
+	assert.NoError(t, err)
+	assert.Len(t, page, 2)
+}
+
+func TestProductRepository_GetBySkus(t *testing.T) {
+	products := NewProductRepository()
+	for _, sku := range []string{"FAL-1000001", "FAL-1000000"} {
+		assert.NoError(t, products.Save(ctx, newFakeProduct(sku)))
+	}
+
+	found, err := products.GetBySkus(ctx, []string{"FAL-1000001", "FAL-9999999", "FAL-1000000", "FAL-1000001"})
+
+	assert.NoError(t, err)
+	assert.Len(t, found, 2)
+	assert.Equal(t, "FAL-1000000", found[0].Sku)
+	assert.Equal(t, "FAL-1000001", found[1].Sku)
+}
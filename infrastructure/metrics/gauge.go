@@ -0,0 +1,18 @@
+package metrics
+
+import (
+	"math"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func gaugeValue(read func() (float64, error)) func() float64 {
+	return func() float64 {
+		value, err := read()
+		if err != nil {
+			log.WithError(err).Warnln("error trying to read metric value")
+			return math.NaN()
+		}
+		return value
+	}
+}
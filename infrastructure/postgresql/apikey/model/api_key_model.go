@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+type APIKeyModel struct {
+	ID        string     `gorm:"column:id;primaryKey"`
+	Name      string     `gorm:"column:name;not null"`
+	Prefix    string     `gorm:"column:prefix;not null"`
+	Hash      string     `gorm:"column:hash;not null;uniqueIndex"`
+	Role      string     `gorm:"column:role;not null"`
+	CreatedAt time.Time  `gorm:"column:created_at"`
+	RevokedAt *time.Time `gorm:"column:revoked_at"`
+}
+
+func (a *APIKeyModel) TableName() string {
+	return "api_keys"
+}
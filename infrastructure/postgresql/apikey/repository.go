@@ -0,0 +1,107 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/database"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/apikey/model"
+	"gorm.io/gorm"
+)
+
+type PersistenceAPIKeyRepository struct {
+	Connection database.GenericDatabaseRepository
+}
+
+func NewPersistenceAPIKeyRepository(conn database.GenericDatabaseRepository) repository.APIKeyRepository {
+	return &PersistenceAPIKeyRepository{
+		Connection: conn,
+	}
+}
+
+func (p *PersistenceAPIKeyRepository) Save(ctx context.Context, key entity.APIKey) error {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return err
+	}
+	db = db.WithContext(ctx)
+	result := db.Create(&model.APIKeyModel{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Hash:      key.Hash,
+		Role:      string(key.Role),
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	})
+	return result.Error
+}
+
+func (p *PersistenceAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*entity.APIKey, error) {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+	key := model.APIKeyModel{}
+	result := db.First(&key, "hash = ?", hash)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	entityKey := toEntity(key)
+	return &entityKey, nil
+}
+
+func (p *PersistenceAPIKeyRepository) GetAll(ctx context.Context) ([]entity.APIKey, error) {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+	keys := make([]model.APIKeyModel, 0)
+	result := db.Order("created_at").Find(&keys)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	entityKeys := make([]entity.APIKey, 0, len(keys))
+	for _, key := range keys {
+		entityKeys = append(entityKeys, toEntity(key))
+	}
+	return entityKeys, nil
+}
+
+func (p *PersistenceAPIKeyRepository) Revoke(ctx context.Context, id string, revokedAt time.Time) error {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return err
+	}
+	db = db.WithContext(ctx)
+	result := db.Model(&model.APIKeyModel{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", revokedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func toEntity(key model.APIKeyModel) entity.APIKey {
+	return entity.APIKey{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Hash:      key.Hash,
+		Role:      entity.Role(key.Role),
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
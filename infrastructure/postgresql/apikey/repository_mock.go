@@ -0,0 +1,41 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/yescorihuela/agrak/domain/entity"
+)
+
+type RepositoryMock struct {
+	mock.Mock
+}
+
+func (m *RepositoryMock) Save(ctx context.Context, key entity.APIKey) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *RepositoryMock) GetByHash(ctx context.Context, hash string) (*entity.APIKey, error) {
+	args := m.Called(hash)
+	var mockedKey *entity.APIKey
+	if args.Get(0) != nil {
+		mockedKey = args.Get(0).(*entity.APIKey)
+	}
+	return mockedKey, args.Error(1)
+}
+
+func (m *RepositoryMock) GetAll(ctx context.Context) ([]entity.APIKey, error) {
+	args := m.Called()
+	var mockedKeys []entity.APIKey
+	if args.Get(0) != nil {
+		mockedKeys = args.Get(0).([]entity.APIKey)
+	}
+	return mockedKeys, args.Error(1)
+}
+
+func (m *RepositoryMock) Revoke(ctx context.Context, id string, revokedAt time.Time) error {
+	args := m.Called(id, revokedAt)
+	return args.Error(0)
+}
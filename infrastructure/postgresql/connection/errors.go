@@ -0,0 +1,16 @@
+package connection
+
+import (
+	"errors"
+
+	"github.com/jackc/pgconn"
+)
+
+// uniqueViolation is the SQLSTATE of a duplicate key.
+const uniqueViolation = "23505"
+
+// IsUniqueViolation reports whether err is a PostgreSQL duplicate key error.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
@@ -0,0 +1,20 @@
+package connection
+
+import (
+	"context"
+
+	"github.com/yescorihuela/agrak/infrastructure/database"
+)
+
+// Ping checks the database answers through the connection pool.
+func Ping(ctx context.Context, conn database.GenericDatabaseRepository) error {
+	db, err := conn.GetConnection()
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
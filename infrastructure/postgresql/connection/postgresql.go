@@ -1,19 +1,22 @@
 package connection
 
 import (
-	"os"
-	"strconv"
+	"context"
+	"fmt"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-var connection *gorm.DB
-
 type PostgresqlConnection struct {
 	options *PostgresqlOptions
 	url     string
+	plugins []gorm.Plugin
+	mutex   sync.Mutex
+	db      *gorm.DB
 }
 
 func NewPostgreSQLConnection(opts ...*PostgresqlOptions) *PostgresqlConnection {
@@ -25,28 +28,108 @@ func NewPostgreSQLConnection(opts ...*PostgresqlOptions) *PostgresqlConnection {
 	}
 }
 
+// GetConnection returns the connection pool, opening it on first use. When
+// the database cannot be reached the error is returned and the next call
+// tries again.
 func (p *PostgresqlConnection) GetConnection() (*gorm.DB, error) {
-	var err error
-	if connection == nil {
-		connection, err = gorm.Open(postgres.Open(p.url), &gorm.Config{})
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.db != nil {
+		return p.db, nil
 	}
+
+	db, err := gorm.Open(postgres.Open(p.url), &gorm.Config{})
 	if err != nil {
-		log.WithError(err).Fatalf("error to trying to open connection in DB")
-	} else {
-		_, err := connection.DB()
-		if err != nil {
-			log.WithError(err).Fatalf("error to trying to connect DB")
+		return nil, fmt.Errorf("error trying to open connection in DB: %w", err)
+	}
+	if err := p.configurePool(db); err != nil {
+		return nil, err
+	}
+	for _, plugin := range p.plugins {
+		if err := db.Use(plugin); err != nil {
+			return nil, err
 		}
 	}
-	return connection, nil
+	p.db = db
+	return db, nil
 }
 
-func InitPGClient() *PostgresqlConnection {
-	databaseName := os.Getenv("POSTGRES_DB")
-	host := os.Getenv("POSTGRES_HOST")
-	port, _ := strconv.Atoi(os.Getenv("PGPORT"))
-	user := os.Getenv("POSTGRES_USER")
-	password := os.Getenv("POSTGRES_PASSWORD")
-	connection := NewPostgreSQLConnection(Config().Server(host).Port(port).DatabaseName(databaseName).User(user).Password(password))
-	return connection
+// Connect opens the connection pool, retrying with exponential backoff while
+// the database is unreachable, for instance while it is still starting.
+func (p *PostgresqlConnection) Connect(ctx context.Context) error {
+	retry := *p.options.retry
+	backoff := retry.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		_, err := p.GetConnection()
+		if err == nil {
+			return nil
+		}
+		if attempt > retry.Attempts {
+			return err
+		}
+		log.WithError(err).WithFields(log.Fields{
+			"attempt":  attempt,
+			"retry_in": backoff.String(),
+		}).Warnln("database unavailable, retrying")
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+		if backoff > retry.MaxBackoff {
+			backoff = retry.MaxBackoff
+		}
+	}
+}
+
+// Use installs GORM plugins on the connection, now if it is already open or
+// as soon as it is opened otherwise.
+func (p *PostgresqlConnection) Use(plugins ...gorm.Plugin) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.plugins = append(p.plugins, plugins...)
+	if p.db == nil {
+		return nil
+	}
+	for _, plugin := range plugins {
+		if err := p.db.Use(plugin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close releases the pooled connections. GetConnection opens a new pool if it
+// is called afterwards.
+func (p *PostgresqlConnection) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.db == nil {
+		return nil
+	}
+	sqlDB, err := p.db.DB()
+	if err != nil {
+		return err
+	}
+	p.db = nil
+	return sqlDB.Close()
+}
+
+func (p *PostgresqlConnection) configurePool(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	pool := p.options.pool
+	sqlDB.SetMaxOpenConns(pool.MaxOpenConns)
+	if pool.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	sqlDB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	return nil
 }
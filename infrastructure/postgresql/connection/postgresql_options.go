@@ -3,21 +3,50 @@ package connection
 import (
 	"fmt"
 	"net/url"
+	"strconv"
+	"time"
 )
 
 type SQLDialect string
 
 var (
-	PostgresqlDefaultPort            = 5432
-	Posgres               SQLDialect = "postgres"
+	PostgresqlDefaultPort               = 5432
+	PostgresqlDefaultSSLMode            = "disable"
+	PostgresqlDefaultRetry              = RetryOptions{Attempts: 5, InitialBackoff: 500 * time.Millisecond, MaxBackoff: 10 * time.Second}
+	Posgres                  SQLDialect = "postgres"
 )
 
 type PostgresqlOptions struct {
-	databaseName *string
-	server       *string
-	port         *int
-	user         *string
-	password     *string
+	databaseName     *string
+	server           *string
+	port             *int
+	user             *string
+	password         *string
+	sslMode          *string
+	sslRootCert      *string
+	sslCert          *string
+	sslKey           *string
+	connectTimeout   *time.Duration
+	statementTimeout *time.Duration
+	pool             PoolOptions
+	retry            *RetryOptions
+}
+
+// PoolOptions tunes the database/sql connection pool. Zero values keep the
+// database/sql defaults.
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// RetryOptions bounds the attempts made by PostgresqlConnection.Connect. The
+// wait between attempts doubles from InitialBackoff up to MaxBackoff.
+type RetryOptions struct {
+	Attempts       int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
 }
 
 func Config() *PostgresqlOptions {
@@ -49,6 +78,52 @@ func (p *PostgresqlOptions) Password(password string) *PostgresqlOptions {
 	return p
 }
 
+// SSLMode sets the libpq sslmode, "disable" when not set.
+func (p *PostgresqlOptions) SSLMode(mode string) *PostgresqlOptions {
+	p.sslMode = &mode
+	return p
+}
+
+// SSLRootCert sets the CA certificate used to verify the server with the
+// verify-ca and verify-full modes.
+func (p *PostgresqlOptions) SSLRootCert(path string) *PostgresqlOptions {
+	p.sslRootCert = &path
+	return p
+}
+
+// SSLCert and SSLKey set the client certificate presented to the server.
+func (p *PostgresqlOptions) SSLCert(path string) *PostgresqlOptions {
+	p.sslCert = &path
+	return p
+}
+
+func (p *PostgresqlOptions) SSLKey(path string) *PostgresqlOptions {
+	p.sslKey = &path
+	return p
+}
+
+func (p *PostgresqlOptions) ConnectTimeout(timeout time.Duration) *PostgresqlOptions {
+	p.connectTimeout = &timeout
+	return p
+}
+
+// StatementTimeout makes the server abort statements running longer than
+// timeout. Zero disables the limit.
+func (p *PostgresqlOptions) StatementTimeout(timeout time.Duration) *PostgresqlOptions {
+	p.statementTimeout = &timeout
+	return p
+}
+
+func (p *PostgresqlOptions) Pool(pool PoolOptions) *PostgresqlOptions {
+	p.pool = pool
+	return p
+}
+
+func (p *PostgresqlOptions) Retry(retry RetryOptions) *PostgresqlOptions {
+	p.retry = &retry
+	return p
+}
+
 func MergeOptions(opts ...*PostgresqlOptions) *PostgresqlOptions {
 	option := new(PostgresqlOptions)
 	for _, opt := range opts {
@@ -67,6 +142,33 @@ func MergeOptions(opts ...*PostgresqlOptions) *PostgresqlOptions {
 		if opt.password != nil {
 			option.password = opt.password
 		}
+		if opt.sslMode != nil && *opt.sslMode != "" {
+			option.sslMode = opt.sslMode
+		}
+		if opt.sslRootCert != nil && *opt.sslRootCert != "" {
+			option.sslRootCert = opt.sslRootCert
+		}
+		if opt.sslCert != nil && *opt.sslCert != "" {
+			option.sslCert = opt.sslCert
+		}
+		if opt.sslKey != nil && *opt.sslKey != "" {
+			option.sslKey = opt.sslKey
+		}
+		if opt.connectTimeout != nil {
+			option.connectTimeout = opt.connectTimeout
+		}
+		if opt.statementTimeout != nil {
+			option.statementTimeout = opt.statementTimeout
+		}
+		if opt.pool != (PoolOptions{}) {
+			option.pool = opt.pool
+		}
+		if opt.retry != nil {
+			option.retry = opt.retry
+		}
+	}
+	if option.retry == nil {
+		option.retry = &PostgresqlDefaultRetry
 	}
 	return option
 }
@@ -76,7 +178,27 @@ func (a *PostgresqlOptions) GetURLConnection() string {
 		a.port = &PostgresqlDefaultPort
 	}
 	query := url.Values{}
-	query.Add("sslmode", "disable")
+	if a.sslMode == nil {
+		a.sslMode = &PostgresqlDefaultSSLMode
+	}
+	query.Add("sslmode", *a.sslMode)
+	for key, value := range map[string]*string{
+		"sslrootcert": a.sslRootCert,
+		"sslcert":     a.sslCert,
+		"sslkey":      a.sslKey,
+	} {
+		if value != nil {
+			query.Add(key, *value)
+		}
+	}
+	if a.connectTimeout != nil && *a.connectTimeout > 0 {
+		// libpq takes whole seconds; round up so short timeouts are not disabled.
+		query.Add("connect_timeout", strconv.Itoa(int((*a.connectTimeout+time.Second-1)/time.Second)))
+	}
+	// Unknown parameters are sent by pgx as run-time parameters of the session.
+	if a.statementTimeout != nil && *a.statementTimeout > 0 {
+		query.Add("statement_timeout", strconv.FormatInt(a.statementTimeout.Milliseconds(), 10))
+	}
 	u := &url.URL{
 		Scheme:   "postgres",
 		User:     url.UserPassword(*a.user, *a.password),
@@ -0,0 +1,335 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/yescorihuela/agrak/infrastructure/database"
+	"github.com/yescorihuela/agrak/shared/logging"
+)
+
+// lockID identifies the advisory lock held while migrating, so replicas
+// starting together apply each migration once.
+const lockID int64 = 72409115310
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+var (
+	ErrSchemaBehind   = errors.New("database schema is behind")
+	ErrSchemaAhead    = errors.New("database schema is newer than this build")
+	ErrUnknownVersion = errors.New("unknown migration version")
+
+	fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies the versioned SQL files embedded in this package and
+// records them in the schema_migrations table.
+type Migrator struct {
+	connection database.GenericDatabaseRepository
+	migrations []Migration
+}
+
+func NewMigrator(conn database.GenericDatabaseRepository) (*Migrator, error) {
+	migrations, err := load(embedded)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		connection: conn,
+		migrations: migrations,
+	}, nil
+}
+
+// Latest returns the version of the newest known migration.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down rolls back the last applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if current == 0 {
+			return nil
+		}
+		index := m.indexOf(current)
+		if index < 0 {
+			return fmt.Errorf("%w %d", ErrUnknownVersion, current)
+		}
+		target := 0
+		if index > 0 {
+			target = m.migrations[index-1].Version
+		}
+		return m.migrate(ctx, conn, current, target)
+	})
+}
+
+// To applies or rolls back migrations until version is the current one.
+// Version 0 rolls back every migration.
+func (m *Migrator) To(ctx context.Context, version int) error {
+	if version != 0 && m.indexOf(version) < 0 {
+		return fmt.Errorf("%w %d", ErrUnknownVersion, version)
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		return m.migrate(ctx, conn, current, version)
+	})
+}
+
+// Status lists every known migration and when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	db, err := m.sqlDB()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, createTable); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Check returns ErrSchemaBehind when migrations are pending and
+// ErrSchemaAhead when the database was migrated by a newer build.
+func (m *Migrator) Check(ctx context.Context) error {
+	db, err := m.sqlDB()
+	if err != nil {
+		return err
+	}
+	var current int
+	err = db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSchemaBehind, err)
+	}
+	switch {
+	case current < m.Latest():
+		return fmt.Errorf("%w: at version %d, latest is %d", ErrSchemaBehind, current, m.Latest())
+	case current > m.Latest():
+		return fmt.Errorf("%w: at version %d, latest known is %d", ErrSchemaAhead, current, m.Latest())
+	}
+	return nil
+}
+
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, current, target int) error {
+	for _, step := range plan(m.migrations, current, target) {
+		logger := logging.FromContext(ctx).WithField("version", step.migration.Version).WithField("name", step.migration.Name)
+		if step.up {
+			logger.Infoln("applying migration")
+		} else {
+			logger.Infoln("rolling back migration")
+		}
+		if err := apply(ctx, conn, step); err != nil {
+			return fmt.Errorf("migration %d_%s: %w", step.migration.Version, step.migration.Name, err)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) indexOf(version int) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Migrator) sqlDB() (*sql.DB, error) {
+	db, err := m.connection.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	return db.DB()
+}
+
+// withLock runs fn on a dedicated connection holding the migration advisory
+// lock. Session advisory locks belong to a connection, so fn must not use
+// the pool.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	db, err := m.sqlDB()
+	if err != nil {
+		return err
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("error trying to acquire the migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    bigint PRIMARY KEY,
+    name       text NOT NULL,
+    applied_at timestamptz NOT NULL DEFAULT now()
+)`
+
+func currentVersion(ctx context.Context, conn *sql.Conn) (int, error) {
+	var current int
+	err := conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current)
+	return current, err
+}
+
+type step struct {
+	migration Migration
+	up        bool
+}
+
+// plan returns the migrations to apply, in order, to go from current to
+// target. Migrations are sorted by version.
+func plan(migrations []Migration, current, target int) []step {
+	steps := make([]step, 0)
+	if target >= current {
+		for _, migration := range migrations {
+			if migration.Version > current && migration.Version <= target {
+				steps = append(steps, step{migration: migration, up: true})
+			}
+		}
+		return steps
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if migrations[i].Version <= current && migrations[i].Version > target {
+			steps = append(steps, step{migration: migrations[i], up: false})
+		}
+	}
+	return steps
+}
+
+// apply runs one step and records it in a single transaction, so a failed
+// migration leaves neither the schema nor schema_migrations half changed.
+func apply(ctx context.Context, conn *sql.Conn, s step) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	script, record, args := s.migration.Down, "DELETE FROM schema_migrations WHERE version = $1", []interface{}{s.migration.Version}
+	if s.up {
+		script, record, args = s.migration.Up, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", []interface{}{s.migration.Version, s.migration.Name}
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// load reads the <version>_<name>.<up|down>.sql files under sql/. Every
+// version needs both files.
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %q", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, path.Join("sql", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Version <= 0 || migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs a positive version and both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
@@ -0,0 +1,88 @@
+package migration
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("should load the embedded migrations in order", func(t *testing.T) {
+		migrations, err := load(embedded)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, migrations)
+		for i, migration := range migrations {
+			assert.NotEmpty(t, migration.Up)
+			assert.NotEmpty(t, migration.Down)
+			if i > 0 {
+				assert.Greater(t, migration.Version, migrations[i-1].Version)
+			}
+		}
+	})
+
+	t.Run("should pair up and down files by version", func(t *testing.T) {
+		migrations, err := load(fstest.MapFS{
+			"sql/0002_second.up.sql":   {Data: []byte("CREATE TABLE b ()")},
+			"sql/0002_second.down.sql": {Data: []byte("DROP TABLE b")},
+			"sql/0001_first.up.sql":    {Data: []byte("CREATE TABLE a ()")},
+			"sql/0001_first.down.sql":  {Data: []byte("DROP TABLE a")},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Migration{
+			{Version: 1, Name: "first", Up: "CREATE TABLE a ()", Down: "DROP TABLE a"},
+			{Version: 2, Name: "second", Up: "CREATE TABLE b ()", Down: "DROP TABLE b"},
+		}, migrations)
+	})
+
+	t.Run("should reject a migration without its down file", func(t *testing.T) {
+		_, err := load(fstest.MapFS{
+			"sql/0001_first.up.sql": {Data: []byte("CREATE TABLE a ()")},
+		})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject unexpected file names", func(t *testing.T) {
+		_, err := load(fstest.MapFS{
+			"sql/first.sql": {Data: []byte("CREATE TABLE a ()")},
+		})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestPlan(t *testing.T) {
+	migrations := []Migration{{Version: 1, Name: "a"}, {Version: 2, Name: "b"}, {Version: 3, Name: "c"}}
+	versions := func(steps []step) []int {
+		result := make([]int, 0, len(steps))
+		for _, s := range steps {
+			result = append(result, s.migration.Version)
+		}
+		return result
+	}
+
+	t.Run("should apply pending migrations in ascending order", func(t *testing.T) {
+		steps := plan(migrations, 1, 3)
+
+		assert.Equal(t, []int{2, 3}, versions(steps))
+		for _, s := range steps {
+			assert.True(t, s.up)
+		}
+	})
+
+	t.Run("should roll back in descending order", func(t *testing.T) {
+		steps := plan(migrations, 3, 0)
+
+		assert.Equal(t, []int{3, 2, 1}, versions(steps))
+		for _, s := range steps {
+			assert.False(t, s.up)
+		}
+	})
+
+	t.Run("should do nothing when already at the target", func(t *testing.T) {
+		assert.Empty(t, plan(migrations, 2, 2))
+	})
+}
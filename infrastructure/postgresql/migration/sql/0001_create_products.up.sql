@@ -0,0 +1,13 @@
+-- Matches the table previously created by GORM AutoMigrate, so existing
+-- databases are adopted without changes.
+CREATE TABLE IF NOT EXISTS products (
+    sku             text PRIMARY KEY,
+    name            text NOT NULL,
+    brand           text NOT NULL,
+    size            text DEFAULT 'ST',
+    price           numeric,
+    principal_image text,
+    other_images    text,
+    created_at      timestamptz,
+    updated_at      timestamptz
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id         text PRIMARY KEY,
+    name       text NOT NULL,
+    prefix     text NOT NULL,
+    hash       text NOT NULL,
+    role       text NOT NULL,
+    created_at timestamptz,
+    revoked_at timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_hash ON api_keys (hash);
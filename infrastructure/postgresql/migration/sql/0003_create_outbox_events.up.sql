@@ -0,0 +1,13 @@
+CREATE TABLE outbox_events (
+    id              text PRIMARY KEY,
+    type            text NOT NULL,
+    sku             text NOT NULL,
+    payload         jsonb NOT NULL,
+    occurred_at     timestamptz NOT NULL,
+    attempts        integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL,
+    last_error      text NOT NULL DEFAULT '',
+    dispatched_at   timestamptz
+);
+
+CREATE INDEX idx_outbox_events_pending ON outbox_events (next_attempt_at) WHERE dispatched_at IS NULL;
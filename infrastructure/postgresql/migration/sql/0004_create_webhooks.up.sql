@@ -0,0 +1,26 @@
+CREATE TABLE webhook_subscriptions (
+    id          text PRIMARY KEY,
+    url         text NOT NULL,
+    secret      text NOT NULL,
+    event_types text NOT NULL DEFAULT '',
+    created_at  timestamptz NOT NULL
+);
+
+CREATE TABLE webhook_deliveries (
+    id               text PRIMARY KEY,
+    subscription_id  text NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id         text NOT NULL,
+    event_type       text NOT NULL,
+    payload          jsonb NOT NULL,
+    status           text NOT NULL,
+    attempts         integer NOT NULL DEFAULT 0,
+    next_attempt_at  timestamptz NOT NULL,
+    last_status_code integer NOT NULL DEFAULT 0,
+    last_error       text NOT NULL DEFAULT '',
+    created_at       timestamptz NOT NULL,
+    updated_at       timestamptz NOT NULL,
+    delivered_at     timestamptz
+);
+
+CREATE INDEX idx_webhook_deliveries_pending ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id, created_at DESC);
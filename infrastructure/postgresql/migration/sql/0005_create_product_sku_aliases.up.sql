@@ -0,0 +1,9 @@
+-- Former SKUs of renamed products, kept so references to them still
+-- resolve.
+CREATE TABLE product_sku_aliases (
+    sku         text PRIMARY KEY,
+    product_sku text NOT NULL,
+    created_at  timestamptz NOT NULL
+);
+
+CREATE INDEX idx_product_sku_aliases_product ON product_sku_aliases (product_sku);
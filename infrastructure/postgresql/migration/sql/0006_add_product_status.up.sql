@@ -0,0 +1,5 @@
+-- Products stored before the lifecycle existed were all visible, so they
+-- start as active.
+ALTER TABLE products ADD COLUMN status text NOT NULL DEFAULT 'active';
+
+CREATE INDEX idx_products_status ON products (status);
@@ -0,0 +1,3 @@
+ALTER TABLE products DROP COLUMN IF EXISTS announced_available;
+ALTER TABLE products DROP COLUMN IF EXISTS available_until;
+ALTER TABLE products DROP COLUMN IF EXISTS available_from;
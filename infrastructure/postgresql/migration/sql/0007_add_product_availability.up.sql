@@ -0,0 +1,8 @@
+ALTER TABLE products ADD COLUMN available_from timestamptz;
+ALTER TABLE products ADD COLUMN available_until timestamptz;
+-- The availability last announced by the scheduler. Products stored before
+-- the windows existed are available and need no announcement.
+ALTER TABLE products ADD COLUMN announced_available boolean NOT NULL DEFAULT true;
+
+CREATE INDEX idx_products_available_from ON products (available_from);
+CREATE INDEX idx_products_available_until ON products (available_until);
@@ -0,0 +1,2 @@
+-- Every replica follows the dispatched events to feed its product streams.
+CREATE INDEX idx_outbox_events_dispatched ON outbox_events (dispatched_at) WHERE dispatched_at IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_outbox_events_dispatched;
+CREATE INDEX idx_outbox_events_dispatched ON outbox_events (dispatched_at) WHERE dispatched_at IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Replicas page through the dispatched events by dispatch time and ID.
+DROP INDEX IF EXISTS idx_outbox_events_dispatched;
+CREATE INDEX idx_outbox_events_dispatched ON outbox_events (dispatched_at, id) WHERE dispatched_at IS NOT NULL;
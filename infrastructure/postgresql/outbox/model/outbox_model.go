@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+type OutboxEventModel struct {
+	ID            string     `gorm:"column:id;primaryKey"`
+	Type          string     `gorm:"column:type;not null"`
+	Sku           string     `gorm:"column:sku;not null"`
+	Payload       []byte     `gorm:"column:payload;type:jsonb;not null"`
+	OccurredAt    time.Time  `gorm:"column:occurred_at;not null"`
+	Attempts      int        `gorm:"column:attempts;not null"`
+	NextAttemptAt time.Time  `gorm:"column:next_attempt_at;not null"`
+	LastError     string     `gorm:"column:last_error;not null"`
+	DispatchedAt  *time.Time `gorm:"column:dispatched_at"`
+}
+
+func (o *OutboxEventModel) TableName() string {
+	return "outbox_events"
+}
+
+// EventPayload is the JSON stored in the payload column.
+type EventPayload struct {
+	PreviousSku   string          `json:"previous_sku,omitempty"`
+	ChangedFields []string        `json:"changed_fields,omitempty"`
+	Product       *ProductPayload `json:"product,omitempty"`
+}
+
+type ProductPayload struct {
+	Sku            string     `json:"sku"`
+	Name           string     `json:"name"`
+	Brand          string     `json:"brand"`
+	Size           string     `json:"size"`
+	Price          float64    `json:"price"`
+	PrincipalImage string     `json:"principal_image"`
+	OtherImages    []string   `json:"other_images"`
+	Status         string     `json:"status,omitempty"`
+	AvailableFrom  *time.Time `json:"available_from,omitempty"`
+	AvailableUntil *time.Time `json:"available_until,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
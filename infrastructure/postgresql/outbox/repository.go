@@ -0,0 +1,200 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/database"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/outbox/model"
+	"gorm.io/gorm"
+)
+
+type PersistenceOutboxRepository struct {
+	Connection database.GenericDatabaseRepository
+}
+
+func NewPersistenceOutboxRepository(conn database.GenericDatabaseRepository) repository.OutboxRepository {
+	return &PersistenceOutboxRepository{
+		Connection: conn,
+	}
+}
+
+func (p *PersistenceOutboxRepository) Append(ctx context.Context, events ...entity.ProductEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return err
+	}
+	db = db.WithContext(ctx)
+	models := make([]model.OutboxEventModel, 0, len(events))
+	for _, event := range events {
+		eventModel, err := toModel(event)
+		if err != nil {
+			return err
+		}
+		models = append(models, eventModel)
+	}
+	return db.Create(&models).Error
+}
+
+// Claim leases the due events with SKIP LOCKED, so relays running in several
+// replicas share the outbox without dispatching an event twice at once.
+func (p *PersistenceOutboxRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entity.OutboxEntry, error) {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+	models := make([]model.OutboxEventModel, 0)
+	result := db.Raw(`UPDATE outbox_events SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM outbox_events
+			WHERE dispatched_at IS NULL AND next_attempt_at <= ?
+			ORDER BY occurred_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, now.Add(lease), now, limit).Scan(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].OccurredAt.Before(models[j].OccurredAt)
+	})
+	return toEntries(models)
+}
+
+func (p *PersistenceOutboxRepository) ListDispatched(ctx context.Context, since time.Time, afterID string, limit int) ([]entity.OutboxEntry, error) {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+	models := make([]model.OutboxEventModel, 0)
+	result := db.Where("dispatched_at IS NOT NULL AND (dispatched_at, id) > (?, ?)", since, afterID).Order("dispatched_at, id").Limit(limit).Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return toEntries(models)
+}
+
+func (p *PersistenceOutboxRepository) MarkDispatched(ctx context.Context, id string, dispatchedAt time.Time) error {
+	return p.update(ctx, id, map[string]interface{}{
+		"dispatched_at": dispatchedAt,
+		"last_error":    "",
+	})
+}
+
+func (p *PersistenceOutboxRepository) MarkFailed(ctx context.Context, id string, nextAttemptAt time.Time, reason string) error {
+	return p.update(ctx, id, map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      reason,
+	})
+}
+
+func (p *PersistenceOutboxRepository) update(ctx context.Context, id string, values map[string]interface{}) error {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return err
+	}
+	db = db.WithContext(ctx)
+	result := db.Model(&model.OutboxEventModel{}).Where("id = ?", id).Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrOutboxEventNotFound
+	}
+	return nil
+}
+
+func toModel(event entity.ProductEvent) (model.OutboxEventModel, error) {
+	payload := model.EventPayload{
+		PreviousSku:   event.PreviousSku,
+		ChangedFields: event.ChangedFields,
+	}
+	if event.Product != nil {
+		payload.Product = &model.ProductPayload{
+			Sku:            event.Product.Sku,
+			Name:           event.Product.Name,
+			Brand:          event.Product.Brand,
+			Size:           event.Product.Size,
+			Price:          event.Product.Price,
+			PrincipalImage: event.Product.PrincipalImage,
+			OtherImages:    event.Product.OtherImages,
+			Status:         string(event.Product.Status),
+			AvailableFrom:  event.Product.AvailableFrom,
+			AvailableUntil: event.Product.AvailableUntil,
+			CreatedAt:      event.Product.CreatedAt,
+			UpdatedAt:      event.Product.UpdatedAt,
+		}
+	}
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return model.OutboxEventModel{}, err
+	}
+	return model.OutboxEventModel{
+		ID:            event.ID,
+		Type:          string(event.Type),
+		Sku:           event.Sku,
+		Payload:       content,
+		OccurredAt:    event.OccurredAt,
+		NextAttemptAt: event.OccurredAt,
+	}, nil
+}
+
+func toEntries(models []model.OutboxEventModel) ([]entity.OutboxEntry, error) {
+	entries := make([]entity.OutboxEntry, 0, len(models))
+	for _, eventModel := range models {
+		event, err := toEntity(eventModel)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entity.OutboxEntry{
+			Event:        event,
+			Attempts:     eventModel.Attempts,
+			LastError:    eventModel.LastError,
+			DispatchedAt: eventModel.DispatchedAt,
+		})
+	}
+	return entries, nil
+}
+
+func toEntity(eventModel model.OutboxEventModel) (entity.ProductEvent, error) {
+	payload := model.EventPayload{}
+	if err := json.Unmarshal(eventModel.Payload, &payload); err != nil {
+		return entity.ProductEvent{}, err
+	}
+	event := entity.ProductEvent{
+		ID:            eventModel.ID,
+		Type:          entity.EventType(eventModel.Type),
+		Sku:           eventModel.Sku,
+		PreviousSku:   payload.PreviousSku,
+		ChangedFields: payload.ChangedFields,
+		OccurredAt:    eventModel.OccurredAt,
+	}
+	if payload.Product != nil {
+		event.Product = &entity.Product{
+			Sku:            payload.Product.Sku,
+			Name:           payload.Product.Name,
+			Brand:          payload.Product.Brand,
+			Size:           payload.Product.Size,
+			Price:          payload.Product.Price,
+			PrincipalImage: payload.Product.PrincipalImage,
+			OtherImages:    payload.Product.OtherImages,
+			Status:         entity.ProductStatus(payload.Product.Status),
+			AvailableFrom:  payload.Product.AvailableFrom,
+			AvailableUntil: payload.Product.AvailableUntil,
+			CreatedAt:      payload.Product.CreatedAt,
+			UpdatedAt:      payload.Product.UpdatedAt,
+		}
+	}
+	return event, nil
+}
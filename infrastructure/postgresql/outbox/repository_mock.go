@@ -0,0 +1,38 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/yescorihuela/agrak/domain/entity"
+)
+
+type RepositoryMock struct {
+	mock.Mock
+}
+
+func (m *RepositoryMock) Append(ctx context.Context, events ...entity.ProductEvent) error {
+	args := m.Called(events)
+	return args.Error(0)
+}
+
+func (m *RepositoryMock) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entity.OutboxEntry, error) {
+	args := m.Called(now, lease, limit)
+	return args.Get(0).([]entity.OutboxEntry), args.Error(1)
+}
+
+func (m *RepositoryMock) MarkDispatched(ctx context.Context, id string, dispatchedAt time.Time) error {
+	args := m.Called(id, dispatchedAt)
+	return args.Error(0)
+}
+
+func (m *RepositoryMock) ListDispatched(ctx context.Context, since time.Time, afterID string, limit int) ([]entity.OutboxEntry, error) {
+	args := m.Called(since, afterID, limit)
+	return args.Get(0).([]entity.OutboxEntry), args.Error(1)
+}
+
+func (m *RepositoryMock) MarkFailed(ctx context.Context, id string, nextAttemptAt time.Time, reason string) error {
+	args := m.Called(id, nextAttemptAt, reason)
+	return args.Error(0)
+}
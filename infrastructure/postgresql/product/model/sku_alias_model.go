@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+type SkuAliasModel struct {
+	Sku        string    `gorm:"column:sku;primaryKey"`
+	ProductSku string    `gorm:"column:product_sku;not null"`
+	CreatedAt  time.Time `gorm:"column:created_at"`
+}
+
+func (a *SkuAliasModel) TableName() string {
+	return "product_sku_aliases"
+}
@@ -1,6 +1,8 @@
 package product
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"strings"
 	"time"
@@ -9,8 +11,11 @@ import (
 	"github.com/yescorihuela/agrak/domain/factory"
 	"github.com/yescorihuela/agrak/domain/repository"
 	"github.com/yescorihuela/agrak/infrastructure/database"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/connection"
 	"github.com/yescorihuela/agrak/infrastructure/postgresql/product/model"
 	"github.com/yescorihuela/agrak/shared/common"
+	"github.com/yescorihuela/agrak/shared/logging"
+	"gorm.io/gorm"
 )
 
 type PersistenceProductRepository struct {
@@ -23,47 +28,57 @@ func NewPersistenceProductRepository(conn database.GenericDatabaseRepository) re
 	}
 }
 
-func (p *PersistenceProductRepository) Save(product entity.Product) error {
+func (p *PersistenceProductRepository) Save(ctx context.Context, product entity.Product) error {
 	db, err := p.Connection.GetConnection()
 	if err != nil {
 		return err
 	}
-
-	checkedProduct, _ := p.GetBySku(product.Sku)
-	if checkedProduct != nil {
-		return errors.New("duplicated sku")
-	}
+	db = db.WithContext(ctx)
 
 	isValid, err := product.IsValid()
 
 	if isValid {
+		now := time.Now()
 		err := db.Create(model.ProductModel{
-			Sku:            product.Sku,
-			Name:           product.Name,
-			Brand:          product.Brand,
-			Size:           product.Size,
-			Price:          product.Price,
-			PrincipalImage: product.PrincipalImage,
-			OtherImages:    common.GetStringFromSlicedUrls(product.OtherImages),
-			CreatedAt:      time.Now(),
-			UpdatedAt:      time.Now(),
+			Sku:                product.Sku,
+			Name:               product.Name,
+			Brand:              product.Brand,
+			Size:               product.Size,
+			Price:              product.Price,
+			PrincipalImage:     product.PrincipalImage,
+			OtherImages:        common.GetStringFromSlicedUrls(product.OtherImages),
+			Status:             string(product.Status),
+			AvailableFrom:      product.AvailableFrom,
+			AvailableUntil:     product.AvailableUntil,
+			AnnouncedAvailable: product.IsAvailableAt(now),
+			CreatedAt:          now,
+			UpdatedAt:          now,
 		})
 
-		if err != nil {
+		if connection.IsUniqueViolation(err.Error) {
+			return repository.ErrDuplicatedProduct
+		}
+		if err.Error != nil {
+			logging.FromContext(ctx).WithError(err.Error).WithField("sku", product.Sku).Errorln("error trying to insert product")
 			return err.Error
 		}
 	}
 	return err
 }
 
-func (p *PersistenceProductRepository) GetBySku(sku string) (*entity.Product, error) {
+func (p *PersistenceProductRepository) GetBySku(ctx context.Context, sku string) (*entity.Product, error) {
 	db, err := p.Connection.GetConnection()
 	if err != nil {
 		return nil, err
 	}
+	db = db.WithContext(ctx)
 	product := model.ProductModel{}
 	result := db.First(&product, "sku = ?", sku)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, repository.ErrProductNotFound
+	}
 	if result.Error != nil {
+		logging.FromContext(ctx).WithError(result.Error).WithField("sku", sku).Errorln("error trying to find product")
 		return nil, result.Error
 	}
 	otherImages := common.GetSlicedUrls(product.OtherImages)
@@ -79,24 +94,31 @@ func (p *PersistenceProductRepository) GetBySku(sku string) (*entity.Product, er
 	if err != nil {
 		return nil, err
 	}
+	entityProduct.Status = entity.ProductStatus(product.Status)
+	entityProduct.AvailableFrom = product.AvailableFrom
+	entityProduct.AvailableUntil = product.AvailableUntil
+	entityProduct.CreatedAt = product.CreatedAt
+	entityProduct.UpdatedAt = product.UpdatedAt
 
 	return entityProduct, nil
 }
 
-func (p *PersistenceProductRepository) GetAllProducts() ([]entity.Product, error) {
+func (p *PersistenceProductRepository) GetAllProducts(ctx context.Context) ([]entity.Product, error) {
 	db, err := p.Connection.GetConnection()
 	if err != nil {
 		return nil, err
 	}
+	db = db.WithContext(ctx)
 
 	products := make([]model.ProductModel, 0)
 
 	result := db.Find(&products)
 	if result.Error != nil {
-		return nil, err
+		logging.FromContext(ctx).WithError(result.Error).Errorln("error trying to list products")
+		return nil, result.Error
 	}
 	if result.RowsAffected <= 0 {
-		return nil, errors.New("records not found")
+		return nil, repository.ErrProductNotFound
 	}
 	entityProducts := make([]entity.Product, 0)
 	for _, v := range products {
@@ -108,17 +130,70 @@ func (p *PersistenceProductRepository) GetAllProducts() ([]entity.Product, error
 			Price:          v.Price,
 			PrincipalImage: v.PrincipalImage,
 			OtherImages:    common.GetSlicedUrls(v.OtherImages),
+			Status:         entity.ProductStatus(v.Status),
+			AvailableFrom:  v.AvailableFrom,
+			AvailableUntil: v.AvailableUntil,
+			CreatedAt:      v.CreatedAt,
+			UpdatedAt:      v.UpdatedAt,
 		}
 		entityProducts = append(entityProducts, productFromModel)
 	}
 	return entityProducts, nil
 }
 
-func (p *PersistenceProductRepository) Update(oldSku string, product entity.Product) (*entity.Product, error) {
+func (p *PersistenceProductRepository) GetBySkus(ctx context.Context, skus []string) ([]entity.Product, error) {
+	if len(skus) == 0 {
+		return []entity.Product{}, nil
+	}
 	db, err := p.Connection.GetConnection()
 	if err != nil {
 		return nil, err
 	}
+	db = db.WithContext(ctx)
+
+	products := make([]model.ProductModel, 0, len(skus))
+	result := db.Where("sku IN ?", skus).Order("sku").Find(&products)
+	if result.Error != nil {
+		logging.FromContext(ctx).WithError(result.Error).WithField("skus", len(skus)).Errorln("error trying to find products")
+		return nil, result.Error
+	}
+	return toEntities(products), nil
+}
+
+func (p *PersistenceProductRepository) GetPage(ctx context.Context, filter repository.ProductFilter, afterSku string, limit int) ([]entity.Product, error) {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx).Where("sku > ?", afterSku)
+	if filter.Brand != "" {
+		db = db.Where("LOWER(brand) = LOWER(?)", filter.Brand)
+	}
+	if filter.SkuPrefix != "" {
+		db = db.Where("sku LIKE ?", likePrefix(filter.SkuPrefix))
+	}
+	if filter.Status != "" {
+		db = db.Where("status = ?", string(filter.Status))
+	}
+	if !filter.AvailableAt.IsZero() {
+		db = db.Where(availableAt, filter.AvailableAt, filter.AvailableAt)
+	}
+
+	products := make([]model.ProductModel, 0, limit)
+	result := db.Order("sku").Limit(limit).Find(&products)
+	if result.Error != nil {
+		logging.FromContext(ctx).WithError(result.Error).Errorln("error trying to list products")
+		return nil, result.Error
+	}
+	return toEntities(products), nil
+}
+
+func (p *PersistenceProductRepository) Update(ctx context.Context, oldSku string, product entity.Product) (*entity.Product, error) {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
 	oldProduct := model.ProductModel{
 		Sku: oldSku,
 	}
@@ -132,11 +207,26 @@ func (p *PersistenceProductRepository) Update(oldSku string, product entity.Prod
 		Price:          product.Price,
 		PrincipalImage: product.PrincipalImage,
 		OtherImages:    otherImages,
+		Status:         string(product.Status),
 		UpdatedAt:      time.Now(),
 	}
 
 	result := db.Model(&oldProduct).Updates(newProduct)
+	if connection.IsUniqueViolation(result.Error) {
+		return nil, repository.ErrDuplicatedProduct
+	}
 	if result.Error != nil {
+		logging.FromContext(ctx).WithError(result.Error).WithField("sku", oldSku).Errorln("error trying to update product")
+		return nil, result.Error
+	}
+	// Updates leaves nil fields alone, so the window is written on its own
+	// to clear the bounds the product no longer has.
+	result = db.Model(&model.ProductModel{Sku: product.Sku}).Select("available_from", "available_until").Updates(model.ProductModel{
+		AvailableFrom:  product.AvailableFrom,
+		AvailableUntil: product.AvailableUntil,
+	})
+	if result.Error != nil {
+		logging.FromContext(ctx).WithError(result.Error).WithField("sku", product.Sku).Errorln("error trying to update product availability")
 		return nil, result.Error
 	}
 
@@ -152,17 +242,188 @@ func (p *PersistenceProductRepository) Update(oldSku string, product entity.Prod
 	if err != nil {
 		return nil, err
 	}
+	updatedProduct.Status = product.Status
+	updatedProduct.AvailableFrom = product.AvailableFrom
+	updatedProduct.AvailableUntil = product.AvailableUntil
+	updatedProduct.UpdatedAt = newProduct.UpdatedAt
 	return updatedProduct, nil
 }
 
-func (p *PersistenceProductRepository) Delete(sku string) error {
+// Rename runs several statements, callers should run it in a transaction.
+func (p *PersistenceProductRepository) Rename(ctx context.Context, sku string, newSku string) (*entity.Product, error) {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+	now := time.Now()
+
+	result := db.Model(&model.ProductModel{}).Where("sku = ?", sku).Updates(map[string]interface{}{
+		"sku":        newSku,
+		"updated_at": now,
+	})
+	if connection.IsUniqueViolation(result.Error) {
+		return nil, repository.ErrDuplicatedProduct
+	}
+	if result.Error != nil {
+		logging.FromContext(ctx).WithError(result.Error).WithField("sku", sku).Errorln("error trying to rename product")
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, repository.ErrProductNotFound
+	}
+	if result := db.Delete(&model.SkuAliasModel{}, "sku = ?", newSku); result.Error != nil {
+		logging.FromContext(ctx).WithError(result.Error).WithField("sku", newSku).Errorln("error trying to delete sku alias")
+		return nil, result.Error
+	}
+	result = db.Model(&model.SkuAliasModel{}).Where("product_sku = ?", sku).Update("product_sku", newSku)
+	if result.Error != nil {
+		logging.FromContext(ctx).WithError(result.Error).WithField("sku", sku).Errorln("error trying to move sku aliases")
+		return nil, result.Error
+	}
+	if result := db.Create(&model.SkuAliasModel{Sku: sku, ProductSku: newSku, CreatedAt: now}); result.Error != nil {
+		logging.FromContext(ctx).WithError(result.Error).WithField("sku", sku).Errorln("error trying to insert sku alias")
+		return nil, result.Error
+	}
+	return p.GetBySku(ctx, newSku)
+}
+
+func (p *PersistenceProductRepository) GetAliasTarget(ctx context.Context, sku string) (string, error) {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return "", err
+	}
+	db = db.WithContext(ctx)
+	alias := model.SkuAliasModel{}
+	result := db.First(&alias, "sku = ?", sku)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return "", repository.ErrSkuAliasNotFound
+	}
+	if result.Error != nil {
+		logging.FromContext(ctx).WithError(result.Error).WithField("sku", sku).Errorln("error trying to find sku alias")
+		return "", result.Error
+	}
+	return alias.ProductSku, nil
+}
+
+func (p *PersistenceProductRepository) Delete(ctx context.Context, sku string) error {
 	db, err := p.Connection.GetConnection()
 	if err != nil {
 		return err
 	}
+	db = db.WithContext(ctx)
 	result := db.Delete(&model.ProductModel{}, "sku = ?", sku)
 	if result.Error != nil {
+		logging.FromContext(ctx).WithError(result.Error).WithField("sku", sku).Errorln("error trying to delete product")
+		return result.Error
+	}
+	result = db.Delete(&model.SkuAliasModel{}, "product_sku = ?", sku)
+	if result.Error != nil {
+		logging.FromContext(ctx).WithError(result.Error).WithField("sku", sku).Errorln("error trying to delete sku aliases")
+		return result.Error
+	}
+	return nil
+}
+
+// GetAvailabilityChanges locks the products with FOR UPDATE SKIP LOCKED, so
+// schedulers running in several replicas announce each change once.
+func (p *PersistenceProductRepository) GetAvailabilityChanges(ctx context.Context, t time.Time) ([]entity.Product, error) {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+	products := make([]model.ProductModel, 0)
+	result := db.Raw(`SELECT * FROM products
+		WHERE announced_available <> (`+availableAt+`)
+		ORDER BY sku
+		FOR UPDATE SKIP LOCKED`, t, t).Scan(&products)
+	if result.Error != nil {
+		logging.FromContext(ctx).WithError(result.Error).Errorln("error trying to find availability changes")
+		return nil, result.Error
+	}
+	return toEntities(products), nil
+}
+
+func (p *PersistenceProductRepository) SetAnnouncedAvailability(ctx context.Context, sku string, available bool) error {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
 		return err
 	}
+	db = db.WithContext(ctx)
+	result := db.Model(&model.ProductModel{}).Where("sku = ?", sku).Update("announced_available", available)
+	if result.Error != nil {
+		logging.FromContext(ctx).WithError(result.Error).WithField("sku", sku).Errorln("error trying to record announced availability")
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrProductNotFound
+	}
 	return nil
 }
+
+func (p *PersistenceProductRepository) NextAvailabilityChange(ctx context.Context, t time.Time) (*time.Time, error) {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+	var next sql.NullTime
+	result := db.Raw(`SELECT MIN(boundary) FROM (
+			SELECT MIN(available_from) AS boundary FROM products WHERE available_from > ?
+			UNION ALL
+			SELECT MIN(available_until) FROM products WHERE available_until > ?
+		) boundaries`, t, t).Scan(&next)
+	if result.Error != nil {
+		logging.FromContext(ctx).WithError(result.Error).Errorln("error trying to find the next availability change")
+		return nil, result.Error
+	}
+	if !next.Valid {
+		return nil, nil
+	}
+	return &next.Time, nil
+}
+
+// availableAt matches the products whose window is open at the time bound to
+// both placeholders.
+const availableAt = "(available_from IS NULL OR available_from <= ?) AND (available_until IS NULL OR available_until > ?)"
+
+func toEntities(products []model.ProductModel) []entity.Product {
+	entityProducts := make([]entity.Product, 0, len(products))
+	for _, v := range products {
+		entityProducts = append(entityProducts, entity.Product{
+			Sku:            v.Sku,
+			Name:           v.Name,
+			Brand:          v.Brand,
+			Size:           v.Size,
+			Price:          v.Price,
+			PrincipalImage: v.PrincipalImage,
+			OtherImages:    common.GetSlicedUrls(v.OtherImages),
+			Status:         entity.ProductStatus(v.Status),
+			AvailableFrom:  v.AvailableFrom,
+			AvailableUntil: v.AvailableUntil,
+			CreatedAt:      v.CreatedAt,
+			UpdatedAt:      v.UpdatedAt,
+		})
+	}
+	return entityProducts
+}
+
+// likePrefix escapes the LIKE wildcards of prefix, so it only matches
+// literally.
+func likePrefix(prefix string) string {
+	return likeEscaper.Replace(prefix) + "%"
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// Count returns the number of stored products.
+func Count(conn database.GenericDatabaseRepository) (int64, error) {
+	db, err := conn.GetConnection()
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	result := db.Model(&model.ProductModel{}).Count(&count)
+	return count, result.Error
+}
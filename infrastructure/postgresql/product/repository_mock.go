@@ -1,35 +1,74 @@
 package product
 
 import (
+	"context"
+	"time"
+
 	"github.com/stretchr/testify/mock"
 	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
 )
 
 type RepositoryMock struct {
 	mock.Mock
 }
 
-func (m *RepositoryMock) Save(product entity.Product) error {
+func (m *RepositoryMock) Save(ctx context.Context, product entity.Product) error {
 	args := m.Called(product)
 	return args.Error(0)
 }
 
-func (m *RepositoryMock) GetBySku(sku string) (*entity.Product, error) {
+func (m *RepositoryMock) GetBySku(ctx context.Context, sku string) (*entity.Product, error) {
 	args := m.Called(sku)
 	return args.Get(0).(*entity.Product), args.Error(1)
 }
 
-func (m *RepositoryMock) GetAllProducts() ([]entity.Product, error) {
+func (m *RepositoryMock) GetAllProducts(ctx context.Context) ([]entity.Product, error) {
 	args := m.Called()
 	return args.Get(0).([]entity.Product), args.Error(1)
 }
 
-func (m *RepositoryMock) Update(oldSku string, product entity.Product) (*entity.Product, error) {
+func (m *RepositoryMock) GetBySkus(ctx context.Context, skus []string) ([]entity.Product, error) {
+	args := m.Called(skus)
+	return args.Get(0).([]entity.Product), args.Error(1)
+}
+
+func (m *RepositoryMock) GetPage(ctx context.Context, filter repository.ProductFilter, afterSku string, limit int) ([]entity.Product, error) {
+	args := m.Called(filter, afterSku, limit)
+	return args.Get(0).([]entity.Product), args.Error(1)
+}
+
+func (m *RepositoryMock) Update(ctx context.Context, oldSku string, product entity.Product) (*entity.Product, error) {
 	args := m.Called(oldSku, product)
 	return args.Get(0).(*entity.Product), args.Error(1)
 }
 
-func (m *RepositoryMock) Delete(sku string) error {
+func (m *RepositoryMock) Rename(ctx context.Context, sku string, newSku string) (*entity.Product, error) {
+	args := m.Called(sku, newSku)
+	return args.Get(0).(*entity.Product), args.Error(1)
+}
+
+func (m *RepositoryMock) GetAliasTarget(ctx context.Context, sku string) (string, error) {
 	args := m.Called(sku)
+	return args.String(0), args.Error(1)
+}
+
+func (m *RepositoryMock) Delete(ctx context.Context, sku string) error {
+	args := m.Called(sku)
+	return args.Error(0)
+}
+
+func (m *RepositoryMock) GetAvailabilityChanges(ctx context.Context, t time.Time) ([]entity.Product, error) {
+	args := m.Called(t)
+	return args.Get(0).([]entity.Product), args.Error(1)
+}
+
+func (m *RepositoryMock) SetAnnouncedAvailability(ctx context.Context, sku string, available bool) error {
+	args := m.Called(sku, available)
 	return args.Error(0)
 }
+
+func (m *RepositoryMock) NextAvailabilityChange(ctx context.Context, t time.Time) (*time.Time, error) {
+	args := m.Called(t)
+	return args.Get(0).(*time.Time), args.Error(1)
+}
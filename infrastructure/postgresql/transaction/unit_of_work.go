@@ -0,0 +1,60 @@
+package transaction
+
+import (
+	"context"
+
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/database"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/outbox"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/product"
+	"gorm.io/gorm"
+)
+
+// UnitOfWork runs work in a database transaction with gorm's
+// db.Transaction, handing out repositories bound to it.
+type UnitOfWork struct {
+	connection database.GenericDatabaseRepository
+}
+
+func NewUnitOfWork(conn database.GenericDatabaseRepository) repository.UnitOfWork {
+	return &UnitOfWork{
+		connection: conn,
+	}
+}
+
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, tx repository.Transaction) error) error {
+	db, err := u.connection.GetConnection()
+	if err != nil {
+		return err
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		conn := txConnection{tx: tx}
+		return fn(ctx, &gormTransaction{
+			products: product.NewPersistenceProductRepository(conn),
+			outbox:   outbox.NewPersistenceOutboxRepository(conn),
+		})
+	})
+}
+
+// txConnection lets the repositories, which ask for a connection on every
+// call, run their statements in tx.
+type txConnection struct {
+	tx *gorm.DB
+}
+
+func (c txConnection) GetConnection() (*gorm.DB, error) {
+	return c.tx, nil
+}
+
+type gormTransaction struct {
+	products repository.ProductRepository
+	outbox   repository.OutboxRepository
+}
+
+func (t *gormTransaction) Products() repository.ProductRepository {
+	return t.products
+}
+
+func (t *gormTransaction) Outbox() repository.OutboxRepository {
+	return t.outbox
+}
@@ -0,0 +1,26 @@
+package transaction
+
+import (
+	"context"
+
+	"github.com/yescorihuela/agrak/domain/repository"
+)
+
+// UnitOfWorkMock runs the work directly against the given repositories, so
+// use cases can be tested with repository mocks.
+type UnitOfWorkMock struct {
+	ProductRepository repository.ProductRepository
+	OutboxRepository  repository.OutboxRepository
+}
+
+func (m *UnitOfWorkMock) Do(ctx context.Context, fn func(ctx context.Context, tx repository.Transaction) error) error {
+	return fn(ctx, m)
+}
+
+func (m *UnitOfWorkMock) Products() repository.ProductRepository {
+	return m.ProductRepository
+}
+
+func (m *UnitOfWorkMock) Outbox() repository.OutboxRepository {
+	return m.OutboxRepository
+}
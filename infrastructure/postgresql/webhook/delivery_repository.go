@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/database"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/webhook/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PersistenceDeliveryRepository struct {
+	Connection database.GenericDatabaseRepository
+}
+
+func NewPersistenceDeliveryRepository(conn database.GenericDatabaseRepository) repository.WebhookDeliveryRepository {
+	return &PersistenceDeliveryRepository{
+		Connection: conn,
+	}
+}
+
+func (p *PersistenceDeliveryRepository) Enqueue(ctx context.Context, deliveries ...entity.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return err
+	}
+	db = db.WithContext(ctx)
+	models := make([]model.WebhookDeliveryModel, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		models = append(models, toModel(delivery))
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models).Error
+}
+
+// Claim leases the due deliveries with SKIP LOCKED, so dispatchers running
+// in several replicas never send a delivery twice at once.
+func (p *PersistenceDeliveryRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entity.WebhookDelivery, error) {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+	models := make([]model.WebhookDeliveryModel, 0)
+	result := db.Raw(`UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, now.Add(lease), string(entity.DeliveryPending), now, limit).Scan(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].CreatedAt.Before(models[j].CreatedAt)
+	})
+	return toDeliveries(models), nil
+}
+
+func (p *PersistenceDeliveryRepository) Record(ctx context.Context, delivery entity.WebhookDelivery) error {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return err
+	}
+	db = db.WithContext(ctx)
+	result := db.Model(&model.WebhookDeliveryModel{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"status":           string(delivery.Status),
+		"attempts":         delivery.Attempts,
+		"next_attempt_at":  delivery.NextAttemptAt,
+		"last_status_code": delivery.LastStatusCode,
+		"last_error":       delivery.LastError,
+		"updated_at":       delivery.UpdatedAt,
+		"delivered_at":     delivery.DeliveredAt,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func (p *PersistenceDeliveryRepository) GetByID(ctx context.Context, subscriptionID, id string) (*entity.WebhookDelivery, error) {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+	delivery := model.WebhookDeliveryModel{}
+	result := db.First(&delivery, "id = ? AND subscription_id = ?", id, subscriptionID)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, repository.ErrDeliveryNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	entityDelivery := toDelivery(delivery)
+	return &entityDelivery, nil
+}
+
+func (p *PersistenceDeliveryRepository) GetBySubscription(ctx context.Context, subscriptionID string, status entity.DeliveryStatus, limit int) ([]entity.WebhookDelivery, error) {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx).Where("subscription_id = ?", subscriptionID)
+	if status != "" {
+		db = db.Where("status = ?", string(status))
+	}
+	models := make([]model.WebhookDeliveryModel, 0)
+	if result := db.Order("created_at DESC").Limit(limit).Find(&models); result.Error != nil {
+		return nil, result.Error
+	}
+	return toDeliveries(models), nil
+}
+
+func toModel(delivery entity.WebhookDelivery) model.WebhookDeliveryModel {
+	return model.WebhookDeliveryModel{
+		ID:             delivery.ID,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      string(delivery.EventType),
+		Payload:        delivery.Payload,
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+}
+
+func toDelivery(delivery model.WebhookDeliveryModel) entity.WebhookDelivery {
+	return entity.WebhookDelivery{
+		ID:             delivery.ID,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      entity.EventType(delivery.EventType),
+		Payload:        delivery.Payload,
+		Status:         entity.DeliveryStatus(delivery.Status),
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+}
+
+func toDeliveries(models []model.WebhookDeliveryModel) []entity.WebhookDelivery {
+	deliveries := make([]entity.WebhookDelivery, 0, len(models))
+	for _, delivery := range models {
+		deliveries = append(deliveries, toDelivery(delivery))
+	}
+	return deliveries
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+type WebhookSubscriptionModel struct {
+	ID         string    `gorm:"column:id;primaryKey"`
+	URL        string    `gorm:"column:url;not null"`
+	Secret     string    `gorm:"column:secret;not null"`
+	EventTypes string    `gorm:"column:event_types;not null"`
+	CreatedAt  time.Time `gorm:"column:created_at;not null"`
+}
+
+func (w *WebhookSubscriptionModel) TableName() string {
+	return "webhook_subscriptions"
+}
+
+type WebhookDeliveryModel struct {
+	ID             string     `gorm:"column:id;primaryKey"`
+	SubscriptionID string     `gorm:"column:subscription_id;not null"`
+	EventID        string     `gorm:"column:event_id;not null"`
+	EventType      string     `gorm:"column:event_type;not null"`
+	Payload        []byte     `gorm:"column:payload;type:jsonb;not null"`
+	Status         string     `gorm:"column:status;not null"`
+	Attempts       int        `gorm:"column:attempts;not null"`
+	NextAttemptAt  time.Time  `gorm:"column:next_attempt_at;not null"`
+	LastStatusCode int        `gorm:"column:last_status_code;not null"`
+	LastError      string     `gorm:"column:last_error;not null"`
+	CreatedAt      time.Time  `gorm:"column:created_at;not null"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at;not null"`
+	DeliveredAt    *time.Time `gorm:"column:delivered_at"`
+}
+
+func (w *WebhookDeliveryModel) TableName() string {
+	return "webhook_deliveries"
+}
@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/database"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/webhook/model"
+	"gorm.io/gorm"
+)
+
+type PersistenceWebhookRepository struct {
+	Connection database.GenericDatabaseRepository
+}
+
+func NewPersistenceWebhookRepository(conn database.GenericDatabaseRepository) repository.WebhookRepository {
+	return &PersistenceWebhookRepository{
+		Connection: conn,
+	}
+}
+
+func (p *PersistenceWebhookRepository) Save(ctx context.Context, subscription entity.WebhookSubscription) error {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return err
+	}
+	db = db.WithContext(ctx)
+	eventTypes := make([]string, 0, len(subscription.EventTypes))
+	for _, eventType := range subscription.EventTypes {
+		eventTypes = append(eventTypes, string(eventType))
+	}
+	return db.Create(&model.WebhookSubscriptionModel{
+		ID:         subscription.ID,
+		URL:        subscription.URL,
+		Secret:     subscription.Secret,
+		EventTypes: strings.Join(eventTypes, ","),
+		CreatedAt:  subscription.CreatedAt,
+	}).Error
+}
+
+func (p *PersistenceWebhookRepository) GetByID(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+	subscription := model.WebhookSubscriptionModel{}
+	result := db.First(&subscription, "id = ?", id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, repository.ErrWebhookNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	entitySubscription := toSubscription(subscription)
+	return &entitySubscription, nil
+}
+
+func (p *PersistenceWebhookRepository) GetAll(ctx context.Context) ([]entity.WebhookSubscription, error) {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+	subscriptions := make([]model.WebhookSubscriptionModel, 0)
+	if result := db.Order("created_at").Find(&subscriptions); result.Error != nil {
+		return nil, result.Error
+	}
+	entitySubscriptions := make([]entity.WebhookSubscription, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		entitySubscriptions = append(entitySubscriptions, toSubscription(subscription))
+	}
+	return entitySubscriptions, nil
+}
+
+func (p *PersistenceWebhookRepository) Delete(ctx context.Context, id string) error {
+	db, err := p.Connection.GetConnection()
+	if err != nil {
+		return err
+	}
+	db = db.WithContext(ctx)
+	result := db.Delete(&model.WebhookSubscriptionModel{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrWebhookNotFound
+	}
+	return nil
+}
+
+func toSubscription(subscription model.WebhookSubscriptionModel) entity.WebhookSubscription {
+	eventTypes := make([]entity.EventType, 0)
+	for _, eventType := range strings.Split(subscription.EventTypes, ",") {
+		if eventType != "" {
+			eventTypes = append(eventTypes, entity.EventType(eventType))
+		}
+	}
+	return entity.WebhookSubscription{
+		ID:         subscription.ID,
+		URL:        subscription.URL,
+		Secret:     subscription.Secret,
+		EventTypes: eventTypes,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/yescorihuela/agrak/domain/entity"
+)
+
+type RepositoryMock struct {
+	mock.Mock
+}
+
+func (m *RepositoryMock) Save(ctx context.Context, subscription entity.WebhookSubscription) error {
+	args := m.Called(subscription)
+	return args.Error(0)
+}
+
+func (m *RepositoryMock) GetByID(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	args := m.Called(id)
+	return args.Get(0).(*entity.WebhookSubscription), args.Error(1)
+}
+
+func (m *RepositoryMock) GetAll(ctx context.Context) ([]entity.WebhookSubscription, error) {
+	args := m.Called()
+	return args.Get(0).([]entity.WebhookSubscription), args.Error(1)
+}
+
+func (m *RepositoryMock) Delete(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+type DeliveryRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *DeliveryRepositoryMock) Enqueue(ctx context.Context, deliveries ...entity.WebhookDelivery) error {
+	args := m.Called(deliveries)
+	return args.Error(0)
+}
+
+func (m *DeliveryRepositoryMock) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entity.WebhookDelivery, error) {
+	args := m.Called(now, lease, limit)
+	return args.Get(0).([]entity.WebhookDelivery), args.Error(1)
+}
+
+func (m *DeliveryRepositoryMock) Record(ctx context.Context, delivery entity.WebhookDelivery) error {
+	args := m.Called(delivery)
+	return args.Error(0)
+}
+
+func (m *DeliveryRepositoryMock) GetByID(ctx context.Context, subscriptionID, id string) (*entity.WebhookDelivery, error) {
+	args := m.Called(subscriptionID, id)
+	return args.Get(0).(*entity.WebhookDelivery), args.Error(1)
+}
+
+func (m *DeliveryRepositoryMock) GetBySubscription(ctx context.Context, subscriptionID string, status entity.DeliveryStatus, limit int) ([]entity.WebhookDelivery, error) {
+	args := m.Called(subscriptionID, status, limit)
+	return args.Get(0).([]entity.WebhookDelivery), args.Error(1)
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Limit describes a token bucket: Burst requests can be served at once and
+// the bucket refills at RequestsPerMinute.
+type Limit struct {
+	RequestsPerMinute int
+	Burst             int
+}
+
+func PerMinute(requests, burst int) Limit {
+	if burst <= 0 {
+		burst = requests
+	}
+	return Limit{
+		RequestsPerMinute: requests,
+		Burst:             burst,
+	}
+}
+
+func (l Limit) IsUnlimited() bool {
+	return l.RequestsPerMinute <= 0
+}
+
+func (l Limit) refillInterval() time.Duration {
+	return time.Minute / time.Duration(l.RequestsPerMinute)
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration
+	RetryAfter time.Duration
+}
+
+// Limiter decides whether the request identified by key fits in limit.
+// Implementations must be safe for concurrent use.
+type Limiter interface {
+	Allow(key string, limit Limit) Result
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+func (r Result) ResetSeconds() int {
+	return seconds(r.ResetAfter)
+}
+
+func (r Result) RetryAfterSeconds() int {
+	return seconds(r.RetryAfter)
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+var DefaultIdleTimeout = 10 * time.Minute
+
+type bucket struct {
+	tokens   float64
+	updated  time.Time
+	lastSeen time.Time
+}
+
+// MemoryLimiter keeps one token bucket per key in process memory. It is
+// enough for a single replica; several replicas need a shared Limiter.
+type MemoryLimiter struct {
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	idleTimeout time.Duration
+	lastSweep   time.Time
+	now         func() time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:     make(map[string]*bucket),
+		idleTimeout: DefaultIdleTimeout,
+		now:         time.Now,
+	}
+}
+
+func (m *MemoryLimiter) Allow(key string, limit Limit) Result {
+	if limit.IsUnlimited() {
+		return Result{Allowed: true}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	capacity := float64(limit.Burst)
+	current, ok := m.buckets[key]
+	if !ok {
+		current = &bucket{tokens: capacity, updated: now}
+		m.buckets[key] = current
+	}
+	current.lastSeen = now
+
+	interval := limit.refillInterval()
+	elapsed := now.Sub(current.updated)
+	current.tokens += float64(elapsed) / float64(interval)
+	if current.tokens > capacity {
+		current.tokens = capacity
+	}
+	current.updated = now
+
+	result := Result{Limit: limit.Burst}
+	if current.tokens >= 1 {
+		current.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - current.tokens) * float64(interval))
+	}
+	result.Remaining = int(current.tokens)
+	result.ResetAfter = time.Duration((capacity - current.tokens) * float64(interval))
+	return result
+}
+
+// sweep drops buckets that have not been used for a while, so keys seen
+// once (e.g. scanning clients) don't accumulate forever.
+func (m *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < m.idleTimeout {
+		return
+	}
+	m.lastSweep = now
+	for key, current := range m.buckets {
+		if now.Sub(current.lastSeen) >= m.idleTimeout {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryLimiter_Allow(t *testing.T) {
+	now := time.Date(2022, time.September, 1, 0, 0, 0, 0, time.UTC)
+	newLimiter := func() *MemoryLimiter {
+		limiter := NewMemoryLimiter()
+		limiter.now = func() time.Time { return now }
+		return limiter
+	}
+	limit := PerMinute(60, 2)
+
+	t.Run("should allow the burst and then reject", func(t *testing.T) {
+		limiter := newLimiter()
+
+		first := limiter.Allow("client", limit)
+		second := limiter.Allow("client", limit)
+		third := limiter.Allow("client", limit)
+
+		assert.True(t, first.Allowed)
+		assert.Equal(t, 1, first.Remaining)
+		assert.True(t, second.Allowed)
+		assert.Equal(t, 0, second.Remaining)
+		assert.False(t, third.Allowed)
+		assert.Equal(t, 1, third.RetryAfterSeconds())
+		assert.Equal(t, 2, third.ResetSeconds())
+	})
+
+	t.Run("should refill over time", func(t *testing.T) {
+		limiter := newLimiter()
+		limiter.Allow("client", limit)
+		limiter.Allow("client", limit)
+
+		now = now.Add(time.Second)
+		assert.True(t, limiter.Allow("client", limit).Allowed)
+		assert.False(t, limiter.Allow("client", limit).Allowed)
+	})
+
+	t.Run("should keep keys apart", func(t *testing.T) {
+		limiter := newLimiter()
+		limiter.Allow("client-a", limit)
+		limiter.Allow("client-a", limit)
+
+		assert.False(t, limiter.Allow("client-a", limit).Allowed)
+		assert.True(t, limiter.Allow("client-b", limit).Allowed)
+	})
+
+	t.Run("should drop idle buckets", func(t *testing.T) {
+		limiter := newLimiter()
+		limiter.Allow("client-a", limit)
+
+		now = now.Add(DefaultIdleTimeout)
+		limiter.Allow("client-b", limit)
+
+		assert.Len(t, limiter.buckets, 1)
+	})
+
+	t.Run("should not limit a zero rate", func(t *testing.T) {
+		limiter := newLimiter()
+		for i := 0; i < 10; i++ {
+			assert.True(t, limiter.Allow("client", PerMinute(0, 0)).Allowed)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package response
+
+import (
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+)
+
+type DTOAPIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Role      string     `json:"role"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// DTOIssuedAPIKey is only returned once, when the key is created.
+type DTOIssuedAPIKey struct {
+	DTOAPIKey
+	Key string `json:"key"`
+}
+
+func ConvertFromAPIKeyToResponse(key entity.APIKey) *DTOAPIKey {
+	return &DTOAPIKey{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Role:      string(key.Role),
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
@@ -0,0 +1,102 @@
+package response
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+)
+
+// DTOEvent is the JSON representation of a product event, as sent to
+// webhooks and streamed to clients.
+type DTOEvent struct {
+	ID         string       `json:"id"`
+	Type       string       `json:"type"`
+	OccurredAt time.Time    `json:"occurred_at"`
+	Data       DTOEventData `json:"data"`
+}
+
+type DTOEventData struct {
+	Sku           string      `json:"sku"`
+	PreviousSku   string      `json:"previous_sku,omitempty"`
+	ChangedFields []string    `json:"changed_fields,omitempty"`
+	Product       *DTOProduct `json:"product,omitempty"`
+}
+
+func ConvertFromEventToResponse(event entity.ProductEvent) *DTOEvent {
+	dto := &DTOEvent{
+		ID:         event.ID,
+		Type:       string(event.Type),
+		OccurredAt: event.OccurredAt,
+		Data: DTOEventData{
+			Sku:           event.Sku,
+			PreviousSku:   event.PreviousSku,
+			ChangedFields: event.ChangedFields,
+		},
+	}
+	if event.Product != nil {
+		dto.Data.Product = ConvertFromEntityToResponse(*event.Product)
+	}
+	return dto
+}
+
+type DTOWebhook struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DTOCreatedWebhook is only returned once, when the subscription is created.
+type DTOCreatedWebhook struct {
+	DTOWebhook
+	Secret string `json:"secret"`
+}
+
+type DTOWebhookDelivery struct {
+	ID             string          `json:"id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+}
+
+func ConvertFromWebhookToResponse(subscription entity.WebhookSubscription) *DTOWebhook {
+	eventTypes := make([]string, 0, len(subscription.EventTypes))
+	for _, eventType := range subscription.EventTypes {
+		eventTypes = append(eventTypes, string(eventType))
+	}
+	return &DTOWebhook{
+		ID:         subscription.ID,
+		URL:        subscription.URL,
+		EventTypes: eventTypes,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+func ConvertFromDeliveryToResponse(delivery entity.WebhookDelivery) *DTOWebhookDelivery {
+	dto := &DTOWebhookDelivery{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		EventType:      string(delivery.EventType),
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+		Payload:        json.RawMessage(delivery.Payload),
+	}
+	if delivery.Status == entity.DeliveryPending {
+		dto.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	return dto
+}
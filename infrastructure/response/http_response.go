@@ -1,17 +1,31 @@
 package response
 
 import (
+	"time"
+
 	"github.com/yescorihuela/agrak/domain/entity"
 )
 
 type DTOProduct struct {
-	Sku            string   `json:"sku"`
-	Name           string   `json:"name"`
-	Brand          string   `json:"brand"`
-	Size           string   `json:"size"`
-	Price          float64  `json:"price"`
-	PrincipalImage string   `json:"principal_image"`
-	OtherImages    []string `json:"other_images"`
+	Sku            string     `json:"sku"`
+	Name           string     `json:"name"`
+	Brand          string     `json:"brand"`
+	Size           string     `json:"size"`
+	Price          float64    `json:"price"`
+	PrincipalImage string     `json:"principal_image"`
+	OtherImages    []string   `json:"other_images"`
+	Status         string     `json:"status,omitempty"`
+	AvailableFrom  *time.Time `json:"available_from,omitempty"`
+	AvailableUntil *time.Time `json:"available_until,omitempty"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+}
+
+// DTOProductBatch answers a batch get: the products found, in the order
+// they were asked for, and the SKUs that matched none.
+type DTOProductBatch struct {
+	Products []DTOProduct `json:"products"`
+	Missing  []string     `json:"missing"`
 }
 
 type ErrorResponse struct {
@@ -33,5 +47,17 @@ func ConvertFromEntityToResponse(ep entity.Product) *DTOProduct {
 		Price:          ep.Price,
 		PrincipalImage: ep.PrincipalImage,
 		OtherImages:    ep.OtherImages,
+		Status:         string(ep.Status),
+		AvailableFrom:  ep.AvailableFrom,
+		AvailableUntil: ep.AvailableUntil,
+		CreatedAt:      timeOrNil(ep.CreatedAt),
+		UpdatedAt:      timeOrNil(ep.UpdatedAt),
+	}
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
 	}
+	return &t
 }
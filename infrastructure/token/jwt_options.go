@@ -0,0 +1,68 @@
+package token
+
+type JWTOptions struct {
+	hmacSecret       *string
+	rsaPublicKeyFile *string
+	jwksFile         *string
+	audience         *string
+	issuer           *string
+}
+
+func Config() *JWTOptions {
+	return &JWTOptions{}
+}
+
+// HMACSecret enables HS256 tokens signed with secret.
+func (j *JWTOptions) HMACSecret(secret string) *JWTOptions {
+	j.hmacSecret = &secret
+	return j
+}
+
+// RSAPublicKeyFile enables RS256 tokens verified with a PEM encoded key.
+func (j *JWTOptions) RSAPublicKeyFile(path string) *JWTOptions {
+	j.rsaPublicKeyFile = &path
+	return j
+}
+
+// JWKSFile enables tokens verified with the keys of a local JWKS document,
+// selected by the token kid header.
+func (j *JWTOptions) JWKSFile(path string) *JWTOptions {
+	j.jwksFile = &path
+	return j
+}
+
+func (j *JWTOptions) Audience(audience string) *JWTOptions {
+	j.audience = &audience
+	return j
+}
+
+func (j *JWTOptions) Issuer(issuer string) *JWTOptions {
+	j.issuer = &issuer
+	return j
+}
+
+func MergeOptions(opts ...*JWTOptions) *JWTOptions {
+	option := new(JWTOptions)
+	for _, opt := range opts {
+		if opt.hmacSecret != nil && *opt.hmacSecret != "" {
+			option.hmacSecret = opt.hmacSecret
+		}
+		if opt.rsaPublicKeyFile != nil && *opt.rsaPublicKeyFile != "" {
+			option.rsaPublicKeyFile = opt.rsaPublicKeyFile
+		}
+		if opt.jwksFile != nil && *opt.jwksFile != "" {
+			option.jwksFile = opt.jwksFile
+		}
+		if opt.audience != nil && *opt.audience != "" {
+			option.audience = opt.audience
+		}
+		if opt.issuer != nil && *opt.issuer != "" {
+			option.issuer = opt.issuer
+		}
+	}
+	return option
+}
+
+func (j *JWTOptions) IsEnabled() bool {
+	return j.hmacSecret != nil || j.rsaPublicKeyFile != nil || j.jwksFile != nil
+}
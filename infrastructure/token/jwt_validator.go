@@ -0,0 +1,139 @@
+package token
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+var ErrNoVerificationKeys = errors.New("no jwt verification keys configured")
+
+// JWTValidator verifies HS256 and RS256 bearer tokens against keys configured
+// locally, so no request ever waits on the identity provider.
+type JWTValidator struct {
+	hmacSecret []byte
+	rsaKey     *rsa.PublicKey
+	keys       *keySet
+	audience   string
+	issuer     string
+	parser     *jwt.Parser
+}
+
+func NewJWTValidator(opts ...*JWTOptions) (*JWTValidator, error) {
+	jwtOptions := MergeOptions(opts...)
+	if !jwtOptions.IsEnabled() {
+		return nil, ErrNoVerificationKeys
+	}
+
+	validator := &JWTValidator{
+		parser: jwt.NewParser(jwt.WithValidMethods([]string{
+			jwt.SigningMethodHS256.Alg(),
+			jwt.SigningMethodRS256.Alg(),
+		})),
+	}
+	if jwtOptions.hmacSecret != nil {
+		validator.hmacSecret = []byte(*jwtOptions.hmacSecret)
+	}
+	if jwtOptions.rsaPublicKeyFile != nil {
+		content, err := os.ReadFile(*jwtOptions.rsaPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		validator.rsaKey, err = jwt.ParseRSAPublicKeyFromPEM(content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rsa public key %s: %w", *jwtOptions.rsaPublicKeyFile, err)
+		}
+	}
+	if jwtOptions.jwksFile != nil {
+		keys, err := loadJWKSFile(*jwtOptions.jwksFile)
+		if err != nil {
+			return nil, err
+		}
+		validator.keys = keys
+	}
+	if jwtOptions.audience != nil {
+		validator.audience = *jwtOptions.audience
+	}
+	if jwtOptions.issuer != nil {
+		validator.issuer = *jwtOptions.issuer
+	}
+	return validator, nil
+}
+
+func (v *JWTValidator) Validate(rawToken string) (*entity.Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(rawToken, claims, v.keyFunc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", usecase.ErrInvalidToken, err)
+	}
+
+	// MapClaims.Valid checks exp and nbf only when present, but tokens
+	// without an expiration are never accepted.
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: token has no expiration", usecase.ErrInvalidToken)
+	}
+	if v.audience != "" && !claims.VerifyAudience(v.audience, true) {
+		return nil, fmt.Errorf("%w: unexpected audience", usecase.ErrInvalidToken)
+	}
+	if v.issuer != "" && !claims.VerifyIssuer(v.issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer", usecase.ErrInvalidToken)
+	}
+
+	subject, _ := claims["sub"].(string)
+	return &entity.Principal{
+		Subject: subject,
+		Method:  entity.AuthMethodJWT,
+		Scopes:  scopesFromClaims(claims),
+	}, nil
+}
+
+func (v *JWTValidator) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		if v.keys != nil {
+			if secret, ok := v.keys.hmacKeys[kid]; ok {
+				return secret, nil
+			}
+		}
+		if v.hmacSecret != nil {
+			return v.hmacSecret, nil
+		}
+	case jwt.SigningMethodRS256.Alg():
+		if v.keys != nil {
+			if key, ok := v.keys.rsaKeys[kid]; ok {
+				return key, nil
+			}
+		}
+		if v.rsaKey != nil {
+			return v.rsaKey, nil
+		}
+	}
+	return nil, fmt.Errorf("no %s key found for kid %q", token.Method.Alg(), kid)
+}
+
+// scopesFromClaims accepts both the space separated "scope" claim of
+// RFC 8693 and the "scp" array used by some providers.
+func scopesFromClaims(claims jwt.MapClaims) []string {
+	scopes := make([]string, 0)
+	if scope, ok := claims["scope"].(string); ok {
+		scopes = append(scopes, strings.Fields(scope)...)
+	}
+	switch scp := claims["scp"].(type) {
+	case string:
+		scopes = append(scopes, strings.Fields(scp)...)
+	case []interface{}:
+		for _, value := range scp {
+			if scope, ok := value.(string); ok {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return scopes
+}
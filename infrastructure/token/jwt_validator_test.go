@@ -0,0 +1,128 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   "checkout-service",
+		"aud":   "products-api",
+		"iss":   "https://gateway.example.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nbf":   time.Now().Add(-time.Minute).Unix(),
+		"scope": "products:read products:write",
+	}
+}
+
+func signHS256(t *testing.T, secret string, claims jwt.MapClaims) string {
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	assert.NoError(t, err)
+	return signed
+}
+
+func TestJWTValidator_HS256(t *testing.T) {
+	secret := "gateway-shared-secret"
+	validator, err := NewJWTValidator(Config().
+		HMACSecret(secret).
+		Audience("products-api").
+		Issuer("https://gateway.example.com"))
+	assert.NoError(t, err)
+
+	t.Run("should accept a valid token", func(t *testing.T) {
+		principal, err := validator.Validate(signHS256(t, secret, validClaims()))
+		assert.NoError(t, err)
+		assert.Equal(t, &entity.Principal{
+			Subject: "checkout-service",
+			Method:  entity.AuthMethodJWT,
+			Scopes:  []string{entity.ScopeProductsRead, entity.ScopeProductsWrite},
+		}, principal)
+	})
+
+	tests := []struct {
+		description string
+		secret      string
+		mutate      func(jwt.MapClaims)
+	}{
+		{"should reject a wrong signature", "another-secret", func(jwt.MapClaims) {}},
+		{"should reject an expired token", secret, func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{"should reject a token without expiration", secret, func(c jwt.MapClaims) { delete(c, "exp") }},
+		{"should reject a token not valid yet", secret, func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(time.Hour).Unix() }},
+		{"should reject another audience", secret, func(c jwt.MapClaims) { c["aud"] = "billing-api" }},
+		{"should reject another issuer", secret, func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			claims := validClaims()
+			tt.mutate(claims)
+			_, err := validator.Validate(signHS256(t, tt.secret, claims))
+			assert.ErrorIs(t, err, usecase.ErrInvalidToken)
+		})
+	}
+
+	t.Run("should reject unsigned tokens", func(t *testing.T) {
+		unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		assert.NoError(t, err)
+		_, err = validator.Validate(unsigned)
+		assert.ErrorIs(t, err, usecase.ErrInvalidToken)
+	})
+}
+
+func TestJWTValidator_RS256WithJWKS(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	jwks, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "gateway-2022",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+		}},
+	})
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(jwksFile, jwks, 0o600))
+
+	validator, err := NewJWTValidator(Config().JWKSFile(jwksFile))
+	assert.NoError(t, err)
+
+	sign := func(kid string) string {
+		claims := validClaims()
+		delete(claims, "scope")
+		claims["scp"] = []string{entity.ScopeProductsRead}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(privateKey)
+		assert.NoError(t, err)
+		return signed
+	}
+
+	t.Run("should accept a token signed by a known kid", func(t *testing.T) {
+		principal, err := validator.Validate(sign("gateway-2022"))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{entity.ScopeProductsRead}, principal.Scopes)
+	})
+
+	t.Run("should reject an unknown kid", func(t *testing.T) {
+		_, err := validator.Validate(sign("gateway-2021"))
+		assert.ErrorIs(t, err, usecase.ErrInvalidToken)
+	})
+}
+
+func TestNewJWTValidator(t *testing.T) {
+	_, err := NewJWTValidator(Config().Audience("products-api"))
+	assert.ErrorIs(t, err, ErrNoVerificationKeys)
+}
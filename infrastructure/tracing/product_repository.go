@@ -0,0 +1,120 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracedProductRepository wraps a repository.ProductRepository with one span
+// per port call. SQL statements run below it get their own spans from
+// GormPlugin.
+type TracedProductRepository struct {
+	repository repository.ProductRepository
+	tracer     trace.Tracer
+}
+
+func NewTracedProductRepository(repository repository.ProductRepository) repository.ProductRepository {
+	return &TracedProductRepository{
+		repository: repository,
+		tracer:     Tracer(),
+	}
+}
+
+func (t *TracedProductRepository) Save(ctx context.Context, product entity.Product) (err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductRepository.Save", trace.WithAttributes(skuKey.String(product.Sku)))
+	defer func() { End(span, err) }()
+	return t.repository.Save(ctx, product)
+}
+
+func (t *TracedProductRepository) Update(ctx context.Context, oldSku string, product entity.Product) (updatedProduct *entity.Product, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductRepository.Update", trace.WithAttributes(skuKey.String(oldSku)))
+	defer func() { End(span, err) }()
+	return t.repository.Update(ctx, oldSku, product)
+}
+
+func (t *TracedProductRepository) GetBySku(ctx context.Context, sku string) (product *entity.Product, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductRepository.GetBySku", trace.WithAttributes(skuKey.String(sku)))
+	defer func() { End(span, ignoreNotFound(err)) }()
+	return t.repository.GetBySku(ctx, sku)
+}
+
+func (t *TracedProductRepository) GetAllProducts(ctx context.Context) (products []entity.Product, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductRepository.GetAllProducts")
+	defer func() {
+		span.SetAttributes(attribute.Int("product.count", len(products)))
+		End(span, err)
+	}()
+	return t.repository.GetAllProducts(ctx)
+}
+
+func (t *TracedProductRepository) GetBySkus(ctx context.Context, skus []string) (products []entity.Product, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductRepository.GetBySkus", trace.WithAttributes(attribute.Int("product.requested", len(skus))))
+	defer func() {
+		span.SetAttributes(attribute.Int("product.count", len(products)))
+		End(span, err)
+	}()
+	return t.repository.GetBySkus(ctx, skus)
+}
+
+func (t *TracedProductRepository) GetPage(ctx context.Context, filter repository.ProductFilter, afterSku string, limit int) (products []entity.Product, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductRepository.GetPage")
+	defer func() {
+		span.SetAttributes(attribute.Int("product.count", len(products)))
+		End(span, err)
+	}()
+	return t.repository.GetPage(ctx, filter, afterSku, limit)
+}
+
+func (t *TracedProductRepository) Rename(ctx context.Context, sku string, newSku string) (renamedProduct *entity.Product, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductRepository.Rename", trace.WithAttributes(skuKey.String(sku), newSkuKey.String(newSku)))
+	defer func() { End(span, err) }()
+	return t.repository.Rename(ctx, sku, newSku)
+}
+
+func (t *TracedProductRepository) GetAliasTarget(ctx context.Context, sku string) (target string, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductRepository.GetAliasTarget", trace.WithAttributes(skuKey.String(sku)))
+	defer func() { End(span, ignoreNotFound(err)) }()
+	return t.repository.GetAliasTarget(ctx, sku)
+}
+
+func (t *TracedProductRepository) Delete(ctx context.Context, sku string) (err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductRepository.Delete", trace.WithAttributes(skuKey.String(sku)))
+	defer func() { End(span, err) }()
+	return t.repository.Delete(ctx, sku)
+}
+
+func (t *TracedProductRepository) GetAvailabilityChanges(ctx context.Context, at time.Time) (products []entity.Product, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductRepository.GetAvailabilityChanges")
+	defer func() {
+		span.SetAttributes(attribute.Int("product.count", len(products)))
+		End(span, err)
+	}()
+	return t.repository.GetAvailabilityChanges(ctx, at)
+}
+
+func (t *TracedProductRepository) SetAnnouncedAvailability(ctx context.Context, sku string, available bool) (err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductRepository.SetAnnouncedAvailability", trace.WithAttributes(skuKey.String(sku), attribute.Bool("product.available", available)))
+	defer func() { End(span, err) }()
+	return t.repository.SetAnnouncedAvailability(ctx, sku, available)
+}
+
+func (t *TracedProductRepository) NextAvailabilityChange(ctx context.Context, at time.Time) (next *time.Time, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductRepository.NextAvailabilityChange")
+	defer func() { End(span, err) }()
+	return t.repository.NextAvailabilityChange(ctx, at)
+}
+
+// ignoreNotFound keeps lookups of unknown SKUs, an expected outcome, from
+// being reported as failed spans.
+func ignoreNotFound(err error) error {
+	if errors.Is(err, repository.ErrProductNotFound) || errors.Is(err, repository.ErrSkuAliasNotFound) {
+		return nil
+	}
+	return err
+}
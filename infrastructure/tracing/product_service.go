@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/usecase"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	skuKey    = attribute.Key("product.sku")
+	newSkuKey = attribute.Key("product.new_sku")
+	statusKey = attribute.Key("product.status")
+)
+
+// TracedService wraps a usecase.Service with one span per use case.
+type TracedService struct {
+	service usecase.Service
+	tracer  trace.Tracer
+}
+
+func NewTracedService(service usecase.Service) usecase.Service {
+	return &TracedService{
+		service: service,
+		tracer:  Tracer(),
+	}
+}
+
+func (t *TracedService) CreateProduct(ctx context.Context, product entity.Product) (createdProduct *entity.Product, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductService.CreateProduct", trace.WithAttributes(skuKey.String(product.Sku)))
+	defer func() { End(span, err) }()
+	return t.service.CreateProduct(ctx, product)
+}
+
+func (t *TracedService) FindBySku(ctx context.Context, sku string) (product *entity.Product, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductService.FindBySku", trace.WithAttributes(skuKey.String(sku)))
+	defer func() { End(span, ignoreNotFound(err)) }()
+	return t.service.FindBySku(ctx, sku)
+}
+
+func (t *TracedService) FindAll(ctx context.Context) (products []entity.Product, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductService.FindAll")
+	defer func() {
+		span.SetAttributes(attribute.Int("product.count", len(products)))
+		End(span, err)
+	}()
+	return t.service.FindAll(ctx)
+}
+
+func (t *TracedService) FindBySkus(ctx context.Context, skus []string) (products []entity.Product, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductService.FindBySkus", trace.WithAttributes(attribute.Int("product.requested", len(skus))))
+	defer func() {
+		span.SetAttributes(attribute.Int("product.count", len(products)))
+		End(span, err)
+	}()
+	return t.service.FindBySkus(ctx, skus)
+}
+
+func (t *TracedService) FindPage(ctx context.Context, filter repository.ProductFilter, afterSku string, limit int) (products []entity.Product, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductService.FindPage")
+	defer func() {
+		span.SetAttributes(attribute.Int("product.count", len(products)))
+		End(span, err)
+	}()
+	return t.service.FindPage(ctx, filter, afterSku, limit)
+}
+
+func (t *TracedService) UpdateProduct(ctx context.Context, oldSku string, product entity.Product) (updatedProduct *entity.Product, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductService.UpdateProduct", trace.WithAttributes(skuKey.String(oldSku)))
+	defer func() { End(span, err) }()
+	return t.service.UpdateProduct(ctx, oldSku, product)
+}
+
+func (t *TracedService) ChangeStatus(ctx context.Context, sku string, status entity.ProductStatus, principal *entity.Principal) (updatedProduct *entity.Product, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductService.ChangeStatus", trace.WithAttributes(skuKey.String(sku), statusKey.String(string(status))))
+	defer func() { End(span, err) }()
+	return t.service.ChangeStatus(ctx, sku, status, principal)
+}
+
+func (t *TracedService) RestoreProduct(ctx context.Context, product entity.Product, principal *entity.Principal) (created bool, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductService.RestoreProduct", trace.WithAttributes(skuKey.String(product.Sku), statusKey.String(string(product.Status))))
+	defer func() { End(span, err) }()
+	return t.service.RestoreProduct(ctx, product, principal)
+}
+
+func (t *TracedService) RenameProduct(ctx context.Context, sku string, newSku string) (renamedProduct *entity.Product, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductService.RenameProduct", trace.WithAttributes(skuKey.String(sku), newSkuKey.String(newSku)))
+	defer func() { End(span, err) }()
+	return t.service.RenameProduct(ctx, sku, newSku)
+}
+
+func (t *TracedService) ResolveAlias(ctx context.Context, sku string) (target string, err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductService.ResolveAlias", trace.WithAttributes(skuKey.String(sku)))
+	defer func() { End(span, ignoreNotFound(err)) }()
+	return t.service.ResolveAlias(ctx, sku)
+}
+
+func (t *TracedService) DeleteProduct(ctx context.Context, sku string) (err error) {
+	ctx, span := t.tracer.Start(ctx, "ProductService.DeleteProduct", trace.WithAttributes(skuKey.String(sku)))
+	defer func() { End(span, err) }()
+	return t.service.DeleteProduct(ctx, sku)
+}
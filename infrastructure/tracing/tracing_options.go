@@ -0,0 +1,61 @@
+package tracing
+
+type Exporter string
+
+var (
+	ExporterNone   Exporter = "none"
+	ExporterStdout Exporter = "stdout"
+	ExporterOTLP   Exporter = "otlp"
+
+	DefaultServiceName = "products-api"
+	DefaultSampleRatio = 1.0
+)
+
+type TracingOptions struct {
+	exporter    *Exporter
+	serviceName *string
+	sampleRatio *float64
+}
+
+func Config() *TracingOptions {
+	return &TracingOptions{}
+}
+
+// Exporter selects where spans are sent. The OTLP exporter is configured
+// through the standard OTEL_EXPORTER_OTLP_* environment variables.
+func (t *TracingOptions) Exporter(exporter Exporter) *TracingOptions {
+	t.exporter = &exporter
+	return t
+}
+
+func (t *TracingOptions) ServiceName(name string) *TracingOptions {
+	t.serviceName = &name
+	return t
+}
+
+// SampleRatio sets the fraction of new traces that are recorded. Traces
+// started upstream keep the sampling decision of their parent.
+func (t *TracingOptions) SampleRatio(ratio float64) *TracingOptions {
+	t.sampleRatio = &ratio
+	return t
+}
+
+func MergeOptions(opts ...*TracingOptions) *TracingOptions {
+	option := &TracingOptions{
+		exporter:    &ExporterNone,
+		serviceName: &DefaultServiceName,
+		sampleRatio: &DefaultSampleRatio,
+	}
+	for _, opt := range opts {
+		if opt.exporter != nil && *opt.exporter != "" {
+			option.exporter = opt.exporter
+		}
+		if opt.serviceName != nil && *opt.serviceName != "" {
+			option.serviceName = opt.serviceName
+		}
+		if opt.sampleRatio != nil {
+			option.sampleRatio = opt.sampleRatio
+		}
+	}
+	return option
+}
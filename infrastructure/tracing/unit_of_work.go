@@ -0,0 +1,40 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/yescorihuela/agrak/domain/repository"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracedUnitOfWork wraps a repository.UnitOfWork with one span per
+// transaction, and the product repository of each transaction with a
+// TracedProductRepository, so calls made in transactions are traced like
+// the others.
+type TracedUnitOfWork struct {
+	unitOfWork repository.UnitOfWork
+	tracer     trace.Tracer
+}
+
+func NewTracedUnitOfWork(unitOfWork repository.UnitOfWork) repository.UnitOfWork {
+	return &TracedUnitOfWork{
+		unitOfWork: unitOfWork,
+		tracer:     Tracer(),
+	}
+}
+
+func (t *TracedUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, tx repository.Transaction) error) (err error) {
+	ctx, span := t.tracer.Start(ctx, "UnitOfWork.Do")
+	defer func() { End(span, err) }()
+	return t.unitOfWork.Do(ctx, func(ctx context.Context, tx repository.Transaction) error {
+		return fn(ctx, &tracedTransaction{Transaction: tx})
+	})
+}
+
+type tracedTransaction struct {
+	repository.Transaction
+}
+
+func (t *tracedTransaction) Products() repository.ProductRepository {
+	return NewTracedProductRepository(t.Transaction.Products())
+}
@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/response"
+	"github.com/yescorihuela/agrak/usecase"
+)
+
+// Publisher queues a delivery of each public product event for every
+// subscription that accepts it, so partners are never told about products
+// that are not published. Deliveries are sent by usecase.WebhookDispatcher.
+type Publisher struct {
+	subscriptions repository.WebhookRepository
+	deliveries    repository.WebhookDeliveryRepository
+	now           func() time.Time
+}
+
+func NewPublisher(subscriptions repository.WebhookRepository, deliveries repository.WebhookDeliveryRepository) usecase.EventPublisher {
+	return &Publisher{
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+		now:           time.Now,
+	}
+}
+
+func (p *Publisher) Name() string {
+	return "webhooks"
+}
+
+func (p *Publisher) Publish(ctx context.Context, event entity.ProductEvent) error {
+	if !event.IsPublic() {
+		return nil
+	}
+	subscriptions, err := p.subscriptions.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(response.ConvertFromEventToResponse(event))
+	if err != nil {
+		return err
+	}
+	now := p.now()
+	deliveries := make([]entity.WebhookDelivery, 0)
+	for _, subscription := range subscriptions {
+		if !subscription.Accepts(event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, entity.WebhookDelivery{
+			ID:             deliveryID(subscription.ID, event.ID),
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        payload,
+			Status:         entity.DeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+	return p.deliveries.Enqueue(ctx, deliveries...)
+}
+
+// deliveryID derives the ID from the subscription and the event, so an event
+// published again maps to the delivery already queued.
+func deliveryID(subscriptionID, eventID string) string {
+	sum := sha256.Sum256([]byte(subscriptionID + "/" + eventID))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/webhook"
+)
+
+func TestPublisher_Publish(t *testing.T) {
+	now := time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)
+	event := entity.ProductEvent{
+		ID:            "e1",
+		Type:          entity.EventProductUpdated,
+		Sku:           "FAL-1000000",
+		ChangedFields: []string{"price"},
+		Product:       &entity.Product{Sku: "FAL-1000000", Name: "Bike", Price: 100, Status: entity.StatusActive},
+		OccurredAt:    now,
+	}
+
+	subscriptionsMock := new(webhook.RepositoryMock)
+	subscriptionsMock.On("GetAll").Return([]entity.WebhookSubscription{
+		{ID: "all"},
+		{ID: "updates", EventTypes: []entity.EventType{entity.EventProductUpdated}},
+		{ID: "deletes", EventTypes: []entity.EventType{entity.EventProductDeleted}},
+	}, nil)
+	var enqueued []entity.WebhookDelivery
+	deliveriesMock := new(webhook.DeliveryRepositoryMock)
+	deliveriesMock.On("Enqueue", mock.Anything).Run(func(args mock.Arguments) {
+		enqueued = args.Get(0).([]entity.WebhookDelivery)
+	}).Return(nil)
+	publisher := NewPublisher(subscriptionsMock, deliveriesMock).(*Publisher)
+	publisher.now = func() time.Time { return now }
+
+	err := publisher.Publish(context.Background(), event)
+
+	assert.NoError(t, err)
+	if assert.Len(t, enqueued, 2) {
+		assert.Equal(t, "all", enqueued[0].SubscriptionID)
+		assert.Equal(t, "updates", enqueued[1].SubscriptionID)
+		assert.NotEqual(t, enqueued[0].ID, enqueued[1].ID)
+		assert.Equal(t, deliveryID("all", "e1"), enqueued[0].ID, "the ID is stable across publications")
+		assert.Equal(t, entity.DeliveryPending, enqueued[0].Status)
+		assert.Equal(t, now, enqueued[0].NextAttemptAt)
+
+		var payload map[string]interface{}
+		assert.NoError(t, json.Unmarshal(enqueued[0].Payload, &payload))
+		assert.Equal(t, "e1", payload["id"])
+		assert.Equal(t, "product.updated", payload["type"])
+		assert.Equal(t, "FAL-1000000", payload["data"].(map[string]interface{})["sku"])
+	}
+}
+
+func TestPublisher_PublishDraft(t *testing.T) {
+	subscriptionsMock := new(webhook.RepositoryMock)
+	deliveriesMock := new(webhook.DeliveryRepositoryMock)
+	publisher := NewPublisher(subscriptionsMock, deliveriesMock)
+
+	for _, eventType := range []entity.EventType{entity.EventProductCreated, entity.EventProductAvailable, entity.EventProductUnavailable} {
+		err := publisher.Publish(context.Background(), entity.ProductEvent{
+			ID:      "e1",
+			Type:    eventType,
+			Sku:     "FAL-1000000",
+			Product: &entity.Product{Sku: "FAL-1000000", Name: "Bike", Price: 100, Status: entity.StatusDraft},
+		})
+		assert.NoError(t, err)
+	}
+
+	subscriptionsMock.AssertNotCalled(t, "GetAll")
+	deliveriesMock.AssertNotCalled(t, "Enqueue", mock.Anything)
+}
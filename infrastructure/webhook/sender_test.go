@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yescorihuela/agrak/domain/entity"
+)
+
+func TestHTTPSender_Send(t *testing.T) {
+	subscription := entity.WebhookSubscription{ID: "w1", Secret: "s3cret"}
+	delivery := entity.WebhookDelivery{ID: "d1", EventType: entity.EventProductCreated, Payload: []byte(`{"id":"e1"}`)}
+	now := time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("should post a signed payload", func(t *testing.T) {
+		var received *http.Request
+		var body []byte
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer receiver.Close()
+		subscription.URL = receiver.URL
+		sender := NewHTTPSender(time.Second).(*HTTPSender)
+		sender.now = func() time.Time { return now }
+
+		statusCode, err := sender.Send(context.Background(), subscription, delivery)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, statusCode)
+		assert.Equal(t, delivery.Payload, body)
+		assert.Equal(t, "1662033600", received.Header.Get(HeaderTimestamp))
+		assert.Equal(t, "sha256="+Sign("s3cret", "1662033600", body), received.Header.Get(HeaderSignature))
+		assert.Equal(t, "product.created", received.Header.Get(HeaderEvent))
+		assert.Equal(t, "d1", received.Header.Get(HeaderDelivery))
+		assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+	})
+
+	t.Run("should fail on non 2xx responses", func(t *testing.T) {
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+		}))
+		defer receiver.Close()
+		subscription.URL = receiver.URL
+
+		statusCode, err := NewHTTPSender(time.Second).Send(context.Background(), subscription, delivery)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "try later")
+		assert.Equal(t, http.StatusServiceUnavailable, statusCode)
+	})
+
+	t.Run("should not follow redirects", func(t *testing.T) {
+		receiver := httptest.NewServer(http.RedirectHandler("https://example.com", http.StatusFound))
+		defer receiver.Close()
+		subscription.URL = receiver.URL
+
+		statusCode, err := NewHTTPSender(time.Second).Send(context.Background(), subscription, delivery)
+
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusFound, statusCode)
+	})
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1662033600.{}' | openssl dgst -sha256 -hmac s3cret
+	assert.Equal(t, "a858873a2e370c6d3596f8a7f637a59e14a08e9beab3a80eb2b165f2eb549463", Sign("s3cret", "1662033600", []byte("{}")))
+}
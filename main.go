@@ -1,12 +1,14 @@
 package main
 
 import (
+	"os"
+
 	log "github.com/sirupsen/logrus"
 	"github.com/yescorihuela/agrak/application"
 )
 
 func main() {
-	if err := application.Run(); err != nil {
+	if err := application.NewCLI().Run(os.Args); err != nil {
 		log.WithError(err).Fatalln("Fatal error")
 	}
 }
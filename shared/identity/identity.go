@@ -0,0 +1,20 @@
+package identity
+
+import (
+	"context"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+)
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *entity.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the caller attached by the authentication middleware,
+// or nil for unauthenticated contexts.
+func FromContext(ctx context.Context) *entity.Principal {
+	principal, _ := ctx.Value(principalKey{}).(*entity.Principal)
+	return principal
+}
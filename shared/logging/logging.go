@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+type loggerKey struct{}
+
+// Configure sets the level and format of the standard logrus logger. Empty
+// values keep info level and JSON output.
+func Configure(level, format string) error {
+	logLevel := log.InfoLevel
+	if strings.TrimSpace(level) != "" {
+		parsed, err := log.ParseLevel(level)
+		if err != nil {
+			return fmt.Errorf("invalid log level %q", level)
+		}
+		logLevel = parsed
+	}
+
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", FormatJSON:
+		log.SetFormatter(&log.JSONFormatter{})
+	case FormatText:
+		log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	default:
+		return fmt.Errorf("invalid log format %q (valid: json, text)", format)
+	}
+	log.SetLevel(logLevel)
+	return nil
+}
+
+func WithLogger(ctx context.Context, logger *log.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the request-scoped logger attached by the request
+// logging middleware, or the standard logger outside of a request.
+func FromContext(ctx context.Context) *log.Entry {
+	if logger, ok := ctx.Value(loggerKey{}).(*log.Entry); ok {
+		return logger
+	}
+	return log.NewEntry(log.StandardLogger())
+}
@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+)
+
+const (
+	apiKeyPrefix       = "agk_"
+	apiKeyDisplayChars = 12
+)
+
+var (
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	ErrInvalidRole   = errors.New("role must be one of reader, editor or admin")
+	ErrEmptyKeyName  = errors.New("empty api key name")
+)
+
+type KeyService interface {
+	IssueKey(ctx context.Context, name string, role entity.Role) (string, *entity.APIKey, error)
+	EnsureKey(ctx context.Context, name, rawKey string, role entity.Role) error
+	Authenticate(ctx context.Context, rawKey string) (*entity.APIKey, error)
+	FindAll(ctx context.Context) ([]entity.APIKey, error)
+	RevokeKey(ctx context.Context, id string) error
+}
+
+type APIKeyService struct {
+	repository repository.APIKeyRepository
+	now        func() time.Time
+}
+
+func NewAPIKeyService(repository repository.APIKeyRepository) KeyService {
+	return &APIKeyService{
+		repository: repository,
+		now:        time.Now,
+	}
+}
+
+// IssueKey creates a new key and returns its plaintext value. Only the hash
+// is persisted, so the plaintext cannot be recovered afterwards.
+func (s *APIKeyService) IssueKey(ctx context.Context, name string, role entity.Role) (string, *entity.APIKey, error) {
+	rawKey, err := generateToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	rawKey = apiKeyPrefix + rawKey
+	key, err := s.saveKey(ctx, name, rawKey, role)
+	if err != nil {
+		return "", nil, err
+	}
+	return rawKey, key, nil
+}
+
+// EnsureKey stores rawKey unless a key with the same hash already exists.
+// It is used to seed the first admin key from the environment.
+func (s *APIKeyService) EnsureKey(ctx context.Context, name, rawKey string, role entity.Role) error {
+	_, err := s.repository.GetByHash(ctx, HashAPIKey(rawKey))
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return err
+	}
+	_, err = s.saveKey(ctx, name, rawKey, role)
+	return err
+}
+
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey string) (*entity.APIKey, error) {
+	if strings.TrimSpace(rawKey) == "" {
+		return nil, ErrInvalidAPIKey
+	}
+	key, err := s.repository.GetByHash(ctx, HashAPIKey(rawKey))
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.IsRevoked() {
+		return nil, ErrInvalidAPIKey
+	}
+	return key, nil
+}
+
+func (s *APIKeyService) FindAll(ctx context.Context) ([]entity.APIKey, error) {
+	keys, err := s.repository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *APIKeyService) RevokeKey(ctx context.Context, id string) error {
+	err := s.repository.Revoke(ctx, id, s.now())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *APIKeyService) saveKey(ctx context.Context, name, rawKey string, role entity.Role) (*entity.APIKey, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, ErrEmptyKeyName
+	}
+	if !role.IsValid() {
+		return nil, ErrInvalidRole
+	}
+	id, err := generateID()
+	if err != nil {
+		return nil, err
+	}
+	prefix := rawKey
+	if len(prefix) > apiKeyDisplayChars {
+		prefix = prefix[:apiKeyDisplayChars]
+	}
+	key := entity.APIKey{
+		ID:        id,
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      HashAPIKey(rawKey),
+		Role:      role,
+		CreatedAt: s.now(),
+	}
+	if err := s.repository.Save(ctx, key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// HashAPIKey returns the value stored for rawKey. Keys are random 256 bit
+// tokens, so a fast hash is enough to make a leaked table useless.
+func HashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateToken(size int) (string, error) {
+	buffer := make([]byte, size)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
+
+func generateID() (string, error) {
+	buffer := make([]byte, 16)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buffer), nil
+}
@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"github.com/yescorihuela/agrak/domain/entity"
+)
+
+type KeyServiceMock struct {
+	mock.Mock
+}
+
+func (m *KeyServiceMock) IssueKey(ctx context.Context, name string, role entity.Role) (string, *entity.APIKey, error) {
+	args := m.Called(name, role)
+	var mockedKey *entity.APIKey
+	if args.Get(1) != nil {
+		mockedKey = args.Get(1).(*entity.APIKey)
+	}
+	return args.String(0), mockedKey, args.Error(2)
+}
+
+func (m *KeyServiceMock) EnsureKey(ctx context.Context, name, rawKey string, role entity.Role) error {
+	args := m.Called(name, rawKey, role)
+	return args.Error(0)
+}
+
+func (m *KeyServiceMock) Authenticate(ctx context.Context, rawKey string) (*entity.APIKey, error) {
+	args := m.Called(rawKey)
+	var mockedKey *entity.APIKey
+	if args.Get(0) != nil {
+		mockedKey = args.Get(0).(*entity.APIKey)
+	}
+	return mockedKey, args.Error(1)
+}
+
+func (m *KeyServiceMock) FindAll(ctx context.Context) ([]entity.APIKey, error) {
+	args := m.Called()
+	var mockedKeys []entity.APIKey
+	if args.Get(0) != nil {
+		mockedKeys = args.Get(0).([]entity.APIKey)
+	}
+	return mockedKeys, args.Error(1)
+}
+
+func (m *KeyServiceMock) RevokeKey(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/apikey"
+)
+
+func TestAPIKeyService_IssueKey(t *testing.T) {
+	t.Run("should store only the hash of the issued key", func(t *testing.T) {
+		repositoryMock := new(apikey.RepositoryMock)
+		repositoryMock.On("Save", mock.AnythingOfType("entity.APIKey")).Return(nil)
+
+		service := NewAPIKeyService(repositoryMock)
+		rawKey, key, err := service.IssueKey(context.Background(), "storefront", entity.RoleReader)
+
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(rawKey, apiKeyPrefix))
+		assert.Equal(t, HashAPIKey(rawKey), key.Hash)
+		assert.NotContains(t, key.Hash, rawKey)
+		assert.Equal(t, rawKey[:apiKeyDisplayChars], key.Prefix)
+		assert.Equal(t, entity.RoleReader, key.Role)
+		repositoryMock.AssertExpectations(t)
+	})
+
+	t.Run("should reject unknown roles", func(t *testing.T) {
+		repositoryMock := new(apikey.RepositoryMock)
+
+		service := NewAPIKeyService(repositoryMock)
+		_, _, err := service.IssueKey(context.Background(), "storefront", entity.Role("owner"))
+
+		assert.ErrorIs(t, err, ErrInvalidRole)
+		repositoryMock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	rawKey := "agk_test-key"
+
+	t.Run("should return the stored key", func(t *testing.T) {
+		storedKey := &entity.APIKey{ID: "1", Role: entity.RoleEditor}
+		repositoryMock := new(apikey.RepositoryMock)
+		repositoryMock.On("GetByHash", HashAPIKey(rawKey)).Return(storedKey, nil)
+
+		key, err := NewAPIKeyService(repositoryMock).Authenticate(context.Background(), rawKey)
+
+		assert.NoError(t, err)
+		assert.Equal(t, storedKey, key)
+	})
+
+	t.Run("should reject unknown keys", func(t *testing.T) {
+		repositoryMock := new(apikey.RepositoryMock)
+		repositoryMock.On("GetByHash", HashAPIKey(rawKey)).Return(nil, repository.ErrAPIKeyNotFound)
+
+		_, err := NewAPIKeyService(repositoryMock).Authenticate(context.Background(), rawKey)
+
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	})
+
+	t.Run("should reject revoked keys", func(t *testing.T) {
+		revokedAt := time.Now()
+		repositoryMock := new(apikey.RepositoryMock)
+		repositoryMock.On("GetByHash", HashAPIKey(rawKey)).Return(&entity.APIKey{ID: "1", Role: entity.RoleAdmin, RevokedAt: &revokedAt}, nil)
+
+		_, err := NewAPIKeyService(repositoryMock).Authenticate(context.Background(), rawKey)
+
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	})
+}
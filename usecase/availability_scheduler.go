@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/shared/logging"
+)
+
+// AvailabilityScheduler emits product.available and product.unavailable
+// events when the availability window of a product opens or closes. The
+// availability it announced is stored with the product, so every change is
+// announced once, across restarts and replicas.
+type AvailabilityScheduler struct {
+	repository repository.ProductRepository
+	unitOfWork repository.UnitOfWork
+	now        func() time.Time
+}
+
+func NewAvailabilityScheduler(productRepository repository.ProductRepository, unitOfWork repository.UnitOfWork) *AvailabilityScheduler {
+	return &AvailabilityScheduler{
+		repository: productRepository,
+		unitOfWork: unitOfWork,
+		now:        time.Now,
+	}
+}
+
+// Run announces availability changes until ctx is done. It wakes up when the
+// next window opens or closes, and at least every interval to pick up the
+// windows changed since.
+func (s *AvailabilityScheduler) Run(ctx context.Context, interval time.Duration) {
+	for {
+		next, err := s.AnnounceChanges(ctx)
+		if err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).WithError(err).Errorln("error trying to announce availability changes")
+		}
+		wait := interval
+		if next != nil {
+			if untilNext := next.Sub(s.now()); untilNext < wait {
+				wait = untilNext
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// AnnounceChanges emits an event for every product whose availability
+// differs from the one last announced for it, and returns when the next
+// window opens or closes, nil when none is scheduled.
+func (s *AvailabilityScheduler) AnnounceChanges(ctx context.Context) (*time.Time, error) {
+	now := s.now()
+	var announced []entity.Product
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, tx repository.Transaction) error {
+		products, err := tx.Products().GetAvailabilityChanges(ctx, now)
+		if err != nil {
+			return err
+		}
+		for i := range products {
+			product := products[i]
+			available := product.IsAvailableAt(now)
+			if err := tx.Products().SetAnnouncedAvailability(ctx, product.Sku, available); err != nil {
+				return err
+			}
+			eventType := entity.EventProductUnavailable
+			if available {
+				eventType = entity.EventProductAvailable
+			}
+			err := appendEvent(ctx, tx, entity.ProductEvent{
+				Type:    eventType,
+				Sku:     product.Sku,
+				Product: &product,
+			}, now)
+			if err != nil {
+				return err
+			}
+		}
+		announced = products
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, product := range announced {
+		logging.FromContext(ctx).WithField("sku", product.Sku).WithField("available", product.IsAvailableAt(now)).Infoln("product availability changed")
+	}
+	return s.repository.NextAvailabilityChange(ctx, now)
+}
@@ -0,0 +1,66 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/infrastructure/memory"
+)
+
+func TestAvailabilityScheduler_AnnounceChanges(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Add(time.Hour).Truncate(time.Second)
+	opens, closes := start.Add(time.Hour), start.Add(2*time.Hour)
+	products := memory.NewProductRepository()
+	outbox := memory.NewOutboxRepository()
+	assert.NoError(t, products.Save(ctx, entity.Product{
+		Sku:            "FAL-1000000",
+		Name:           "Bicicleta infantil",
+		Brand:          "Oxford",
+		Size:           "16",
+		Price:          130000.00,
+		PrincipalImage: "https://via.placeholder.com/500x500.png",
+		Status:         entity.StatusActive,
+		AvailableFrom:  &opens,
+		AvailableUntil: &closes,
+	}))
+	now := start
+	scheduler := NewAvailabilityScheduler(products, memory.NewUnitOfWork(products, outbox))
+	scheduler.now = func() time.Time { return now }
+	announced := func() []entity.EventType {
+		entries, err := outbox.Claim(ctx, closes.Add(time.Hour), time.Minute, 10)
+		assert.NoError(t, err)
+		types := make([]entity.EventType, 0, len(entries))
+		for _, entry := range entries {
+			assert.NoError(t, outbox.MarkDispatched(ctx, entry.Event.ID, now))
+			assert.Equal(t, now, entry.Event.OccurredAt)
+			types = append(types, entry.Event.Type)
+		}
+		return types
+	}
+
+	next, err := scheduler.AnnounceChanges(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, &opens, next)
+	assert.Empty(t, announced(), "the window has not opened yet")
+
+	now = opens
+	next, err = scheduler.AnnounceChanges(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, &closes, next)
+	assert.Equal(t, []entity.EventType{entity.EventProductAvailable}, announced())
+
+	next, err = scheduler.AnnounceChanges(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, &closes, next)
+	assert.Empty(t, announced(), "an opening is only announced once")
+
+	now = closes
+	next, err = scheduler.AnnounceChanges(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, next)
+	assert.Equal(t, []entity.EventType{entity.EventProductUnavailable}, announced())
+}
@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/shared/logging"
+)
+
+// followOverlap is how far back each poll of an OutboxFollower looks again,
+// so events dispatched by replicas whose clocks lag behind, or committed
+// after later ones, are not skipped.
+const followOverlap = 10 * time.Second
+
+// OutboxFollower publishes the events dispatched by the relay of any
+// replica, so publishers local to each replica, like the product stream,
+// see every event and not only the ones claimed by their own relay. Events
+// dispatched within followOverlap are published again, so publishers must
+// deduplicate by event ID.
+type OutboxFollower struct {
+	outbox     repository.OutboxRepository
+	publishers []EventPublisher
+	batchSize  int
+	cursor     time.Time
+}
+
+// NewOutboxFollower follows the events dispatched from now on.
+func NewOutboxFollower(outbox repository.OutboxRepository, batchSize int, publishers ...EventPublisher) *OutboxFollower {
+	if batchSize <= 0 {
+		batchSize = DefaultRelayBatchSize
+	}
+	return &OutboxFollower{
+		outbox:     outbox,
+		publishers: publishers,
+		batchSize:  batchSize,
+		cursor:     time.Now(),
+	}
+}
+
+// Run publishes the dispatched events every interval until ctx is done.
+func (f *OutboxFollower) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := f.FollowDispatched(ctx); err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).WithError(err).Errorln("error trying to follow dispatched outbox events")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// FollowDispatched publishes the events dispatched since the previous call,
+// a batch at a time and each once per call. Batches are paged by dispatch
+// time and event ID, so events dispatched at the same time are not skipped
+// nor published again however many there are. Publishers only fail for the
+// event they reject, which is not retried.
+func (f *OutboxFollower) FollowDispatched(ctx context.Context) error {
+	since, afterID := f.cursor.Add(-followOverlap), ""
+	for {
+		entries, err := f.outbox.ListDispatched(ctx, since, afterID, f.batchSize)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			f.publish(ctx, entry.Event)
+			if entry.DispatchedAt.After(f.cursor) {
+				f.cursor = *entry.DispatchedAt
+			}
+		}
+		if len(entries) < f.batchSize {
+			return nil
+		}
+		last := entries[len(entries)-1]
+		since, afterID = *last.DispatchedAt, last.Event.ID
+	}
+}
+
+func (f *OutboxFollower) publish(ctx context.Context, event entity.ProductEvent) {
+	for _, publisher := range f.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			logging.FromContext(ctx).WithError(err).WithField("publisher", publisher.Name()).
+				WithField("event_id", event.ID).Warnln("error trying to publish dispatched event")
+		}
+	}
+}
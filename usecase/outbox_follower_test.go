@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/infrastructure/memory"
+)
+
+func TestOutboxFollower_FollowDispatched(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)
+	outbox := memory.NewOutboxRepository()
+	dispatch := func(id string, at time.Time) {
+		assert.NoError(t, outbox.Append(ctx, entity.ProductEvent{ID: id, Type: entity.EventProductCreated, Sku: "FAL-1000000", OccurredAt: at}))
+		assert.NoError(t, outbox.MarkDispatched(ctx, id, at))
+	}
+	dispatch("before", start.Add(-time.Minute))
+
+	t.Run("should publish the events dispatched by every relay", func(t *testing.T) {
+		replicas := []*publisherFake{{name: "first"}, {name: "second"}}
+		followers := make([]*OutboxFollower, 0, len(replicas))
+		for _, stream := range replicas {
+			follower := NewOutboxFollower(outbox, 2, stream)
+			follower.cursor = start
+			followers = append(followers, follower)
+		}
+		dispatch("a", start.Add(time.Second))
+		dispatch("b", start.Add(2*time.Second))
+		dispatch("c", start.Add(3*time.Second))
+
+		for i, follower := range followers {
+			assert.NoError(t, follower.FollowDispatched(ctx))
+			assert.Equal(t, []string{"a", "b", "c"}, replicas[i].published, "events older than the overlap are skipped")
+			assert.Equal(t, start.Add(3*time.Second), follower.cursor)
+		}
+	})
+
+	t.Run("should page through events dispatched at the same time", func(t *testing.T) {
+		stream := &publisherFake{name: "stream"}
+		follower := NewOutboxFollower(outbox, 2, stream)
+		follower.cursor = start.Add(time.Hour)
+		at := start.Add(time.Hour + time.Second)
+		for _, id := range []string{"same-1", "same-2", "same-3", "same-4", "same-5"} {
+			dispatch(id, at)
+		}
+
+		assert.NoError(t, follower.FollowDispatched(ctx))
+		assert.Equal(t, []string{"same-1", "same-2", "same-3", "same-4", "same-5"}, stream.published, "full pages sharing a time are published once each")
+		assert.Equal(t, at, follower.cursor)
+	})
+
+	t.Run("should look back for events dispatched late", func(t *testing.T) {
+		stream := &publisherFake{name: "stream"}
+		follower := NewOutboxFollower(outbox, 10, stream)
+		follower.cursor = start.Add(3 * time.Second)
+		dispatch("late", start.Add(2500*time.Millisecond))
+
+		assert.NoError(t, follower.FollowDispatched(ctx))
+		assert.Contains(t, stream.published, "late")
+	})
+}
@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/shared/logging"
+)
+
+const (
+	DefaultRelayBatchSize = 100
+	relayLease            = 2 * time.Minute
+	relayPublishTimeout   = 30 * time.Second
+	relayInitialBackoff   = time.Second
+	relayMaxBackoff       = 5 * time.Minute
+)
+
+// EventPublisher delivers product events to a downstream system. Delivery
+// is at least once: an event is published again, to every publisher, until
+// all of them accept it, so publishers should deduplicate by event ID.
+type EventPublisher interface {
+	Name() string
+	Publish(ctx context.Context, event entity.ProductEvent) error
+}
+
+// OutboxRelay dispatches the events recorded in the outbox to publishers.
+// Failed events are retried with exponential backoff.
+type OutboxRelay struct {
+	outbox     repository.OutboxRepository
+	publishers []EventPublisher
+	batchSize  int
+	now        func() time.Time
+}
+
+func NewOutboxRelay(outbox repository.OutboxRepository, batchSize int, publishers ...EventPublisher) *OutboxRelay {
+	if batchSize <= 0 {
+		batchSize = DefaultRelayBatchSize
+	}
+	return &OutboxRelay{
+		outbox:     outbox,
+		publishers: publishers,
+		batchSize:  batchSize,
+		now:        time.Now,
+	}
+}
+
+// Run dispatches events until ctx is done, checking the outbox every
+// interval once it is empty.
+func (r *OutboxRelay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		dispatched, err := r.DispatchPending(ctx)
+		if err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).WithError(err).Errorln("error trying to dispatch outbox events")
+		}
+		if dispatched == r.batchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchPending publishes one batch of due events and returns how many
+// were claimed.
+func (r *OutboxRelay) DispatchPending(ctx context.Context) (int, error) {
+	entries, err := r.outbox.Claim(ctx, r.now(), relayLease, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return len(entries), err
+		}
+		if err := r.dispatch(ctx, entry); err != nil {
+			return len(entries), err
+		}
+	}
+	return len(entries), nil
+}
+
+func (r *OutboxRelay) dispatch(ctx context.Context, entry entity.OutboxEntry) error {
+	event := entry.Event
+	logger := logging.FromContext(ctx).WithField("event_id", event.ID).WithField("event_type", event.Type).WithField("sku", event.Sku)
+	for _, publisher := range r.publishers {
+		publishCtx, cancel := context.WithTimeout(ctx, relayPublishTimeout)
+		err := publisher.Publish(publishCtx, event)
+		cancel()
+		if err != nil {
+			attempts := entry.Attempts + 1
+			nextAttemptAt := r.now().Add(RelayBackoff(attempts))
+			logger.WithError(err).WithField("publisher", publisher.Name()).WithField("attempts", attempts).
+				WithField("next_attempt_at", nextAttemptAt).Warnln("error trying to publish event")
+			return r.outbox.MarkFailed(ctx, event.ID, nextAttemptAt, fmt.Sprintf("%s: %v", publisher.Name(), err))
+		}
+	}
+	logger.Debugln("event dispatched")
+	return r.outbox.MarkDispatched(ctx, event.ID, r.now())
+}
+
+// RelayBackoff returns the wait before retrying an event that failed
+// attempts times: one second, doubled on every attempt, up to five minutes.
+func RelayBackoff(attempts int) time.Duration {
+	backoff := relayInitialBackoff
+	for i := 1; i < attempts && backoff < relayMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > relayMaxBackoff {
+		return relayMaxBackoff
+	}
+	return backoff
+}
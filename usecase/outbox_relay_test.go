@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/infrastructure/memory"
+)
+
+type publisherFake struct {
+	name      string
+	err       error
+	published []string
+}
+
+func (p *publisherFake) Name() string {
+	return p.name
+}
+
+func (p *publisherFake) Publish(ctx context.Context, event entity.ProductEvent) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, event.ID)
+	return nil
+}
+
+func TestOutboxRelay_DispatchPending(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)
+	newOutbox := func(ids ...string) *memory.OutboxRepository {
+		outbox := memory.NewOutboxRepository()
+		for i, id := range ids {
+			_ = outbox.Append(ctx, entity.ProductEvent{ID: id, Type: entity.EventProductCreated, Sku: "FAL-1000000", OccurredAt: start.Add(time.Duration(i) * time.Second)})
+		}
+		return outbox
+	}
+
+	t.Run("should publish events in order to every publisher", func(t *testing.T) {
+		outbox := newOutbox("a", "b")
+		first, second := &publisherFake{name: "first"}, &publisherFake{name: "second"}
+		relay := NewOutboxRelay(outbox, 10, first, second)
+		relay.now = func() time.Time { return start.Add(time.Minute) }
+
+		dispatched, err := relay.DispatchPending(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, dispatched)
+		assert.Equal(t, []string{"a", "b"}, first.published)
+		assert.Equal(t, []string{"a", "b"}, second.published)
+		assert.True(t, outbox.Dispatched("a"))
+		assert.True(t, outbox.Dispatched("b"))
+	})
+
+	t.Run("should retry failed events after the backoff", func(t *testing.T) {
+		outbox := newOutbox("a")
+		publisher := &publisherFake{name: "flaky", err: errors.New("unavailable")}
+		now := start.Add(time.Minute)
+		relay := NewOutboxRelay(outbox, 10, publisher)
+		relay.now = func() time.Time { return now }
+
+		_, err := relay.DispatchPending(ctx)
+		assert.NoError(t, err)
+		assert.False(t, outbox.Dispatched("a"))
+
+		dispatched, _ := relay.DispatchPending(ctx)
+		assert.Equal(t, 0, dispatched, "the event is not due before its backoff")
+
+		now = now.Add(RelayBackoff(1))
+		publisher.err = nil
+		dispatched, err = relay.DispatchPending(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, dispatched)
+		assert.True(t, outbox.Dispatched("a"))
+	})
+
+	t.Run("should not hand out events claimed by another relay", func(t *testing.T) {
+		outbox := newOutbox("a")
+		entries, err := outbox.Claim(ctx, start, time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+
+		entries, err = outbox.Claim(ctx, start.Add(30*time.Second), time.Minute, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
+
+func TestRelayBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, RelayBackoff(1))
+	assert.Equal(t, 2*time.Second, RelayBackoff(2))
+	assert.Equal(t, 8*time.Second, RelayBackoff(4))
+	assert.Equal(t, 5*time.Minute, RelayBackoff(30))
+}
@@ -1,64 +1,353 @@
 package usecase
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/yescorihuela/agrak/domain/entity"
 	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/shared/logging"
+)
+
+var (
+	ErrSkuChange     = errors.New("the sku of a product can only be changed by renaming it")
+	ErrInvalidSku    = errors.New("invalid sku format (right format: FAL-XXXXXXX)")
+	ErrSameSku       = errors.New("the new sku is the current sku of the product")
+	ErrInvalidStatus = errors.New("status must be draft, active or discontinued")
 )
 
 type Service interface {
-	CreateProduct(product entity.Product) error
-	FindBySku(sku string) (*entity.Product, error)
-	FindAll() ([]entity.Product, error)
-	UpdateProduct(oldSku string, product entity.Product) (*entity.Product, error)
-	DeleteProduct(sku string) error
+	// CreateProduct stores product as a draft and returns it as stored.
+	CreateProduct(ctx context.Context, product entity.Product) (*entity.Product, error)
+	FindBySku(ctx context.Context, sku string) (*entity.Product, error)
+	// FindBySkus returns the products found among skus, ordered by SKU.
+	FindBySkus(ctx context.Context, skus []string) ([]entity.Product, error)
+	FindAll(ctx context.Context) ([]entity.Product, error)
+	// FindPage returns up to limit products matching filter ordered by SKU,
+	// starting after afterSku.
+	FindPage(ctx context.Context, filter repository.ProductFilter, afterSku string, limit int) ([]entity.Product, error)
+	// UpdateProduct returns ErrSkuChange when product.Sku is not oldSku. The
+	// status of the product is kept.
+	UpdateProduct(ctx context.Context, oldSku string, product entity.Product) (*entity.Product, error)
+	// ChangeStatus moves the product stored under sku to status on behalf
+	// of principal, see entity.Product.TransitionTo.
+	ChangeStatus(ctx context.Context, sku string, status entity.ProductStatus, principal *entity.Principal) (*entity.Product, error)
+	// RestoreProduct creates product or replaces the product stored under
+	// its SKU, moving it to the status it carries on behalf of principal, and
+	// tells whether it was created. It backs the import of exported products.
+	RestoreProduct(ctx context.Context, product entity.Product, principal *entity.Principal) (bool, error)
+	// RenameProduct moves the product stored under sku to newSku and keeps
+	// sku as an alias of it.
+	RenameProduct(ctx context.Context, sku string, newSku string) (*entity.Product, error)
+	// ResolveAlias returns the current SKU of the product sku used to name,
+	// or repository.ErrSkuAliasNotFound.
+	ResolveAlias(ctx context.Context, sku string) (string, error)
+	DeleteProduct(ctx context.Context, sku string) error
 }
 
+// ProductService reads through repository and runs every write in a
+// unitOfWork transaction, together with the product event it emits to the
+// outbox.
 type ProductService struct {
 	repository repository.ProductRepository
+	unitOfWork repository.UnitOfWork
+	now        func() time.Time
 }
 
-func NewProductService(repository repository.ProductRepository) Service {
+func NewProductService(productRepository repository.ProductRepository, unitOfWork repository.UnitOfWork) Service {
 	return &ProductService{
-		repository: repository,
+		repository: productRepository,
+		unitOfWork: unitOfWork,
+		now:        time.Now,
 	}
 }
 
-func (s *ProductService) CreateProduct(product entity.Product) error {
-	err := s.repository.Save(product)
+func (s *ProductService) CreateProduct(ctx context.Context, product entity.Product) (*entity.Product, error) {
+	product.Status = entity.StatusDraft
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, tx repository.Transaction) error {
+		if err := ensureSkuAvailable(ctx, tx.Products(), product.Sku, ""); err != nil {
+			return err
+		}
+		if err := tx.Products().Save(ctx, product); err != nil {
+			return err
+		}
+		return s.emit(ctx, tx, entity.ProductEvent{
+			Type:    entity.EventProductCreated,
+			Sku:     product.Sku,
+			Product: &product,
+		})
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	logging.FromContext(ctx).WithField("sku", product.Sku).Infoln("product created")
+	return &product, nil
 }
 
-func (s *ProductService) FindBySku(sku string) (*entity.Product, error) {
-	product, err := s.repository.GetBySku(sku)
+func (s *ProductService) FindBySku(ctx context.Context, sku string) (*entity.Product, error) {
+	product, err := s.repository.GetBySku(ctx, sku)
 	if err != nil {
 		return nil, err
 	}
 	return product, nil
 }
 
-func (s *ProductService) FindAll() ([]entity.Product, error) {
-	products, err := s.repository.GetAllProducts()
+func (s *ProductService) FindBySkus(ctx context.Context, skus []string) ([]entity.Product, error) {
+	return s.repository.GetBySkus(ctx, skus)
+}
+
+func (s *ProductService) FindAll(ctx context.Context) ([]entity.Product, error) {
+	products, err := s.repository.GetAllProducts(ctx)
 	if err != nil {
 		return nil, err
 	}
 	return products, nil
 }
 
-func (s *ProductService) UpdateProduct(oldSku string, product entity.Product) (*entity.Product, error) {
-	updatedProduct, err := s.repository.Update(oldSku, product)
+func (s *ProductService) FindPage(ctx context.Context, filter repository.ProductFilter, afterSku string, limit int) ([]entity.Product, error) {
+	return s.repository.GetPage(ctx, filter, afterSku, limit)
+}
+
+func (s *ProductService) UpdateProduct(ctx context.Context, oldSku string, product entity.Product) (*entity.Product, error) {
+	var updatedProduct *entity.Product
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, tx repository.Transaction) error {
+		oldProduct, err := tx.Products().GetBySku(ctx, oldSku)
+		if err != nil {
+			return err
+		}
+		if product.Sku != oldSku {
+			return ErrSkuChange
+		}
+		product.Status = oldProduct.Status
+		updatedProduct, err = tx.Products().Update(ctx, oldSku, product)
+		if err != nil {
+			return err
+		}
+		changedFields := entity.ChangedFields(*oldProduct, *updatedProduct)
+		if len(changedFields) == 0 {
+			return nil
+		}
+		return s.emit(ctx, tx, entity.ProductEvent{
+			Type:          entity.EventProductUpdated,
+			Sku:           updatedProduct.Sku,
+			ChangedFields: changedFields,
+			Product:       updatedProduct,
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
+	logging.FromContext(ctx).WithField("sku", oldSku).Infoln("product updated")
 	return updatedProduct, nil
 }
 
-func (s *ProductService) DeleteProduct(sku string) error {
-	err := s.repository.Delete(sku)
+func (s *ProductService) ChangeStatus(ctx context.Context, sku string, status entity.ProductStatus, principal *entity.Principal) (*entity.Product, error) {
+	var updatedProduct *entity.Product
+	var previousStatus entity.ProductStatus
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, tx repository.Transaction) error {
+		product, err := tx.Products().GetBySku(ctx, sku)
+		if err != nil {
+			return err
+		}
+		previousStatus = product.Status
+		if err := product.TransitionTo(status, principal); err != nil {
+			return err
+		}
+		updatedProduct, err = tx.Products().Update(ctx, sku, *product)
+		if err != nil {
+			return err
+		}
+		return s.emit(ctx, tx, entity.ProductEvent{
+			Type:          entity.EventProductUpdated,
+			Sku:           sku,
+			ChangedFields: []string{"status"},
+			Product:       updatedProduct,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).WithField("sku", sku).WithField("from", previousStatus).WithField("to", status).Infoln("product status changed")
+	return updatedProduct, nil
+}
+
+// RestoreProduct creates missing products as drafts and keeps the status of
+// stored ones, then moves them to the status product carries, when it has
+// one, through the transitions ChangeStatus allows.
+func (s *ProductService) RestoreProduct(ctx context.Context, product entity.Product, principal *entity.Principal) (bool, error) {
+	status := product.Status
+	if status != "" && !status.IsValid() {
+		return false, ErrInvalidStatus
+	}
+	created := false
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, tx repository.Transaction) error {
+		oldProduct, err := tx.Products().GetBySku(ctx, product.Sku)
+		if errors.Is(err, repository.ErrProductNotFound) {
+			created = true
+			product.Status = entity.StatusDraft
+			if err := restoreStatus(&product, status, principal); err != nil {
+				return err
+			}
+			if err := ensureSkuAvailable(ctx, tx.Products(), product.Sku, ""); err != nil {
+				return err
+			}
+			if err := tx.Products().Save(ctx, product); err != nil {
+				return err
+			}
+			return s.emit(ctx, tx, entity.ProductEvent{
+				Type:    entity.EventProductCreated,
+				Sku:     product.Sku,
+				Product: &product,
+			})
+		}
+		if err != nil {
+			return err
+		}
+		product.Status = oldProduct.Status
+		if err := restoreStatus(&product, status, principal); err != nil {
+			return err
+		}
+		updatedProduct, err := tx.Products().Update(ctx, product.Sku, product)
+		if err != nil {
+			return err
+		}
+		changedFields := entity.ChangedFields(*oldProduct, *updatedProduct)
+		if len(changedFields) == 0 {
+			return nil
+		}
+		return s.emit(ctx, tx, entity.ProductEvent{
+			Type:          entity.EventProductUpdated,
+			Sku:           updatedProduct.Sku,
+			ChangedFields: changedFields,
+			Product:       updatedProduct,
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+	logging.FromContext(ctx).WithField("sku", product.Sku).WithField("created", created).Infoln("product restored")
+	return created, nil
+}
+
+// restoreStatus moves product to status, unless it is empty, through
+// entity.Product.TransitionTo. Drafts are published on their way to being
+// discontinued, so they must pass the same validations.
+func restoreStatus(product *entity.Product, status entity.ProductStatus, principal *entity.Principal) error {
+	if status == "" || status == product.Status {
+		return nil
+	}
+	if product.Status == entity.StatusDraft && status == entity.StatusDiscontinued {
+		if err := product.TransitionTo(entity.StatusActive, principal); err != nil {
+			return err
+		}
+	}
+	return product.TransitionTo(status, principal)
+}
+
+// RenameProduct records the rename as an update of the sku field, with the
+// old SKU as PreviousSku. Renaming a product back to one of its aliases is
+// allowed.
+func (s *ProductService) RenameProduct(ctx context.Context, sku string, newSku string) (*entity.Product, error) {
+	if !(&entity.Product{Sku: newSku}).IsValidSku() {
+		return nil, ErrInvalidSku
+	}
+	if newSku == sku {
+		return nil, ErrSameSku
+	}
+	var renamedProduct *entity.Product
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, tx repository.Transaction) error {
+		if _, err := tx.Products().GetBySku(ctx, sku); err != nil {
+			return err
+		}
+		if err := ensureSkuAvailable(ctx, tx.Products(), newSku, sku); err != nil {
+			return err
+		}
+		var err error
+		renamedProduct, err = tx.Products().Rename(ctx, sku, newSku)
+		if err != nil {
+			return err
+		}
+		return s.emit(ctx, tx, entity.ProductEvent{
+			Type:          entity.EventProductUpdated,
+			Sku:           newSku,
+			PreviousSku:   sku,
+			ChangedFields: []string{"sku"},
+			Product:       renamedProduct,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).WithField("sku", sku).WithField("new_sku", newSku).Infoln("product renamed")
+	return renamedProduct, nil
+}
+
+func (s *ProductService) ResolveAlias(ctx context.Context, sku string) (string, error) {
+	return s.repository.GetAliasTarget(ctx, sku)
+}
+
+// DeleteProduct succeeds without emitting an event when sku does not exist.
+func (s *ProductService) DeleteProduct(ctx context.Context, sku string) error {
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, tx repository.Transaction) error {
+		product, err := tx.Products().GetBySku(ctx, sku)
+		if errors.Is(err, repository.ErrProductNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Products().Delete(ctx, sku); err != nil {
+			return err
+		}
+		return s.emit(ctx, tx, entity.ProductEvent{
+			Type:    entity.EventProductDeleted,
+			Sku:     sku,
+			Product: product,
+		})
+	})
 	if err != nil {
 		return err
 	}
+	logging.FromContext(ctx).WithField("sku", sku).Infoln("product deleted")
 	return nil
 }
+
+// emit records event in the outbox of tx, so it is only dispatched if the
+// change it describes is committed.
+func (s *ProductService) emit(ctx context.Context, tx repository.Transaction, event entity.ProductEvent) error {
+	return appendEvent(ctx, tx, event, s.now())
+}
+
+// appendEvent records event, occurred at occurredAt, in the outbox of tx.
+func appendEvent(ctx context.Context, tx repository.Transaction, event entity.ProductEvent, occurredAt time.Time) error {
+	id, err := generateID()
+	if err != nil {
+		return err
+	}
+	event.ID = id
+	event.OccurredAt = occurredAt
+	return tx.Outbox().Append(ctx, event)
+}
+
+// ensureSkuAvailable returns repository.ErrDuplicatedProduct when sku is
+// taken by a product, or is an alias of a product other than owner, so the
+// references to renamed products keep resolving to them. The unique key
+// still guards against concurrent transactions.
+func ensureSkuAvailable(ctx context.Context, products repository.ProductRepository, sku string, owner string) error {
+	_, err := products.GetBySku(ctx, sku)
+	switch {
+	case err == nil:
+		return repository.ErrDuplicatedProduct
+	case !errors.Is(err, repository.ErrProductNotFound):
+		return err
+	}
+	target, err := products.GetAliasTarget(ctx, sku)
+	switch {
+	case err == nil && target != owner:
+		return repository.ErrDuplicatedProduct
+	case err == nil, errors.Is(err, repository.ErrSkuAliasNotFound):
+		return nil
+	}
+	return err
+}
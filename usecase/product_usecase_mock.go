@@ -1,20 +1,27 @@
 package usecase
 
 import (
+	"context"
+
 	"github.com/stretchr/testify/mock"
 	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
 )
 
 type UseCaseMock struct {
 	mock.Mock
 }
 
-func (m *UseCaseMock) CreateProduct(product entity.Product) error {
+func (m *UseCaseMock) CreateProduct(ctx context.Context, product entity.Product) (*entity.Product, error) {
 	args := m.Called(product)
-	return args.Error(0)
+	var createdProduct *entity.Product
+	if args.Get(0) != nil {
+		createdProduct = args.Get(0).(*entity.Product)
+	}
+	return createdProduct, args.Error(1)
 }
 
-func (m *UseCaseMock) FindBySku(sku string) (*entity.Product, error) {
+func (m *UseCaseMock) FindBySku(ctx context.Context, sku string) (*entity.Product, error) {
 	args := m.Called(sku)
 	var mockedEntityProduct *entity.Product
 	var mockedError error
@@ -29,7 +36,7 @@ func (m *UseCaseMock) FindBySku(sku string) (*entity.Product, error) {
 	return mockedEntityProduct, mockedError
 }
 
-func (m *UseCaseMock) FindAll() ([]entity.Product, error) {
+func (m *UseCaseMock) FindAll(ctx context.Context) ([]entity.Product, error) {
 	args := m.Called()
 	var mockedEntityProduct []entity.Product
 	var mockedError error
@@ -44,7 +51,37 @@ func (m *UseCaseMock) FindAll() ([]entity.Product, error) {
 	return mockedEntityProduct, mockedError
 }
 
-func (m *UseCaseMock) UpdateProduct(oldSku string, product entity.Product) (*entity.Product, error) {
+func (m *UseCaseMock) FindBySkus(ctx context.Context, skus []string) ([]entity.Product, error) {
+	args := m.Called(skus)
+	var mockedEntityProduct []entity.Product
+	var mockedError error
+	if args.Get(0) != nil {
+		mockedEntityProduct = args.Get(0).([]entity.Product)
+	}
+
+	if args.Get(1) != nil {
+		mockedError = args.Get(1).(error)
+	}
+
+	return mockedEntityProduct, mockedError
+}
+
+func (m *UseCaseMock) FindPage(ctx context.Context, filter repository.ProductFilter, afterSku string, limit int) ([]entity.Product, error) {
+	args := m.Called(filter, afterSku, limit)
+	var mockedEntityProduct []entity.Product
+	var mockedError error
+	if args.Get(0) != nil {
+		mockedEntityProduct = args.Get(0).([]entity.Product)
+	}
+
+	if args.Get(1) != nil {
+		mockedError = args.Get(1).(error)
+	}
+
+	return mockedEntityProduct, mockedError
+}
+
+func (m *UseCaseMock) UpdateProduct(ctx context.Context, oldSku string, product entity.Product) (*entity.Product, error) {
 	args := m.Called(oldSku, product)
 	var mockedEntityProduct *entity.Product
 	var mockedError error
@@ -59,7 +96,47 @@ func (m *UseCaseMock) UpdateProduct(oldSku string, product entity.Product) (*ent
 	return mockedEntityProduct, mockedError
 }
 
-func (m *UseCaseMock) DeleteProduct(sku string) error {
+func (m *UseCaseMock) RestoreProduct(ctx context.Context, product entity.Product, principal *entity.Principal) (bool, error) {
+	args := m.Called(product, principal)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *UseCaseMock) ChangeStatus(ctx context.Context, sku string, status entity.ProductStatus, principal *entity.Principal) (*entity.Product, error) {
+	args := m.Called(sku, status, principal)
+	var mockedEntityProduct *entity.Product
+	var mockedError error
+	if args.Get(0) != nil {
+		mockedEntityProduct = args.Get(0).(*entity.Product)
+	}
+
+	if args.Get(1) != nil {
+		mockedError = args.Get(1).(error)
+	}
+
+	return mockedEntityProduct, mockedError
+}
+
+func (m *UseCaseMock) RenameProduct(ctx context.Context, sku string, newSku string) (*entity.Product, error) {
+	args := m.Called(sku, newSku)
+	var mockedEntityProduct *entity.Product
+	var mockedError error
+	if args.Get(0) != nil {
+		mockedEntityProduct = args.Get(0).(*entity.Product)
+	}
+
+	if args.Get(1) != nil {
+		mockedError = args.Get(1).(error)
+	}
+
+	return mockedEntityProduct, mockedError
+}
+
+func (m *UseCaseMock) ResolveAlias(ctx context.Context, sku string) (string, error) {
+	args := m.Called(sku)
+	return args.String(0), args.Error(1)
+}
+
+func (m *UseCaseMock) DeleteProduct(ctx context.Context, sku string) error {
 	args := m.Called(sku)
 	return args.Error(0)
 }
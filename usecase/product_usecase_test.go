@@ -1,15 +1,25 @@
 package usecase
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/memory"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/outbox"
 	"github.com/yescorihuela/agrak/infrastructure/postgresql/product"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/transaction"
 )
 
+func newUnitOfWorkMock(products *product.RepositoryMock, events *outbox.RepositoryMock) *transaction.UnitOfWorkMock {
+	events.On("Append", mock.Anything).Return(nil).Maybe()
+	return &transaction.UnitOfWorkMock{ProductRepository: products, OutboxRepository: events}
+}
+
 func TestProductService_Save(t *testing.T) {
 	t.Run("should not return an error", func(t *testing.T) {
 		productRepositoryMock := new(product.RepositoryMock)
@@ -25,21 +35,333 @@ func TestProductService_Save(t *testing.T) {
 				"https://via.placeholder.com/500x260.png?text=Agrak+Exercise+Resolution",
 				"https://via.placeholder.com/500x500.png?text=Agrak+Exercise+Resolution",
 			},
+			Status: entity.StatusDraft,
 		}
+		productRepositoryMock.On("GetBySku", productFake.Sku).Return((*entity.Product)(nil), repository.ErrProductNotFound)
+		productRepositoryMock.On("GetAliasTarget", productFake.Sku).Return("", repository.ErrSkuAliasNotFound)
 		productRepositoryMock.On("Save", productFake).Return(nil)
 
-		useCase := NewProductService(productRepositoryMock)
-		err := useCase.CreateProduct(productFake)
+		useCase := NewProductService(productRepositoryMock, newUnitOfWorkMock(productRepositoryMock, new(outbox.RepositoryMock)))
+		createdProduct, err := useCase.CreateProduct(context.Background(), productFake)
 		assert.NoError(t, err)
+		assert.Equal(t, &productFake, createdProduct, "the stored draft is returned")
 	})
 	t.Run("should return an error", func(t *testing.T) {
 		t.Run("should not return an error", func(t *testing.T) {
 			productRepositoryMock := new(product.RepositoryMock)
+			productRepositoryMock.On("GetBySku", mock.Anything).Return((*entity.Product)(nil), repository.ErrProductNotFound)
+			productRepositoryMock.On("GetAliasTarget", mock.Anything).Return("", repository.ErrSkuAliasNotFound)
 			productRepositoryMock.On("Save", mock.Anything).Return(errors.New("any repository error"))
 
-			useCase := NewProductService(productRepositoryMock)
-			err := useCase.CreateProduct(entity.Product{})
+			useCase := NewProductService(productRepositoryMock, newUnitOfWorkMock(productRepositoryMock, new(outbox.RepositoryMock)))
+			_, err := useCase.CreateProduct(context.Background(), entity.Product{})
 			assert.EqualError(t, err, "any repository error")
 		})
 	})
 }
+
+func TestProductService_Duplicates(t *testing.T) {
+	existing := &entity.Product{
+		Sku:            "FAL-1000001",
+		Name:           "Bicicleta infantil",
+		Brand:          "Oxford",
+		Size:           "16",
+		Price:          130000.00,
+		PrincipalImage: "https://via.placeholder.com/500x500.png",
+	}
+
+	t.Run("should not save a product with a taken sku", func(t *testing.T) {
+		productRepositoryMock := new(product.RepositoryMock)
+		productRepositoryMock.On("GetBySku", existing.Sku).Return(existing, nil)
+
+		useCase := NewProductService(productRepositoryMock, newUnitOfWorkMock(productRepositoryMock, new(outbox.RepositoryMock)))
+		_, err := useCase.CreateProduct(context.Background(), *existing)
+
+		assert.ErrorIs(t, err, repository.ErrDuplicatedProduct)
+		productRepositoryMock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+
+	t.Run("should not save a product with the sku of a renamed one", func(t *testing.T) {
+		productRepositoryMock := new(product.RepositoryMock)
+		productRepositoryMock.On("GetBySku", existing.Sku).Return((*entity.Product)(nil), repository.ErrProductNotFound)
+		productRepositoryMock.On("GetAliasTarget", existing.Sku).Return("FAL-1000002", nil)
+
+		useCase := NewProductService(productRepositoryMock, newUnitOfWorkMock(productRepositoryMock, new(outbox.RepositoryMock)))
+		_, err := useCase.CreateProduct(context.Background(), *existing)
+
+		assert.ErrorIs(t, err, repository.ErrDuplicatedProduct)
+		productRepositoryMock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+
+	t.Run("should not rename a product to a taken sku", func(t *testing.T) {
+		productRepositoryMock := new(product.RepositoryMock)
+		productRepositoryMock.On("GetBySku", "FAL-1000000").Return(existing, nil)
+		productRepositoryMock.On("GetBySku", existing.Sku).Return(existing, nil)
+
+		useCase := NewProductService(productRepositoryMock, newUnitOfWorkMock(productRepositoryMock, new(outbox.RepositoryMock)))
+		_, err := useCase.RenameProduct(context.Background(), "FAL-1000000", existing.Sku)
+
+		assert.ErrorIs(t, err, repository.ErrDuplicatedProduct)
+		productRepositoryMock.AssertNotCalled(t, "Rename", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should not change the sku through an update", func(t *testing.T) {
+		productRepositoryMock := new(product.RepositoryMock)
+		productRepositoryMock.On("GetBySku", "FAL-1000000").Return(existing, nil)
+
+		useCase := NewProductService(productRepositoryMock, newUnitOfWorkMock(productRepositoryMock, new(outbox.RepositoryMock)))
+		_, err := useCase.UpdateProduct(context.Background(), "FAL-1000000", *existing)
+
+		assert.ErrorIs(t, err, ErrSkuChange)
+		productRepositoryMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should not rename a product to an invalid sku", func(t *testing.T) {
+		productRepositoryMock := new(product.RepositoryMock)
+
+		useCase := NewProductService(productRepositoryMock, newUnitOfWorkMock(productRepositoryMock, new(outbox.RepositoryMock)))
+		_, err := useCase.RenameProduct(context.Background(), existing.Sku, "ABC-1")
+
+		assert.ErrorIs(t, err, ErrInvalidSku)
+	})
+
+	t.Run("should not update a missing product", func(t *testing.T) {
+		productRepositoryMock := new(product.RepositoryMock)
+		productRepositoryMock.On("GetBySku", "FAL-1000000").Return((*entity.Product)(nil), repository.ErrProductNotFound)
+
+		useCase := NewProductService(productRepositoryMock, newUnitOfWorkMock(productRepositoryMock, new(outbox.RepositoryMock)))
+		_, err := useCase.UpdateProduct(context.Background(), "FAL-1000000", *existing)
+
+		assert.ErrorIs(t, err, repository.ErrProductNotFound)
+	})
+}
+
+func TestProductService_Events(t *testing.T) {
+	oldProduct := entity.Product{
+		Sku:            "FAL-1000000",
+		Name:           "Bicicleta infantil",
+		Brand:          "Oxford",
+		Size:           "16",
+		Price:          130000.00,
+		PrincipalImage: "https://via.placeholder.com/500x500.png",
+		Status:         entity.StatusDraft,
+	}
+	lastEvent := func(events *outbox.RepositoryMock) entity.ProductEvent {
+		calls := events.Calls
+		appended := calls[len(calls)-1].Arguments.Get(0).([]entity.ProductEvent)
+		return appended[0]
+	}
+
+	t.Run("should record a created event with the product", func(t *testing.T) {
+		productRepositoryMock := new(product.RepositoryMock)
+		productRepositoryMock.On("GetBySku", oldProduct.Sku).Return((*entity.Product)(nil), repository.ErrProductNotFound)
+		productRepositoryMock.On("GetAliasTarget", oldProduct.Sku).Return("", repository.ErrSkuAliasNotFound)
+		productRepositoryMock.On("Save", oldProduct).Return(nil)
+		events := new(outbox.RepositoryMock)
+
+		useCase := NewProductService(productRepositoryMock, newUnitOfWorkMock(productRepositoryMock, events))
+		_, err := useCase.CreateProduct(context.Background(), oldProduct)
+
+		assert.NoError(t, err)
+		event := lastEvent(events)
+		assert.Equal(t, entity.EventProductCreated, event.Type)
+		assert.Equal(t, oldProduct.Sku, event.Sku)
+		assert.NotEmpty(t, event.ID)
+		assert.False(t, event.OccurredAt.IsZero())
+	})
+
+	t.Run("should record the changed fields of an update", func(t *testing.T) {
+		newProduct := oldProduct
+		newProduct.Price = 99990
+		productRepositoryMock := new(product.RepositoryMock)
+		productRepositoryMock.On("GetBySku", oldProduct.Sku).Return(&oldProduct, nil)
+		productRepositoryMock.On("Update", oldProduct.Sku, newProduct).Return(&newProduct, nil)
+		events := new(outbox.RepositoryMock)
+
+		useCase := NewProductService(productRepositoryMock, newUnitOfWorkMock(productRepositoryMock, events))
+		_, err := useCase.UpdateProduct(context.Background(), oldProduct.Sku, newProduct)
+
+		assert.NoError(t, err)
+		event := lastEvent(events)
+		assert.Equal(t, entity.EventProductUpdated, event.Type)
+		assert.Equal(t, "FAL-1000000", event.Sku)
+		assert.Empty(t, event.PreviousSku)
+		assert.Equal(t, []string{"price"}, event.ChangedFields)
+	})
+
+	t.Run("should record the previous sku of a rename", func(t *testing.T) {
+		renamedProduct := oldProduct
+		renamedProduct.Sku = "FAL-1000001"
+		productRepositoryMock := new(product.RepositoryMock)
+		productRepositoryMock.On("GetBySku", oldProduct.Sku).Return(&oldProduct, nil)
+		productRepositoryMock.On("GetBySku", renamedProduct.Sku).Return((*entity.Product)(nil), repository.ErrProductNotFound)
+		productRepositoryMock.On("GetAliasTarget", renamedProduct.Sku).Return("", repository.ErrSkuAliasNotFound)
+		productRepositoryMock.On("Rename", oldProduct.Sku, renamedProduct.Sku).Return(&renamedProduct, nil)
+		events := new(outbox.RepositoryMock)
+
+		useCase := NewProductService(productRepositoryMock, newUnitOfWorkMock(productRepositoryMock, events))
+		_, err := useCase.RenameProduct(context.Background(), oldProduct.Sku, renamedProduct.Sku)
+
+		assert.NoError(t, err)
+		event := lastEvent(events)
+		assert.Equal(t, entity.EventProductUpdated, event.Type)
+		assert.Equal(t, "FAL-1000001", event.Sku)
+		assert.Equal(t, "FAL-1000000", event.PreviousSku)
+		assert.Equal(t, []string{"sku"}, event.ChangedFields)
+	})
+
+	t.Run("should not record an event when deleting a missing product", func(t *testing.T) {
+		productRepositoryMock := new(product.RepositoryMock)
+		productRepositoryMock.On("GetBySku", oldProduct.Sku).Return((*entity.Product)(nil), repository.ErrProductNotFound)
+		events := new(outbox.RepositoryMock)
+
+		useCase := NewProductService(productRepositoryMock, newUnitOfWorkMock(productRepositoryMock, events))
+		err := useCase.DeleteProduct(context.Background(), oldProduct.Sku)
+
+		assert.NoError(t, err)
+		events.AssertNotCalled(t, "Append", mock.Anything)
+		productRepositoryMock.AssertNotCalled(t, "Delete", mock.Anything)
+	})
+
+	t.Run("should fail the write when the event cannot be recorded", func(t *testing.T) {
+		productRepositoryMock := new(product.RepositoryMock)
+		productRepositoryMock.On("GetBySku", oldProduct.Sku).Return(&oldProduct, nil)
+		productRepositoryMock.On("Delete", oldProduct.Sku).Return(nil)
+		events := new(outbox.RepositoryMock)
+		events.On("Append", mock.Anything).Return(errors.New("outbox unavailable"))
+
+		useCase := NewProductService(productRepositoryMock, &transaction.UnitOfWorkMock{ProductRepository: productRepositoryMock, OutboxRepository: events})
+		err := useCase.DeleteProduct(context.Background(), oldProduct.Sku)
+
+		assert.EqualError(t, err, "outbox unavailable")
+	})
+}
+
+func TestProductService_ChangeStatus(t *testing.T) {
+	draft := entity.Product{
+		Sku:            "FAL-1000000",
+		Name:           "Bicicleta infantil",
+		Brand:          "Oxford",
+		Size:           "16",
+		Price:          130000.00,
+		PrincipalImage: "https://via.placeholder.com/500x500.png",
+		Status:         entity.StatusDraft,
+	}
+
+	t.Run("should publish a draft and record the changed status", func(t *testing.T) {
+		published := draft
+		published.Status = entity.StatusActive
+		productRepositoryMock := new(product.RepositoryMock)
+		stored := draft
+		productRepositoryMock.On("GetBySku", draft.Sku).Return(&stored, nil)
+		productRepositoryMock.On("Update", draft.Sku, mock.Anything).Return(&published, nil)
+		events := new(outbox.RepositoryMock)
+
+		useCase := NewProductService(productRepositoryMock, newUnitOfWorkMock(productRepositoryMock, events))
+		updatedProduct, err := useCase.ChangeStatus(context.Background(), draft.Sku, entity.StatusActive, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, entity.StatusActive, updatedProduct.Status)
+		assert.Equal(t, entity.StatusActive, productRepositoryMock.Calls[1].Arguments.Get(1).(entity.Product).Status)
+		appended := events.Calls[len(events.Calls)-1].Arguments.Get(0).([]entity.ProductEvent)
+		assert.Equal(t, []string{"status"}, appended[0].ChangedFields)
+	})
+
+	t.Run("should not publish an invalid product", func(t *testing.T) {
+		invalid := draft
+		invalid.Brand = ""
+		productRepositoryMock := new(product.RepositoryMock)
+		productRepositoryMock.On("GetBySku", draft.Sku).Return(&invalid, nil)
+
+		useCase := NewProductService(productRepositoryMock, newUnitOfWorkMock(productRepositoryMock, new(outbox.RepositoryMock)))
+		_, err := useCase.ChangeStatus(context.Background(), draft.Sku, entity.StatusActive, nil)
+
+		assert.ErrorIs(t, err, entity.ErrNotPublishable)
+		assert.EqualError(t, err, "product cannot be published: empty brand")
+		productRepositoryMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should only let admins reactivate a discontinued product", func(t *testing.T) {
+		discontinued := draft
+		discontinued.Status = entity.StatusDiscontinued
+		editor := &entity.Principal{Method: entity.AuthMethodAPIKey, Role: entity.RoleEditor}
+		admin := &entity.Principal{Method: entity.AuthMethodJWT, Scopes: []string{entity.ScopeAdmin}}
+		productRepositoryMock := new(product.RepositoryMock)
+		productRepositoryMock.On("GetBySku", draft.Sku).Return(&discontinued, nil)
+		productRepositoryMock.On("Update", draft.Sku, mock.Anything).Return(&discontinued, nil)
+
+		useCase := NewProductService(productRepositoryMock, newUnitOfWorkMock(productRepositoryMock, new(outbox.RepositoryMock)))
+		_, err := useCase.ChangeStatus(context.Background(), draft.Sku, entity.StatusActive, editor)
+		assert.ErrorIs(t, err, entity.ErrTransitionForbidden)
+
+		_, err = useCase.ChangeStatus(context.Background(), draft.Sku, entity.StatusActive, admin)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should reject transitions outside the workflow", func(t *testing.T) {
+		stored := draft
+		productRepositoryMock := new(product.RepositoryMock)
+		productRepositoryMock.On("GetBySku", draft.Sku).Return(&stored, nil)
+
+		useCase := NewProductService(productRepositoryMock, newUnitOfWorkMock(productRepositoryMock, new(outbox.RepositoryMock)))
+		_, err := useCase.ChangeStatus(context.Background(), draft.Sku, entity.StatusDiscontinued, nil)
+
+		assert.ErrorIs(t, err, entity.ErrInvalidTransition)
+	})
+}
+
+func TestProductService_RestoreProduct(t *testing.T) {
+	ctx := context.Background()
+	restored := entity.Product{
+		Sku:            "FAL-1000000",
+		Name:           "Bicicleta infantil",
+		Brand:          "Oxford",
+		Size:           "16",
+		Price:          130000.00,
+		PrincipalImage: "https://via.placeholder.com/500x500.png",
+		Status:         entity.StatusDiscontinued,
+	}
+	newService := func() Service {
+		products := memory.NewProductRepository()
+		return NewProductService(products, memory.NewUnitOfWork(products, memory.NewOutboxRepository()))
+	}
+
+	t.Run("should create a product through the transitions to its status", func(t *testing.T) {
+		service := newService()
+
+		created, err := service.RestoreProduct(ctx, restored, nil)
+
+		assert.NoError(t, err)
+		assert.True(t, created)
+		stored, err := service.FindBySku(ctx, restored.Sku)
+		assert.NoError(t, err)
+		assert.Equal(t, entity.StatusDiscontinued, stored.Status)
+	})
+
+	t.Run("should not publish an invalid product", func(t *testing.T) {
+		service := newService()
+		invalid := restored
+		invalid.Brand = ""
+
+		_, err := service.RestoreProduct(ctx, invalid, nil)
+
+		assert.ErrorIs(t, err, entity.ErrNotPublishable)
+		_, err = service.FindBySku(ctx, restored.Sku)
+		assert.ErrorIs(t, err, repository.ErrProductNotFound)
+	})
+
+	t.Run("should only let admins reactivate a discontinued product", func(t *testing.T) {
+		service := newService()
+		_, err := service.RestoreProduct(ctx, restored, nil)
+		assert.NoError(t, err)
+		active := restored
+		active.Status = entity.StatusActive
+
+		_, err = service.RestoreProduct(ctx, active, nil)
+		assert.ErrorIs(t, err, entity.ErrTransitionForbidden)
+
+		created, err := service.RestoreProduct(ctx, active, &entity.Principal{Method: entity.AuthMethodAPIKey, Role: entity.RoleAdmin})
+		assert.NoError(t, err)
+		assert.False(t, created)
+	})
+}
@@ -0,0 +1,13 @@
+package usecase
+
+import (
+	"errors"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+)
+
+var ErrInvalidToken = errors.New("invalid bearer token")
+
+type TokenValidator interface {
+	Validate(rawToken string) (*entity.Principal, error)
+}
@@ -0,0 +1,19 @@
+package usecase
+
+import (
+	"github.com/stretchr/testify/mock"
+	"github.com/yescorihuela/agrak/domain/entity"
+)
+
+type TokenValidatorMock struct {
+	mock.Mock
+}
+
+func (m *TokenValidatorMock) Validate(rawToken string) (*entity.Principal, error) {
+	args := m.Called(rawToken)
+	var mockedPrincipal *entity.Principal
+	if args.Get(0) != nil {
+		mockedPrincipal = args.Get(0).(*entity.Principal)
+	}
+	return mockedPrincipal, args.Error(1)
+}
@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/shared/logging"
+)
+
+const (
+	DefaultWebhookMaxAttempts = 10
+	webhookBatchSize          = 20
+	webhookLease              = 2 * time.Minute
+	webhookInitialBackoff     = 10 * time.Second
+	webhookMaxBackoff         = time.Hour
+)
+
+// WebhookSender posts a delivery to its subscription. It returns the HTTP
+// status code received, if any, and an error unless the receiver accepted
+// the delivery with a 2xx response.
+type WebhookSender interface {
+	Send(ctx context.Context, subscription entity.WebhookSubscription, delivery entity.WebhookDelivery) (int, error)
+}
+
+// WebhookDispatcher sends pending deliveries. Failed deliveries are retried
+// with exponential backoff and become dead after maxAttempts attempts.
+type WebhookDispatcher struct {
+	subscriptions repository.WebhookRepository
+	deliveries    repository.WebhookDeliveryRepository
+	sender        WebhookSender
+	maxAttempts   int
+	now           func() time.Time
+}
+
+func NewWebhookDispatcher(subscriptions repository.WebhookRepository, deliveries repository.WebhookDeliveryRepository, sender WebhookSender, maxAttempts int) *WebhookDispatcher {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultWebhookMaxAttempts
+	}
+	return &WebhookDispatcher{
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+		sender:        sender,
+		maxAttempts:   maxAttempts,
+		now:           time.Now,
+	}
+}
+
+// Run sends deliveries until ctx is done, checking for due deliveries every
+// interval once there are none left.
+func (d *WebhookDispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		sent, err := d.DispatchPending(ctx)
+		if err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).WithError(err).Errorln("error trying to dispatch webhook deliveries")
+		}
+		if sent == webhookBatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchPending sends one batch of due deliveries concurrently and returns
+// how many were claimed.
+func (d *WebhookDispatcher) DispatchPending(ctx context.Context) (int, error) {
+	deliveries, err := d.deliveries.Claim(ctx, d.now(), webhookLease, webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, len(deliveries))
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery entity.WebhookDelivery) {
+			defer wg.Done()
+			errs <- d.dispatch(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return len(deliveries), err
+		}
+	}
+	return len(deliveries), nil
+}
+
+func (d *WebhookDispatcher) dispatch(ctx context.Context, delivery entity.WebhookDelivery) error {
+	logger := logging.FromContext(ctx).WithField("delivery_id", delivery.ID).WithField("webhook_id", delivery.SubscriptionID)
+	subscription, err := d.subscriptions.GetByID(ctx, delivery.SubscriptionID)
+	if errors.Is(err, repository.ErrWebhookNotFound) {
+		// Deleted meanwhile, its deliveries went with it.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	statusCode, err := d.sender.Send(ctx, *subscription, delivery)
+	now := d.now()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.UpdatedAt = now
+	switch {
+	case err == nil:
+		delivery.Status = entity.DeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.maxAttempts:
+		delivery.Status = entity.DeliveryDead
+		delivery.LastError = err.Error()
+		logger.WithError(err).WithField("attempts", delivery.Attempts).Warnln("webhook delivery is dead")
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(WebhookBackoff(delivery.Attempts))
+		logger.WithError(err).WithField("attempts", delivery.Attempts).
+			WithField("next_attempt_at", delivery.NextAttemptAt).Warnln("error trying to deliver webhook")
+	}
+	return d.deliveries.Record(ctx, delivery)
+}
+
+// WebhookBackoff returns the wait before retrying a delivery that failed
+// attempts times: ten seconds, doubled on every attempt, up to an hour.
+func WebhookBackoff(attempts int) time.Duration {
+	backoff := webhookInitialBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookMaxBackoff {
+		return webhookMaxBackoff
+	}
+	return backoff
+}
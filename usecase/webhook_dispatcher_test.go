@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/webhook"
+)
+
+type senderFake struct {
+	statusCode int
+	err        error
+}
+
+func (s *senderFake) Send(ctx context.Context, subscription entity.WebhookSubscription, delivery entity.WebhookDelivery) (int, error) {
+	return s.statusCode, s.err
+}
+
+func TestWebhookDispatcher_DispatchPending(t *testing.T) {
+	now := time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)
+	subscription := &entity.WebhookSubscription{ID: "w1", URL: "https://example.com/hooks", Secret: "s3cret"}
+	pending := entity.WebhookDelivery{ID: "d1", SubscriptionID: "w1", EventID: "e1", Status: entity.DeliveryPending, Attempts: 2}
+
+	newDispatcher := func(sender WebhookSender, recorded *entity.WebhookDelivery) (*WebhookDispatcher, *webhook.DeliveryRepositoryMock) {
+		subscriptionsMock := new(webhook.RepositoryMock)
+		subscriptionsMock.On("GetByID", "w1").Return(subscription, nil)
+		deliveriesMock := new(webhook.DeliveryRepositoryMock)
+		deliveriesMock.On("Claim", now, webhookLease, webhookBatchSize).Return([]entity.WebhookDelivery{pending}, nil)
+		deliveriesMock.On("Record", mock.AnythingOfType("entity.WebhookDelivery")).Run(func(args mock.Arguments) {
+			*recorded = args.Get(0).(entity.WebhookDelivery)
+		}).Return(nil)
+		dispatcher := NewWebhookDispatcher(subscriptionsMock, deliveriesMock, sender, 3)
+		dispatcher.now = func() time.Time { return now }
+		return dispatcher, deliveriesMock
+	}
+
+	t.Run("should mark accepted deliveries as succeeded", func(t *testing.T) {
+		var recorded entity.WebhookDelivery
+		dispatcher, deliveriesMock := newDispatcher(&senderFake{statusCode: 204}, &recorded)
+
+		sent, err := dispatcher.DispatchPending(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, sent)
+		assert.Equal(t, entity.DeliverySucceeded, recorded.Status)
+		assert.Equal(t, 3, recorded.Attempts)
+		assert.Equal(t, 204, recorded.LastStatusCode)
+		assert.Equal(t, &now, recorded.DeliveredAt)
+		deliveriesMock.AssertExpectations(t)
+	})
+
+	t.Run("should retry failed deliveries after the backoff", func(t *testing.T) {
+		var recorded entity.WebhookDelivery
+		dispatcher, _ := newDispatcher(&senderFake{statusCode: 503, err: errors.New("unavailable")}, &recorded)
+		dispatcher.maxAttempts = 10
+
+		_, err := dispatcher.DispatchPending(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, entity.DeliveryPending, recorded.Status)
+		assert.Equal(t, 3, recorded.Attempts)
+		assert.Equal(t, 503, recorded.LastStatusCode)
+		assert.Equal(t, "unavailable", recorded.LastError)
+		assert.Equal(t, now.Add(WebhookBackoff(3)), recorded.NextAttemptAt)
+	})
+
+	t.Run("should mark deliveries as dead after the last attempt", func(t *testing.T) {
+		var recorded entity.WebhookDelivery
+		dispatcher, _ := newDispatcher(&senderFake{err: errors.New("connection refused")}, &recorded)
+
+		_, err := dispatcher.DispatchPending(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, entity.DeliveryDead, recorded.Status)
+		assert.Equal(t, "connection refused", recorded.LastError)
+	})
+
+	t.Run("should skip deliveries of deleted subscriptions", func(t *testing.T) {
+		subscriptionsMock := new(webhook.RepositoryMock)
+		subscriptionsMock.On("GetByID", "w1").Return((*entity.WebhookSubscription)(nil), repository.ErrWebhookNotFound)
+		deliveriesMock := new(webhook.DeliveryRepositoryMock)
+		deliveriesMock.On("Claim", now, webhookLease, webhookBatchSize).Return([]entity.WebhookDelivery{pending}, nil)
+		dispatcher := NewWebhookDispatcher(subscriptionsMock, deliveriesMock, &senderFake{}, 3)
+		dispatcher.now = func() time.Time { return now }
+
+		_, err := dispatcher.DispatchPending(context.Background())
+
+		assert.NoError(t, err)
+		deliveriesMock.AssertNotCalled(t, "Record", mock.Anything)
+	})
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, WebhookBackoff(1))
+	assert.Equal(t, 20*time.Second, WebhookBackoff(2))
+	assert.Equal(t, 80*time.Second, WebhookBackoff(4))
+	assert.Equal(t, time.Hour, WebhookBackoff(20))
+}
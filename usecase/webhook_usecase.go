@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+)
+
+const (
+	webhookSecretPrefix = "whsec_"
+	webhookSecretBytes  = 32
+	maxDeliveriesListed = 100
+)
+
+var (
+	ErrInvalidWebhookURL     = errors.New("url must be an absolute http or https URL")
+	ErrInvalidEventType      = errors.New("unknown event type")
+	ErrInvalidDeliveryStatus = errors.New("status must be pending, succeeded or dead")
+)
+
+type WebhookService interface {
+	// CreateSubscription stores a subscription. A secret is generated when
+	// secret is empty.
+	CreateSubscription(ctx context.Context, rawURL, secret string, eventTypes []entity.EventType) (*entity.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]entity.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id string) (*entity.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+	// ListDeliveries returns the latest deliveries of a subscription, newest
+	// first. An empty status lists them all.
+	ListDeliveries(ctx context.Context, subscriptionID string, status entity.DeliveryStatus) ([]entity.WebhookDelivery, error)
+	// Redeliver schedules a delivery to be sent again now, with a fresh
+	// retry budget, whatever its status.
+	Redeliver(ctx context.Context, subscriptionID, deliveryID string) (*entity.WebhookDelivery, error)
+}
+
+type WebhookSubscriptionService struct {
+	subscriptions repository.WebhookRepository
+	deliveries    repository.WebhookDeliveryRepository
+	now           func() time.Time
+}
+
+func NewWebhookService(subscriptions repository.WebhookRepository, deliveries repository.WebhookDeliveryRepository) WebhookService {
+	return &WebhookSubscriptionService{
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+		now:           time.Now,
+	}
+}
+
+func (s *WebhookSubscriptionService) CreateSubscription(ctx context.Context, rawURL, secret string, eventTypes []entity.EventType) (*entity.WebhookSubscription, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+	for _, eventType := range eventTypes {
+		if !eventType.IsValid() {
+			return nil, fmt.Errorf("%w %q", ErrInvalidEventType, eventType)
+		}
+	}
+	if strings.TrimSpace(secret) == "" {
+		token, err := generateToken(webhookSecretBytes)
+		if err != nil {
+			return nil, err
+		}
+		secret = webhookSecretPrefix + token
+	}
+	id, err := generateID()
+	if err != nil {
+		return nil, err
+	}
+	subscription := entity.WebhookSubscription{
+		ID:         id,
+		URL:        parsed.String(),
+		Secret:     secret,
+		EventTypes: eventTypes,
+		CreatedAt:  s.now(),
+	}
+	if err := s.subscriptions.Save(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (s *WebhookSubscriptionService) ListSubscriptions(ctx context.Context) ([]entity.WebhookSubscription, error) {
+	return s.subscriptions.GetAll(ctx)
+}
+
+func (s *WebhookSubscriptionService) GetSubscription(ctx context.Context, id string) (*entity.WebhookSubscription, error) {
+	return s.subscriptions.GetByID(ctx, id)
+}
+
+func (s *WebhookSubscriptionService) DeleteSubscription(ctx context.Context, id string) error {
+	return s.subscriptions.Delete(ctx, id)
+}
+
+func (s *WebhookSubscriptionService) ListDeliveries(ctx context.Context, subscriptionID string, status entity.DeliveryStatus) ([]entity.WebhookDelivery, error) {
+	if status != "" && !status.IsValid() {
+		return nil, ErrInvalidDeliveryStatus
+	}
+	if _, err := s.subscriptions.GetByID(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.deliveries.GetBySubscription(ctx, subscriptionID, status, maxDeliveriesListed)
+}
+
+func (s *WebhookSubscriptionService) Redeliver(ctx context.Context, subscriptionID, deliveryID string) (*entity.WebhookDelivery, error) {
+	delivery, err := s.deliveries.GetByID(ctx, subscriptionID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Status = entity.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = s.now()
+	delivery.UpdatedAt = s.now()
+	if err := s.deliveries.Record(ctx, *delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yescorihuela/agrak/domain/entity"
+	"github.com/yescorihuela/agrak/domain/repository"
+	"github.com/yescorihuela/agrak/infrastructure/postgresql/webhook"
+)
+
+func TestWebhookService_CreateSubscription(t *testing.T) {
+	t.Run("should generate a secret when none is given", func(t *testing.T) {
+		subscriptionsMock := new(webhook.RepositoryMock)
+		subscriptionsMock.On("Save", mock.AnythingOfType("entity.WebhookSubscription")).Return(nil)
+
+		service := NewWebhookService(subscriptionsMock, new(webhook.DeliveryRepositoryMock))
+		subscription, err := service.CreateSubscription(context.Background(), "https://example.com/hooks", "", []entity.EventType{entity.EventProductCreated})
+
+		assert.NoError(t, err)
+		assert.Len(t, subscription.ID, 32)
+		assert.True(t, strings.HasPrefix(subscription.Secret, webhookSecretPrefix))
+		assert.Equal(t, []entity.EventType{entity.EventProductCreated}, subscription.EventTypes)
+		subscriptionsMock.AssertExpectations(t)
+	})
+
+	t.Run("should keep the given secret", func(t *testing.T) {
+		subscriptionsMock := new(webhook.RepositoryMock)
+		subscriptionsMock.On("Save", mock.AnythingOfType("entity.WebhookSubscription")).Return(nil)
+
+		service := NewWebhookService(subscriptionsMock, new(webhook.DeliveryRepositoryMock))
+		subscription, err := service.CreateSubscription(context.Background(), "http://localhost:9000/hooks", "s3cret", nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "s3cret", subscription.Secret)
+	})
+
+	t.Run("should reject invalid URLs", func(t *testing.T) {
+		for _, rawURL := range []string{"", "example.com/hooks", "ftp://example.com", "https://"} {
+			subscriptionsMock := new(webhook.RepositoryMock)
+
+			service := NewWebhookService(subscriptionsMock, new(webhook.DeliveryRepositoryMock))
+			_, err := service.CreateSubscription(context.Background(), rawURL, "", nil)
+
+			assert.ErrorIs(t, err, ErrInvalidWebhookURL, rawURL)
+			subscriptionsMock.AssertNotCalled(t, "Save", mock.Anything)
+		}
+	})
+
+	t.Run("should reject unknown event types", func(t *testing.T) {
+		subscriptionsMock := new(webhook.RepositoryMock)
+
+		service := NewWebhookService(subscriptionsMock, new(webhook.DeliveryRepositoryMock))
+		_, err := service.CreateSubscription(context.Background(), "https://example.com/hooks", "", []entity.EventType{"product.sold"})
+
+		assert.ErrorIs(t, err, ErrInvalidEventType)
+		subscriptionsMock.AssertNotCalled(t, "Save", mock.Anything)
+	})
+}
+
+func TestWebhookService_ListDeliveries(t *testing.T) {
+	t.Run("should list the deliveries of an existing subscription", func(t *testing.T) {
+		deliveries := []entity.WebhookDelivery{{ID: "d1", SubscriptionID: "w1", Status: entity.DeliveryDead}}
+		subscriptionsMock := new(webhook.RepositoryMock)
+		subscriptionsMock.On("GetByID", "w1").Return(&entity.WebhookSubscription{ID: "w1"}, nil)
+		deliveriesMock := new(webhook.DeliveryRepositoryMock)
+		deliveriesMock.On("GetBySubscription", "w1", entity.DeliveryDead, maxDeliveriesListed).Return(deliveries, nil)
+
+		result, err := NewWebhookService(subscriptionsMock, deliveriesMock).ListDeliveries(context.Background(), "w1", entity.DeliveryDead)
+
+		assert.NoError(t, err)
+		assert.Equal(t, deliveries, result)
+	})
+
+	t.Run("should return not found for unknown subscriptions", func(t *testing.T) {
+		subscriptionsMock := new(webhook.RepositoryMock)
+		subscriptionsMock.On("GetByID", "w1").Return((*entity.WebhookSubscription)(nil), repository.ErrWebhookNotFound)
+
+		_, err := NewWebhookService(subscriptionsMock, new(webhook.DeliveryRepositoryMock)).ListDeliveries(context.Background(), "w1", "")
+
+		assert.ErrorIs(t, err, repository.ErrWebhookNotFound)
+	})
+
+	t.Run("should reject unknown statuses", func(t *testing.T) {
+		_, err := NewWebhookService(new(webhook.RepositoryMock), new(webhook.DeliveryRepositoryMock)).ListDeliveries(context.Background(), "w1", "failed")
+
+		assert.ErrorIs(t, err, ErrInvalidDeliveryStatus)
+	})
+}
+
+func TestWebhookService_Redeliver(t *testing.T) {
+	now := time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC)
+	deliveriesMock := new(webhook.DeliveryRepositoryMock)
+	deliveriesMock.On("GetByID", "w1", "d1").Return(&entity.WebhookDelivery{
+		ID: "d1", SubscriptionID: "w1", Status: entity.DeliveryDead, Attempts: 10, LastError: "timeout",
+	}, nil)
+	deliveriesMock.On("Record", mock.AnythingOfType("entity.WebhookDelivery")).Return(nil)
+	service := NewWebhookService(new(webhook.RepositoryMock), deliveriesMock).(*WebhookSubscriptionService)
+	service.now = func() time.Time { return now }
+
+	delivery, err := service.Redeliver(context.Background(), "w1", "d1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, entity.DeliveryPending, delivery.Status)
+	assert.Equal(t, 0, delivery.Attempts)
+	assert.Equal(t, now, delivery.NextAttemptAt)
+	assert.Equal(t, "timeout", delivery.LastError, "the last error is kept until the next attempt")
+	deliveriesMock.AssertExpectations(t)
+}